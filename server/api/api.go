@@ -1,24 +1,91 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/router"
+	"github.com/teamhanko/passkey-server/api/services/admin"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/config"
 	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/provisioning"
+	"github.com/teamhanko/passkey-server/stats"
+	"log"
+	"net/http"
 	"sync"
+	"time"
 )
 
-func StartPublic(cfg *config.Config, wg *sync.WaitGroup, persister persistence.Persister, authenticatorMetadata mapper.AuthenticatorMetadata) {
+// shutdownTimeout is how long running requests may take to finish when the server is stopped
+const shutdownTimeout = 10 * time.Second
+
+// StartPublic serves the public API until the context is canceled
+func StartPublic(ctx context.Context, cfg *config.Config, wg *sync.WaitGroup, persister persistence.Persister, authenticatorMetadata mapper.AuthenticatorMetadata, auditSinks *auditlog.Sinks) {
 	defer wg.Done()
 
-	mainRouter := router.NewMainRouter(cfg, persister, authenticatorMetadata)
-	mainRouter.Logger.Fatal(mainRouter.Start(cfg.Address))
+	auditEnricher, err := auditlog.NewEnricher(cfg.GeoIp)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer auditEnricher.Close()
+
+	if cfg.Metrics.Enabled {
+		err = metrics.Register(cfg.Metrics, persister.GetStatsPersister(nil), authenticatorMetadata)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		metricsRouter := router.NewMetricsRouter()
+		go serve(ctx, metricsRouter, cfg.Metrics.Address)
+	}
+
+	mainRouter := router.NewMainRouter(cfg, persister, authenticatorMetadata, auditSinks, auditEnricher)
+	serve(ctx, mainRouter, cfg.Address)
 }
 
-func StartAdmin(cfg *config.Config, wg *sync.WaitGroup, persister persistence.Persister, prometheus echo.MiddlewareFunc) {
+// StartAdmin serves the admin API and runs the background jobs until the context is canceled
+func StartAdmin(ctx context.Context, cfg *config.Config, wg *sync.WaitGroup, persister persistence.Persister, prometheus echo.MiddlewareFunc, auditSinks *auditlog.Sinks) {
 	defer wg.Done()
 
-	adminRouter := router.NewAdminRouter(cfg, persister, prometheus)
-	adminRouter.Logger.Fatal(adminRouter.Start(cfg.AdminAddress))
+	if cfg.Provisioning.Enabled() {
+		go provisioning.Watch(cfg.Provisioning, persister)
+	}
+
+	go auditlog.WatchRetention(cfg.AuditRetention, persister)
+	go admin.WatchIdempotencyKeys(persister.GetIdempotencyKeyPersister(nil))
+
+	if cfg.Stats.Rollup {
+		go stats.Watch(cfg.Stats, persister)
+	}
+
+	adminRouter := router.NewAdminRouter(cfg, persister, prometheus, auditSinks.Select(cfg.AdminAuditSinks))
+	serve(ctx, adminRouter, cfg.AdminAddress)
+}
+
+// serve starts the router and shuts it down when the context is canceled. It returns after the running requests
+// finished, so their audit logs reach the sinks before they are closed.
+func serve(ctx context.Context, e *echo.Echo, address string) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		err := e.Shutdown(shutdownCtx)
+		if err != nil {
+			e.Logger.Error(err)
+		}
+	}()
+
+	err := e.Start(address)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		e.Logger.Fatal(err)
+	}
+
+	<-stopped
 }
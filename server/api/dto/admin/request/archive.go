@@ -0,0 +1,15 @@
+package request
+
+import "encoding/json"
+
+type ExportTenantDto struct {
+	Passphrase       string `json:"passphrase" validate:"required,min=12"`
+	IncludeJwks      bool   `json:"include_jwks"`
+	IncludeAuditLogs bool   `json:"include_audit_logs"`
+}
+
+type ImportTenantDto struct {
+	Archive    json.RawMessage `json:"archive" validate:"required"`
+	Passphrase string          `json:"passphrase" validate:"required,min=12"`
+	TenantId   *string         `json:"tenant_id" validate:"omitempty,uuid4"`
+}
@@ -0,0 +1,56 @@
+package request
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/crypto"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"time"
+)
+
+type CreateAuditLogConfigDto struct {
+	OutputStream   string `json:"output_stream" validate:"required,oneof=stdout stderr"`
+	ConsoleEnabled *bool  `json:"enable_console" validate:"required,boolean"`
+	StorageEnabled *bool  `json:"enable_storage" validate:"required,boolean"`
+	// CheckpointInterval signs the audit log chain every n entries with a JWK of the tenant. 0 disables checkpoints.
+	CheckpointInterval int `json:"checkpoint_interval" validate:"min=0"`
+	// Sinks are the names of the audit sinks of the server config the entries are sent to
+	Sinks []string `json:"sinks,omitempty" validate:"omitempty,unique,dive,required"`
+	// IpAnonymization truncates the source IP to its /24 (IPv4) or /48 (IPv6) network or replaces it by a salted hash
+	IpAnonymization string `json:"ip_anonymization,omitempty" validate:"omitempty,oneof=none truncate hash"`
+	// StoreUserAgent defaults to true
+	StoreUserAgent *bool `json:"store_user_agent,omitempty" validate:"omitempty,boolean"`
+	RetentionDays  int   `json:"retention_days" validate:"min=0"`
+}
+
+func (dto *CreateAuditLogConfigDto) ToModel(configModel models.Config) models.AuditLogConfig {
+	auditLogId, _ := uuid.NewV4()
+	ipHashSalt, _ := crypto.GenerateRandomStringURLSafe(32)
+	now := time.Now()
+
+	ipAnonymization := config.IpAnonymizationNone
+	if dto.IpAnonymization != "" {
+		ipAnonymization = dto.IpAnonymization
+	}
+
+	storeUserAgent := true
+	if dto.StoreUserAgent != nil {
+		storeUserAgent = *dto.StoreUserAgent
+	}
+
+	return models.AuditLogConfig{
+		ID:                 auditLogId,
+		ConfigID:           configModel.ID,
+		OutputStream:       dto.OutputStream,
+		ConsoleEnabled:     *dto.ConsoleEnabled,
+		StorageEnabled:     *dto.StorageEnabled,
+		CheckpointInterval: dto.CheckpointInterval,
+		Sinks:              dto.Sinks,
+		IpAnonymization:    ipAnonymization,
+		IpHashSalt:         ipHashSalt,
+		StoreUserAgent:     storeUserAgent,
+		RetentionDays:      dto.RetentionDays,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+}
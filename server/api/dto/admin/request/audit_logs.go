@@ -2,13 +2,52 @@ package request
 
 import "time"
 
+type AuditLogFilterDto struct {
+	StartTime     *time.Time `query:"start_time"`
+	EndTime       *time.Time `query:"end_time"`
+	Types         []string   `query:"type"`
+	UserId        string     `query:"actor_user_id"`
+	IP            string     `query:"meta_source_ip"`
+	SearchString  string     `query:"q"`
+	Actor         string     `query:"actor"`
+	TargetType    string     `query:"target_type"`
+	TargetId      string     `query:"target_id"`
+	TransactionId string     `query:"transaction_id"`
+	Error         string     `query:"error"`
+	Browser       string     `query:"meta_browser"`
+	Os            string     `query:"meta_os"`
+	Device        string     `query:"meta_device" validate:"omitempty,oneof=desktop mobile tablet bot"`
+	Country       string     `query:"meta_country" validate:"omitempty,iso3166_1_alpha2"`
+	City          string     `query:"meta_city"`
+	Asn           int        `query:"meta_asn" validate:"gte=0"`
+}
+
 type ListAuditLogDto struct {
-	Page         int        `query:"page"`
-	PerPage      int        `query:"per_page"`
-	StartTime    *time.Time `query:"start_time"`
-	EndTime      *time.Time `query:"end_time"`
-	Types        []string   `query:"type"`
-	UserId       string     `query:"actor_user_id"`
-	IP           string     `query:"meta_source_ip"`
-	SearchString string     `query:"q"`
+	AuditLogFilterDto
+	Page    int `query:"page"`
+	PerPage int `query:"per_page"`
+	// Pagination selects offset pagination with a total count or cursor pagination on the creation time and id
+	Pagination string `query:"pagination" validate:"omitempty,oneof=offset cursor"`
+	Cursor     string `query:"cursor"`
+}
+
+// UsesCursor returns true if the list is paged by a cursor. Links to the next page contain the cursor.
+func (dto *ListAuditLogDto) UsesCursor() bool {
+	return dto.Pagination == "cursor" || dto.Cursor != ""
+}
+
+// ListAdminAuditLogDto lists the audit logs of admin operations across all tenants
+type ListAdminAuditLogDto struct {
+	ListAuditLogDto
+	TenantId string `query:"tenant_id" validate:"omitempty,uuid4"`
+}
+
+type ExportAuditLogDto struct {
+	AuditLogFilterDto
+	Format string `query:"format" validate:"omitempty,oneof=ndjson csv"`
+}
+
+type VerifyAuditLogDto struct {
+	StartTime *time.Time `query:"start_time"`
+	EndTime   *time.Time `query:"end_time"`
 }
@@ -8,25 +8,26 @@ import (
 )
 
 type CreateConfigDto struct {
-	Cors    CreateCorsDto          `json:"cors" validate:"required"`
-	Passkey CreatePasskeyConfigDto `json:"webauthn" validate:"required"`
-	Mfa     *CreateMFAConfigDto    `json:"mfa" validate:"omitempty"`
+	Cors     CreateCorsDto            `json:"cors" validate:"required"`
+	Passkey  CreatePasskeyConfigDto   `json:"webauthn" validate:"required"`
+	Mfa      *CreateMFAConfigDto      `json:"mfa" validate:"omitempty"`
+	AuditLog *CreateAuditLogConfigDto `json:"audit_log" validate:"omitempty"`
 }
 
 func (dto *CreateConfigDto) ToModel(tenant models.Tenant) models.Config {
 	configId, _ := uuid.NewV4()
 	now := time.Now()
 
-	auditLogId, _ := uuid.NewV4()
-	auditLogModel := models.AuditLogConfig{
-		ID:             auditLogId,
-		ConfigID:       configId,
-		OutputStream:   config.OutputStreamStdOut,
-		ConsoleEnabled: true,
-		StorageEnabled: true,
-		CreatedAt:      now,
-		UpdatedAt:      now,
+	auditLogDto := dto.AuditLog
+	if auditLogDto == nil {
+		enabled := true
+		auditLogDto = &CreateAuditLogConfigDto{
+			OutputStream:   config.OutputStreamStdOut,
+			ConsoleEnabled: &enabled,
+			StorageEnabled: &enabled,
+		}
 	}
+	auditLogModel := auditLogDto.ToModel(models.Config{ID: configId})
 
 	configModel := models.Config{
 		ID:             configId,
@@ -43,3 +44,12 @@ func (dto *CreateConfigDto) ToModel(tenant models.Tenant) models.Config {
 type UpdateConfigDto struct {
 	CreateConfigDto
 }
+
+type DiffConfigVersionsDto struct {
+	From int `query:"from" validate:"required,min=1"`
+	To   int `query:"to" validate:"required,min=1"`
+}
+
+type RollbackConfigDto struct {
+	Version int `param:"version" validate:"required,min=1"`
+}
@@ -0,0 +1,146 @@
+package request
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MaxImportCredentials limits the number of credentials of a single import request
+const MaxImportCredentials = 1000
+
+type ImportCredentialsDto struct {
+	Credentials []ImportCredentialDto `json:"credentials"`
+}
+
+type ImportCredentialDto struct {
+	CredentialId   string   `json:"credential_id" validate:"required"`
+	PublicKey      string   `json:"public_key" validate:"required"`
+	SignCount      uint32   `json:"sign_count"`
+	AAGUID         *string  `json:"aaguid" validate:"omitempty,uuid"`
+	Transports     []string `json:"transports" validate:"omitempty,dive,oneof=usb nfc ble smart-card hybrid internal"`
+	BackupEligible bool     `json:"backup_eligible"`
+	BackupState    bool     `json:"backup_state"`
+	Name           *string  `json:"name" validate:"omitempty,max=255"`
+	RPId           *string  `json:"rp_id"`
+	IsMFA          bool     `json:"is_mfa"`
+	UserId         string   `json:"user_id" validate:"required,max=255"`
+	UserName       string   `json:"user_name" validate:"required,max=255"`
+	DisplayName    *string  `json:"display_name" validate:"omitempty,max=255"`
+
+	// ParseError is set when a CSV row could not be parsed, so it can be reported together with the other rows
+	ParseError error `json:"-"`
+}
+
+// ParseImportCredentialsCsv reads credentials from a CSV file. The first line must contain the column names, which are
+// the same as the JSON field names. Transports are separated by spaces.
+func ParseImportCredentialsCsv(reader io.Reader) ([]ImportCredentialDto, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read csv header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	for _, required := range []string{"credential_id", "public_key", "user_id", "user_name"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("csv header is missing column '%s'", required)
+		}
+	}
+
+	credentials := make([]ImportCredentialDto, 0)
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to read csv: %w", err)
+		}
+
+		if len(credentials) >= MaxImportCredentials {
+			return nil, fmt.Errorf("csv cannot have more than %d rows", MaxImportCredentials)
+		}
+
+		credentials = append(credentials, parseCsvRecord(columns, record))
+	}
+
+	if len(credentials) == 0 {
+		return nil, errors.New("csv contains no credentials")
+	}
+
+	return credentials, nil
+}
+
+func parseCsvRecord(columns map[string]int, record []string) ImportCredentialDto {
+	get := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+
+		return ""
+	}
+
+	getOptional := func(name string) *string {
+		if value := get(name); value != "" {
+			return &value
+		}
+
+		return nil
+	}
+
+	var parseErrors []string
+	getBool := func(name string) bool {
+		value := get(name)
+		if value == "" {
+			return false
+		}
+
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			parseErrors = append(parseErrors, fmt.Sprintf("%s must be a boolean", name))
+		}
+
+		return parsed
+	}
+
+	dto := ImportCredentialDto{
+		CredentialId:   get("credential_id"),
+		PublicKey:      get("public_key"),
+		AAGUID:         getOptional("aaguid"),
+		Transports:     strings.Fields(get("transports")),
+		BackupEligible: getBool("backup_eligible"),
+		BackupState:    getBool("backup_state"),
+		Name:           getOptional("name"),
+		RPId:           getOptional("rp_id"),
+		IsMFA:          getBool("is_mfa"),
+		UserId:         get("user_id"),
+		UserName:       get("user_name"),
+		DisplayName:    getOptional("display_name"),
+	}
+
+	if signCount := get("sign_count"); signCount != "" {
+		parsed, err := strconv.ParseUint(signCount, 10, 32)
+		if err != nil {
+			parseErrors = append(parseErrors, "sign_count must be a positive number")
+		}
+
+		dto.SignCount = uint32(parsed)
+	}
+
+	if len(parseErrors) > 0 {
+		dto.ParseError = errors.New(strings.Join(parseErrors, " and "))
+	}
+
+	return dto
+}
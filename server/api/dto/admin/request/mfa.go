@@ -13,6 +13,7 @@ type CreateMFAConfigDto struct {
 	Attachment             *protocol.AuthenticatorAttachment     `json:"attachment" validate:"omitempty,oneof=platform cross-platform"`
 	AttestationPreference  *protocol.ConveyancePreference        `json:"attestation_preference" validate:"omitempty,oneof=none indirect direct enterprise"`
 	ResidentKeyRequirement *protocol.ResidentKeyRequirement      `json:"resident_key_requirement" validate:"omitempty,oneof=discouraged preferred required"`
+	RequireForLogin        bool                                  `json:"require_for_login"`
 }
 
 func (dto *CreateMFAConfigDto) ToModel(configModel models.Config) models.MfaConfig {
@@ -20,11 +21,12 @@ func (dto *CreateMFAConfigDto) ToModel(configModel models.Config) models.MfaConf
 	now := time.Now()
 
 	mfaConfig := models.MfaConfig{
-		ID:        mfaConfigId,
-		ConfigID:  configModel.ID,
-		Timeout:   dto.Timeout,
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:              mfaConfigId,
+		ConfigID:        configModel.ID,
+		Timeout:         dto.Timeout,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		RequireForLogin: dto.RequireForLogin,
 	}
 
 	if dto.AttestationPreference == nil {
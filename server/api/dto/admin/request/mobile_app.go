@@ -0,0 +1,52 @@
+package request
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"strings"
+	"time"
+)
+
+type CreateAndroidAppDto struct {
+	PackageName            string   `json:"package_name" validate:"required"`
+	Sha256CertFingerprints []string `json:"sha256_cert_fingerprints" validate:"required,min=1,unique,dive,sha256_fingerprint"`
+}
+
+func (dto *CreateAndroidAppDto) ToModel(relyingParty models.RelyingParty) models.MobileApp {
+	appId, _ := uuid.NewV4()
+	now := time.Now()
+
+	fingerprints := make([]string, len(dto.Sha256CertFingerprints))
+	for i, fingerprint := range dto.Sha256CertFingerprints {
+		fingerprints[i] = strings.ToUpper(fingerprint)
+	}
+	joinedFingerprints := strings.Join(fingerprints, ",")
+
+	return models.MobileApp{
+		ID:                     appId,
+		RelyingPartyID:         relyingParty.ID,
+		Platform:               models.MobileAppPlatformAndroid,
+		AppId:                  dto.PackageName,
+		Sha256CertFingerprints: &joinedFingerprints,
+		CreatedAt:              now,
+		UpdatedAt:              now,
+	}
+}
+
+type CreateIosAppDto struct {
+	AppId string `json:"app_id" validate:"required,apple_app_id"`
+}
+
+func (dto *CreateIosAppDto) ToModel(relyingParty models.RelyingParty) models.MobileApp {
+	appId, _ := uuid.NewV4()
+	now := time.Now()
+
+	return models.MobileApp{
+		ID:             appId,
+		RelyingPartyID: relyingParty.ID,
+		Platform:       models.MobileAppPlatformIOS,
+		AppId:          dto.AppId,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
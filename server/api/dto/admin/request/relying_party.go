@@ -7,13 +7,15 @@ import (
 )
 
 type CreateRelyingPartyDto struct {
-	Id          string   `json:"id" validate:"required"`
-	DisplayName string   `json:"display_name" validate:"required"`
-	Icon        *string  `json:"icon" validate:"omitempty,url"`
-	Origins     []string `json:"origins" validate:"required,min=1"`
+	Id          string                `json:"id" validate:"required"`
+	DisplayName string                `json:"display_name" validate:"required"`
+	Icon        *string               `json:"icon" validate:"omitempty,url"`
+	Origins     []string              `json:"origins" validate:"required,min=1"`
+	AndroidApps []CreateAndroidAppDto `json:"android_apps" validate:"omitempty,dive"`
+	IosApps     []CreateIosAppDto     `json:"ios_apps" validate:"omitempty,dive"`
 }
 
-func (dto *CreateRelyingPartyDto) ToModel(config models.WebauthnConfig) models.RelyingParty {
+func (dto *CreateRelyingPartyDto) ToModel(config models.WebauthnConfig, isDefault bool) models.RelyingParty {
 	rpId, _ := uuid.NewV4()
 	now := time.Now()
 	var origins models.WebauthnOrigins
@@ -36,10 +38,19 @@ func (dto *CreateRelyingPartyDto) ToModel(config models.WebauthnConfig) models.R
 		RPId:             dto.Id,
 		DisplayName:      dto.DisplayName,
 		Icon:             dto.Icon,
+		IsDefault:        isDefault,
 		Origins:          origins,
 		CreatedAt:        now,
 		UpdatedAt:        now,
 	}
 
+	for _, androidApp := range dto.AndroidApps {
+		relyingParty.MobileApps = append(relyingParty.MobileApps, androidApp.ToModel(relyingParty))
+	}
+
+	for _, iosApp := range dto.IosApps {
+		relyingParty.MobileApps = append(relyingParty.MobileApps, iosApp.ToModel(relyingParty))
+	}
+
 	return relyingParty
 }
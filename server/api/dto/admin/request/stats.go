@@ -0,0 +1,9 @@
+package request
+
+import "time"
+
+type GetStatsDto struct {
+	StartTime *time.Time `query:"start_time"`
+	EndTime   *time.Time `query:"end_time"`
+	Interval  string     `query:"interval" validate:"omitempty,oneof=hour day"`
+}
@@ -7,6 +7,7 @@ import (
 )
 
 type CreateTenantDto struct {
+	Id           *string         `json:"id" validate:"omitempty,uuid4"`
 	DisplayName  string          `json:"display_name" validate:"required"`
 	Config       CreateConfigDto `json:"config" validate:"required"`
 	CreateApiKey bool            `json:"create_api_key"`
@@ -14,6 +15,10 @@ type CreateTenantDto struct {
 
 func (dto *CreateTenantDto) ToModel() models.Tenant {
 	tenantId, _ := uuid.NewV4()
+	if dto.Id != nil {
+		tenantId = uuid.FromStringOrNil(*dto.Id)
+	}
+
 	now := time.Now()
 
 	tenant := models.Tenant{
@@ -4,4 +4,32 @@ type UserListRequest struct {
 	PerPage       int    `query:"per_page"`
 	Page          int    `query:"page"`
 	SortDirection string `query:"sort_direction"`
+	SortBy        string `query:"sort_by"`
+	UserId        string `query:"user_id"`
+	Name          string `query:"name"`
+	DisplayName   string `query:"display_name"`
+	Query         string `query:"q"`
+	Credentials   string `query:"credentials"`
+	UnusedForDays int    `query:"unused_for_days"`
+}
+
+type CreateUserDto struct {
+	UserId      string  `json:"user_id" validate:"required,max=255"`
+	Name        string  `json:"name" validate:"required,max=128"`
+	DisplayName *string `json:"display_name" validate:"omitempty,max=128"`
+	Icon        *string `json:"icon" validate:"omitempty,url"`
+}
+
+type UpdateUserDto struct {
+	Name        string  `json:"name" validate:"required,max=128"`
+	DisplayName *string `json:"display_name" validate:"omitempty,max=128"`
+	Icon        *string `json:"icon" validate:"omitempty,url"`
+}
+
+type UpdateUserCredentialDto struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type UpdateUserCredentialStatusDto struct {
+	Reason *string `json:"reason" validate:"omitempty,max=255"`
 }
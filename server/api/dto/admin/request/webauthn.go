@@ -8,12 +8,37 @@ import (
 )
 
 type CreatePasskeyConfigDto struct {
-	RelyingParty           CreateRelyingPartyDto                 `json:"relying_party" validate:"required"`
-	Timeout                int                                   `json:"timeout" validate:"required,number"`
-	UserVerification       *protocol.UserVerificationRequirement `json:"user_verification" validate:"omitempty,oneof=required preferred discouraged"`
-	Attachment             *protocol.AuthenticatorAttachment     `json:"attachment" validate:"omitempty,oneof=platform cross-platform"`
-	AttestationPreference  *protocol.ConveyancePreference        `json:"attestation_preference" validate:"omitempty,oneof=none indirect direct enterprise"`
-	ResidentKeyRequirement *protocol.ResidentKeyRequirement      `json:"resident_key_requirement" validate:"omitempty,oneof=discouraged preferred required"`
+	RelyingParty             CreateRelyingPartyDto                 `json:"relying_party" validate:"required"`
+	AdditionalRelyingParties []CreateRelyingPartyDto               `json:"additional_relying_parties" validate:"omitempty,dive"`
+	Timeout                  int                                   `json:"timeout" validate:"required,number"`
+	UserVerification         *protocol.UserVerificationRequirement `json:"user_verification" validate:"omitempty,oneof=required preferred discouraged"`
+	Attachment               *protocol.AuthenticatorAttachment     `json:"attachment" validate:"omitempty,oneof=platform cross-platform"`
+	AttestationPreference    *protocol.ConveyancePreference        `json:"attestation_preference" validate:"omitempty,oneof=none indirect direct enterprise"`
+	ResidentKeyRequirement   *protocol.ResidentKeyRequirement      `json:"resident_key_requirement" validate:"omitempty,oneof=discouraged preferred required"`
+	TransportStripping       *CreateTransportStrippingDto          `json:"transport_stripping" validate:"omitempty"`
+	StoreCreationIp          bool                                  `json:"store_creation_ip"`
+}
+
+type CreateTransportStrippingDto struct {
+	Policy            models.TransportStrippingPolicy `json:"policy" validate:"required,oneof=always never user_agent"`
+	UserAgentPatterns []string                        `json:"user_agent_patterns" validate:"omitempty,unique,dive,required,regexp"`
+}
+
+func (dto *CreateTransportStrippingDto) ToRuleModels(webauthnConfig models.WebauthnConfig) models.TransportStrippingRules {
+	var rules models.TransportStrippingRules
+	now := time.Now()
+	for _, pattern := range dto.UserAgentPatterns {
+		ruleId, _ := uuid.NewV4()
+		rules = append(rules, models.TransportStrippingRule{
+			ID:               ruleId,
+			WebauthnConfigID: webauthnConfig.ID,
+			UserAgentPattern: pattern,
+			CreatedAt:        now,
+			UpdatedAt:        now,
+		})
+	}
+
+	return rules
 }
 
 func (dto *CreatePasskeyConfigDto) ToModel(configModel models.Config) models.WebauthnConfig {
@@ -26,6 +51,8 @@ func (dto *CreatePasskeyConfigDto) ToModel(configModel models.Config) models.Web
 		Timeout:   dto.Timeout,
 		CreatedAt: now,
 		UpdatedAt: now,
+
+		StoreCreationIp: dto.StoreCreationIp,
 	}
 
 	if dto.AttestationPreference == nil {
@@ -48,23 +75,53 @@ func (dto *CreatePasskeyConfigDto) ToModel(configModel models.Config) models.Web
 		passkeyConfig.UserVerification = *dto.UserVerification
 	}
 
+	if dto.TransportStripping == nil {
+		passkeyConfig.TransportStripping = models.TransportStrippingAlways
+	} else {
+		passkeyConfig.TransportStripping = dto.TransportStripping.Policy
+		passkeyConfig.TransportStrippingRules = dto.TransportStripping.ToRuleModels(passkeyConfig)
+	}
+
 	return passkeyConfig
 }
 
+func (dto *CreatePasskeyConfigDto) ToRelyingPartyModels(webauthnConfig models.WebauthnConfig) models.RelyingParties {
+	relyingParties := models.RelyingParties{dto.RelyingParty.ToModel(webauthnConfig, true)}
+	for _, additionalRP := range dto.AdditionalRelyingParties {
+		relyingParties = append(relyingParties, additionalRP.ToModel(webauthnConfig, false))
+	}
+
+	return relyingParties
+}
+
+// HasUniqueRelyingPartyIds checks that no RP ID is configured twice for the tenant
+func (dto *CreatePasskeyConfigDto) HasUniqueRelyingPartyIds() bool {
+	rpIds := map[string]bool{dto.RelyingParty.Id: true}
+	for _, additionalRP := range dto.AdditionalRelyingParties {
+		if rpIds[additionalRP.Id] {
+			return false
+		}
+
+		rpIds[additionalRP.Id] = true
+	}
+
+	return true
+}
+
 func (dto *CreatePasskeyConfigDto) ToMfaModel(configModel models.Config) models.MfaConfig {
 	mfaConfigId, _ := uuid.NewV4()
 	now := time.Now()
 
 	mfaConfig := models.MfaConfig{
-		ID:        mfaConfigId,
-		ConfigID:  configModel.ID,
-		Timeout:   dto.Timeout,
-		CreatedAt: now,
-		UpdatedAt: now,
-		AttestationPreference: protocol.PreferDirectAttestation,
+		ID:                     mfaConfigId,
+		ConfigID:               configModel.ID,
+		Timeout:                dto.Timeout,
+		CreatedAt:              now,
+		UpdatedAt:              now,
+		AttestationPreference:  protocol.PreferDirectAttestation,
 		ResidentKeyRequirement: protocol.ResidentKeyRequirementDiscouraged,
-		UserVerification: protocol.VerificationPreferred,
-		Attachment: protocol.CrossPlatform,
+		UserVerification:       protocol.VerificationPreferred,
+		Attachment:             protocol.CrossPlatform,
 	}
 
 	return mfaConfig
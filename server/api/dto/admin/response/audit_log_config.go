@@ -0,0 +1,27 @@
+package response
+
+import "github.com/teamhanko/passkey-server/persistence/models"
+
+type GetAuditLogResponse struct {
+	OutputStream       string   `json:"output_stream"`
+	ConsoleEnabled     bool     `json:"enable_console"`
+	StorageEnabled     bool     `json:"enable_storage"`
+	CheckpointInterval int      `json:"checkpoint_interval"`
+	Sinks              []string `json:"sinks,omitempty"`
+	IpAnonymization    string   `json:"ip_anonymization"`
+	StoreUserAgent     bool     `json:"store_user_agent"`
+	RetentionDays      int      `json:"retention_days"`
+}
+
+func ToGetAuditLogResponse(auditLogConfig *models.AuditLogConfig) GetAuditLogResponse {
+	return GetAuditLogResponse{
+		OutputStream:       auditLogConfig.OutputStream,
+		ConsoleEnabled:     auditLogConfig.ConsoleEnabled,
+		StorageEnabled:     auditLogConfig.StorageEnabled,
+		CheckpointInterval: auditLogConfig.CheckpointInterval,
+		Sinks:              auditLogConfig.Sinks,
+		IpAnonymization:    auditLogConfig.IpAnonymization,
+		StoreUserAgent:     auditLogConfig.StoreUserAgent,
+		RetentionDays:      auditLogConfig.RetentionDays,
+	}
+}
@@ -6,6 +6,7 @@ type GetConfigResponse struct {
 	Cors     GetCorsResponse     `json:"cors"`
 	Webauthn GetWebauthnResponse `json:"webauthn"`
 	MFA      GetMFAResponse      `json:"mfa"`
+	AuditLog GetAuditLogResponse `json:"audit_log"`
 }
 
 func ToGetConfigResponse(config *models.Config) GetConfigResponse {
@@ -13,5 +14,6 @@ func ToGetConfigResponse(config *models.Config) GetConfigResponse {
 		Cors:     ToGetCorsResponse(&config.Cors),
 		Webauthn: ToGetWebauthnResponse(&config.WebauthnConfig),
 		MFA:      ToGetMFAResponse(config.MfaConfig),
+		AuditLog: ToGetAuditLogResponse(&config.AuditLogConfig),
 	}
 }
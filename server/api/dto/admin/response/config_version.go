@@ -0,0 +1,36 @@
+package response
+
+import (
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/utils"
+	"time"
+)
+
+type ConfigVersionResponse struct {
+	Version    int                     `json:"version"`
+	ChangeType models.ConfigChangeType `json:"change_type"`
+	ChangedBy  *string                 `json:"changed_by,omitempty"`
+	CreatedAt  time.Time               `json:"created_at"`
+}
+
+type ListConfigVersionResponses []ConfigVersionResponse
+
+func ToListConfigVersionResponses(versions models.ConfigVersions) ListConfigVersionResponses {
+	responses := make(ListConfigVersionResponses, 0)
+	for _, version := range versions {
+		responses = append(responses, ConfigVersionResponse{
+			Version:    version.Version,
+			ChangeType: version.ChangeType,
+			ChangedBy:  version.ChangedBy,
+			CreatedAt:  version.CreatedAt,
+		})
+	}
+
+	return responses
+}
+
+type DiffConfigVersionsResponse struct {
+	From    int                `json:"from"`
+	To      int                `json:"to"`
+	Changes []utils.JsonChange `json:"changes"`
+}
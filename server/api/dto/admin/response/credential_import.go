@@ -0,0 +1,22 @@
+package response
+
+type CredentialImportStatus string
+
+const (
+	CredentialImportStatusImported CredentialImportStatus = "imported"
+	CredentialImportStatusFailed   CredentialImportStatus = "failed"
+)
+
+type CredentialImportResponse struct {
+	Imported int                   `json:"imported"`
+	Failed   int                   `json:"failed"`
+	Rows     []CredentialImportRow `json:"rows"`
+}
+
+type CredentialImportRow struct {
+	Row          int                    `json:"row"`
+	CredentialId string                 `json:"credential_id"`
+	UserId       string                 `json:"user_id"`
+	Status       CredentialImportStatus `json:"status"`
+	Error        *string                `json:"error,omitempty"`
+}
@@ -11,6 +11,7 @@ type GetMFAResponse struct {
 	Attachment             protocol.AuthenticatorAttachment     `json:"attachment"`
 	AttestationPreference  protocol.ConveyancePreference        `json:"attestation_preference"`
 	ResidentKeyRequirement protocol.ResidentKeyRequirement      `json:"resident_key_requirement"`
+	RequireForLogin        bool                                 `json:"require_for_login"`
 }
 
 func ToGetMFAResponse(webauthn *models.MfaConfig) GetMFAResponse {
@@ -20,5 +21,6 @@ func ToGetMFAResponse(webauthn *models.MfaConfig) GetMFAResponse {
 		Attachment:             webauthn.Attachment,
 		AttestationPreference:  webauthn.AttestationPreference,
 		ResidentKeyRequirement: webauthn.ResidentKeyRequirement,
+		RequireForLogin:        webauthn.RequireForLogin,
 	}
 }
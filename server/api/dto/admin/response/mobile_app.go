@@ -0,0 +1,62 @@
+package response
+
+import "github.com/teamhanko/passkey-server/persistence/models"
+
+const assetLinksLoginCredsRelation = "delegate_permission/common.get_login_creds"
+
+type GetAndroidAppResponse struct {
+	PackageName            string   `json:"package_name"`
+	Sha256CertFingerprints []string `json:"sha256_cert_fingerprints"`
+}
+
+type GetIosAppResponse struct {
+	AppId string `json:"app_id"`
+}
+
+type AssetLinkTarget struct {
+	Namespace              string   `json:"namespace"`
+	PackageName            string   `json:"package_name"`
+	Sha256CertFingerprints []string `json:"sha256_cert_fingerprints"`
+}
+
+type AssetLink struct {
+	Relation []string        `json:"relation"`
+	Target   AssetLinkTarget `json:"target"`
+}
+
+// ToAssetLinksResponse renders the content of the 'assetlinks.json' for the android apps of the relying party
+func ToAssetLinksResponse(relyingParty *models.RelyingParty) []AssetLink {
+	assetLinks := make([]AssetLink, 0)
+	for _, app := range relyingParty.GetMobileApps(models.MobileAppPlatformAndroid) {
+		assetLinks = append(assetLinks, AssetLink{
+			Relation: []string{assetLinksLoginCredsRelation},
+			Target: AssetLinkTarget{
+				Namespace:              "android_app",
+				PackageName:            app.AppId,
+				Sha256CertFingerprints: app.GetFingerprints(),
+			},
+		})
+	}
+
+	return assetLinks
+}
+
+type AppleWebCredentials struct {
+	Apps []string `json:"apps"`
+}
+
+type AppleAppSiteAssociation struct {
+	WebCredentials AppleWebCredentials `json:"webcredentials"`
+}
+
+// ToAppleAppSiteAssociationResponse renders the content of the 'apple-app-site-association' for the iOS apps of the relying party
+func ToAppleAppSiteAssociationResponse(relyingParty *models.RelyingParty) AppleAppSiteAssociation {
+	apps := make([]string, 0)
+	for _, app := range relyingParty.GetMobileApps(models.MobileAppPlatformIOS) {
+		apps = append(apps, app.AppId)
+	}
+
+	return AppleAppSiteAssociation{
+		WebCredentials: AppleWebCredentials{Apps: apps},
+	}
+}
@@ -3,10 +3,12 @@ package response
 import "github.com/teamhanko/passkey-server/persistence/models"
 
 type GetRelyingPartyResponse struct {
-	Id          string   `json:"id"`
-	DisplayName string   `json:"display_name"`
-	Icon        *string  `json:"icon,omitempty"`
-	Origins     []string `json:"origins"`
+	Id          string                  `json:"id"`
+	DisplayName string                  `json:"display_name"`
+	Icon        *string                 `json:"icon,omitempty"`
+	Origins     []string                `json:"origins"`
+	AndroidApps []GetAndroidAppResponse `json:"android_apps,omitempty"`
+	IosApps     []GetIosAppResponse     `json:"ios_apps,omitempty"`
 }
 
 func ToGetRelyingPartyResponse(relyingParty *models.RelyingParty) GetRelyingPartyResponse {
@@ -15,10 +17,25 @@ func ToGetRelyingPartyResponse(relyingParty *models.RelyingParty) GetRelyingPart
 		origins = append(origins, origin.Origin)
 	}
 
+	var androidApps []GetAndroidAppResponse
+	for _, app := range relyingParty.GetMobileApps(models.MobileAppPlatformAndroid) {
+		androidApps = append(androidApps, GetAndroidAppResponse{
+			PackageName:            app.AppId,
+			Sha256CertFingerprints: app.GetFingerprints(),
+		})
+	}
+
+	var iosApps []GetIosAppResponse
+	for _, app := range relyingParty.GetMobileApps(models.MobileAppPlatformIOS) {
+		iosApps = append(iosApps, GetIosAppResponse{AppId: app.AppId})
+	}
+
 	return GetRelyingPartyResponse{
 		Id:          relyingParty.RPId,
 		DisplayName: relyingParty.DisplayName,
 		Icon:        relyingParty.Icon,
 		Origins:     origins,
+		AndroidApps: androidApps,
+		IosApps:     iosApps,
 	}
 }
@@ -9,7 +9,7 @@ import (
 type SecretResponseDto struct {
 	Id        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
-	Secret    string    `json:"secret"`
+	Secret    string    `json:"secret,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 }
 
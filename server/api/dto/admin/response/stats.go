@@ -0,0 +1,31 @@
+package response
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/stats"
+	"time"
+)
+
+type StatsResponse struct {
+	StartTime   time.Time             `json:"start_time"`
+	EndTime     time.Time             `json:"end_time"`
+	Interval    string                `json:"interval"`
+	Buckets     []stats.Bucket        `json:"buckets"`
+	Ceremonies  []stats.CeremonyStats `json:"ceremonies"`
+	ActiveUsers int                   `json:"active_users"`
+	// Registrations and BackupEligible cover the credentials registered in the time range
+	Registrations  []AuthenticatorRegistrations `json:"registrations_by_authenticator"`
+	BackupEligible BackupEligibleStats          `json:"backup_eligible"`
+}
+
+type AuthenticatorRegistrations struct {
+	AAGUID uuid.UUID `json:"aaguid"`
+	Name   *string   `json:"name,omitempty"`
+	Count  int       `json:"count"`
+}
+
+type BackupEligibleStats struct {
+	Total          int      `json:"total"`
+	BackupEligible int      `json:"backup_eligible"`
+	Share          *float64 `json:"share,omitempty"`
+}
@@ -36,6 +36,17 @@ type CreateTenantResponse struct {
 	ApiKey *SecretResponseDto `json:"api_key,omitempty"`
 }
 
+// WithoutSecrets returns the response without the secret of the API key, so it can be stored for replays
+func (response CreateTenantResponse) WithoutSecrets() CreateTenantResponse {
+	if response.ApiKey != nil {
+		apiKey := *response.ApiKey
+		apiKey.Secret = ""
+		response.ApiKey = &apiKey
+	}
+
+	return response
+}
+
 func ToCreateTenantResponse(tenant *models.Tenant, apiKey *models.Secret) CreateTenantResponse {
 	return CreateTenantResponse{
 		Id:     tenant.ID,
@@ -4,14 +4,17 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/teamhanko/passkey-server/api/dto/response"
 	"github.com/teamhanko/passkey-server/persistence/models"
+	"time"
 )
 
 type UserListDto struct {
-	ID          uuid.UUID `json:"id"`
-	UserID      string    `json:"user_id"`
-	Name        string    `json:"name"`
-	Icon        string    `json:"icon"`
-	DisplayName string    `json:"display_name"`
+	ID          uuid.UUID  `json:"id"`
+	UserID      string     `json:"user_id"`
+	Name        string     `json:"name"`
+	Icon        string     `json:"icon"`
+	DisplayName string     `json:"display_name"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	DisabledAt  *time.Time `json:"disabled_at"`
 }
 
 func UserListDtoFromModel(user models.WebauthnUser) UserListDto {
@@ -21,6 +24,8 @@ func UserListDtoFromModel(user models.WebauthnUser) UserListDto {
 		Name:        user.Name,
 		Icon:        user.Icon,
 		DisplayName: user.DisplayName,
+		LastLoginAt: user.LastLoginAt,
+		DisabledAt:  user.DisabledAt,
 	}
 }
 
@@ -38,7 +43,7 @@ func UserGetDtoFromModel(user models.WebauthnUser) UserGetDto {
 	}
 
 	for _, credential := range user.WebauthnCredentials {
-		dto.Credentials = append(dto.Credentials, response.CredentialDtoFromModel(credential))
+		dto.Credentials = append(dto.Credentials, response.CredentialDtoFromModel(credential, nil))
 	}
 
 	for _, transaction := range user.Transactions {
@@ -0,0 +1,93 @@
+package response
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/api/dto/response"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"time"
+)
+
+// UserDataExport contains all data stored about a user, e.g. to answer a data subject access request
+type UserDataExport struct {
+	ExportedAt   time.Time                 `json:"exported_at"`
+	TenantId     uuid.UUID                 `json:"tenant_id"`
+	User         UserDataProfile           `json:"user"`
+	Credentials  []UserDataCredential      `json:"credentials"`
+	Transactions []response.TransactionDto `json:"transactions"`
+	SessionData  []UserDataSession         `json:"session_data"`
+	AuditLogs    models.AuditLogs          `json:"audit_logs"`
+}
+
+type UserDataProfile struct {
+	UserListDto
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// UserDataCredential additionally contains the metadata of the registration of the credential
+type UserDataCredential struct {
+	response.CredentialDto
+	CreatedUserAgent *string `json:"created_user_agent,omitempty"`
+	CreatedIp        *string `json:"created_ip,omitempty"`
+}
+
+// UserDataSession is a pending ceremony of the user
+type UserDataSession struct {
+	ID               uuid.UUID  `json:"id"`
+	Operation        string     `json:"operation"`
+	UserVerification string     `json:"user_verification"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ExpiresAt        *time.Time `json:"expires_at,omitempty"`
+}
+
+type UserErasureDto struct {
+	// Pseudonym replaces the user id in the pseudonymized audit logs
+	Pseudonym              string `json:"pseudonym"`
+	PseudonymizedAuditLogs int    `json:"pseudonymized_audit_logs"`
+}
+
+func UserDataExportFromModels(user models.WebauthnUser, sessionData []models.WebauthnSessionData, auditLogs models.AuditLogs) UserDataExport {
+	export := UserDataExport{
+		ExportedAt: time.Now().UTC(),
+		TenantId:   user.TenantID,
+		User: UserDataProfile{
+			UserListDto: UserListDtoFromModel(user),
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+		},
+		Credentials:  make([]UserDataCredential, 0, len(user.WebauthnCredentials)),
+		Transactions: make([]response.TransactionDto, 0, len(user.Transactions)),
+		SessionData:  make([]UserDataSession, 0, len(sessionData)),
+		AuditLogs:    auditLogs,
+	}
+
+	for _, credential := range user.WebauthnCredentials {
+		export.Credentials = append(export.Credentials, UserDataCredential{
+			CredentialDto:    response.CredentialDtoFromModel(credential, nil),
+			CreatedUserAgent: credential.CreatedUserAgent,
+			CreatedIp:        credential.CreatedIp,
+		})
+	}
+
+	for _, transaction := range user.Transactions {
+		export.Transactions = append(export.Transactions, response.TransactionDtoFromModel(transaction))
+	}
+
+	for _, session := range sessionData {
+		dto := UserDataSession{
+			ID:               session.ID,
+			Operation:        string(session.Operation),
+			UserVerification: session.UserVerification,
+			CreatedAt:        session.CreatedAt,
+		}
+
+		if session.ExpiresAt.Valid {
+			expiresAt := session.ExpiresAt.Time
+			dto.ExpiresAt = &expiresAt
+		}
+
+		export.SessionData = append(export.SessionData, dto)
+	}
+
+	return export
+}
@@ -6,21 +6,51 @@ import (
 )
 
 type GetWebauthnResponse struct {
-	RelyingParty           GetRelyingPartyResponse              `json:"relying_party"`
-	Timeout                int                                  `json:"timeout"`
-	UserVerification       protocol.UserVerificationRequirement `json:"user_verification"`
-	Attachment             *protocol.AuthenticatorAttachment    `json:"attachment,omitempty"`
-	AttestationPreference  protocol.ConveyancePreference        `json:"attestation_preference"`
-	ResidentKeyRequirement protocol.ResidentKeyRequirement      `json:"resident_key_requirement"`
+	RelyingParty             GetRelyingPartyResponse              `json:"relying_party"`
+	AdditionalRelyingParties []GetRelyingPartyResponse            `json:"additional_relying_parties,omitempty"`
+	Timeout                  int                                  `json:"timeout"`
+	UserVerification         protocol.UserVerificationRequirement `json:"user_verification"`
+	Attachment               *protocol.AuthenticatorAttachment    `json:"attachment,omitempty"`
+	AttestationPreference    protocol.ConveyancePreference        `json:"attestation_preference"`
+	ResidentKeyRequirement   protocol.ResidentKeyRequirement      `json:"resident_key_requirement"`
+	TransportStripping       GetTransportStrippingResponse        `json:"transport_stripping"`
+	StoreCreationIp          bool                                 `json:"store_creation_ip"`
+}
+
+type GetTransportStrippingResponse struct {
+	Policy            models.TransportStrippingPolicy `json:"policy"`
+	UserAgentPatterns []string                        `json:"user_agent_patterns,omitempty"`
 }
 
 func ToGetWebauthnResponse(webauthn *models.WebauthnConfig) GetWebauthnResponse {
+	var relyingParty GetRelyingPartyResponse
+	additionalRelyingParties := make([]GetRelyingPartyResponse, 0)
+	for i := range webauthn.RelyingParties {
+		rp := &webauthn.RelyingParties[i]
+		if rp.IsDefault {
+			relyingParty = ToGetRelyingPartyResponse(rp)
+		} else {
+			additionalRelyingParties = append(additionalRelyingParties, ToGetRelyingPartyResponse(rp))
+		}
+	}
+
+	var userAgentPatterns []string
+	for _, rule := range webauthn.TransportStrippingRules {
+		userAgentPatterns = append(userAgentPatterns, rule.UserAgentPattern)
+	}
+
 	return GetWebauthnResponse{
-		RelyingParty:           ToGetRelyingPartyResponse(&webauthn.RelyingParty),
-		Timeout:                webauthn.Timeout,
-		UserVerification:       webauthn.UserVerification,
-		Attachment:             webauthn.Attachment,
-		AttestationPreference:  webauthn.AttestationPreference,
-		ResidentKeyRequirement: webauthn.ResidentKeyRequirement,
+		RelyingParty:             relyingParty,
+		AdditionalRelyingParties: additionalRelyingParties,
+		Timeout:                  webauthn.Timeout,
+		UserVerification:         webauthn.UserVerification,
+		Attachment:               webauthn.Attachment,
+		AttestationPreference:    webauthn.AttestationPreference,
+		ResidentKeyRequirement:   webauthn.ResidentKeyRequirement,
+		TransportStripping: GetTransportStrippingResponse{
+			Policy:            webauthn.TransportStripping,
+			UserAgentPatterns: userAgentPatterns,
+		},
+		StoreCreationIp: webauthn.StoreCreationIp,
 	}
 }
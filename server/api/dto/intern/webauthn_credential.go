@@ -11,7 +11,7 @@ import (
 	"time"
 )
 
-func WebauthnCredentialToModel(credential *webauthn.Credential, userId string, webauthnUserId uuid.UUID, backupEligible bool, backupState bool, authenticatorMetadata mapper.AuthenticatorMetadata, isMFACredential bool) *models.WebauthnCredential {
+func WebauthnCredentialToModel(credential *webauthn.Credential, userId string, webauthnUserId uuid.UUID, backupEligible bool, backupState bool, authenticatorMetadata mapper.AuthenticatorMetadata, isMFACredential bool, rpId string) *models.WebauthnCredential {
 	now := time.Now().UTC()
 	aaguid, _ := uuid.FromBytes(credential.Authenticator.AAGUID)
 	credentialID := base64.RawURLEncoding.EncodeToString(credential.ID)
@@ -35,6 +35,8 @@ func WebauthnCredentialToModel(credential *webauthn.Credential, userId string, w
 		BackupEligible:  backupEligible,
 		BackupState:     backupState,
 		IsMFA:           isMFACredential,
+		RPId:            &rpId,
+		Status:          models.CredentialStatusActive,
 
 		WebauthnUserID: webauthnUserId,
 	}
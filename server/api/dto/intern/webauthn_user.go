@@ -14,13 +14,28 @@ type WebauthnUser struct {
 	IsMfaUser           bool
 }
 
-func NewWebauthnUser(user models.WebauthnUser, isMfaUser bool) *WebauthnUser {
+// NewWebauthnUser creates a webauthn user which only exposes the active credentials usable for the given relying
+// party. Credentials without a relying party (created before multiple relying parties were supported) are always usable.
+func NewWebauthnUser(user models.WebauthnUser, isMfaUser bool, rpId string) *WebauthnUser {
+	credentials := make([]models.WebauthnCredential, 0, len(user.WebauthnCredentials))
+	for _, credential := range user.WebauthnCredentials {
+		if !credential.IsActive() {
+			continue
+		}
+
+		if rpId != "" && credential.RPId != nil && *credential.RPId != rpId {
+			continue
+		}
+
+		credentials = append(credentials, credential)
+	}
+
 	return &WebauthnUser{
 		UserId:              user.UserID,
 		Name:                user.Name,
 		Icon:                user.Icon,
 		DisplayName:         user.DisplayName,
-		WebauthnCredentials: user.WebauthnCredentials,
+		WebauthnCredentials: credentials,
 		IsMfaUser:           isMfaUser,
 	}
 }
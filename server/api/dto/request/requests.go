@@ -2,6 +2,7 @@ package request
 
 import (
 	"encoding/json"
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/gofrs/uuid"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"strings"
@@ -9,7 +10,7 @@ import (
 )
 
 type CredentialRequests interface {
-	ListCredentialsDto | GetCredentialDto | DeleteCredentialsDto | UpdateCredentialsDto
+	ListCredentialsDto | GetCredentialDto | DeleteCredentialsDto | UpdateCredentialsDto | UpdateCredentialStatusDto
 }
 
 type TenantDto struct {
@@ -17,7 +18,17 @@ type TenantDto struct {
 }
 
 type ListCredentialsDto struct {
-	UserId string `query:"user_id" validate:"required"`
+	UserId         string     `query:"user_id" validate:"required"`
+	Page           int        `query:"page" validate:"omitempty,min=1"`
+	PerPage        int        `query:"per_page" validate:"omitempty,min=1,max=100"`
+	SortBy         string     `query:"sort_by" validate:"omitempty,oneof=created_at last_used_at name"`
+	SortDirection  string     `query:"sort_direction" validate:"omitempty,oneof=asc desc"`
+	Type           string     `query:"type" validate:"omitempty,oneof=passkey mfa"`
+	BackupEligible *bool      `query:"backup_eligible"`
+	BackupState    *bool      `query:"backup_state"`
+	AAGUID         string     `query:"aaguid" validate:"omitempty,uuid"`
+	LastUsedBefore *time.Time `query:"last_used_before"`
+	LastUsedAfter  *time.Time `query:"last_used_after"`
 }
 
 type GetCredentialDto struct {
@@ -33,15 +44,21 @@ type UpdateCredentialsDto struct {
 	Name         string `json:"name" validate:"required"`
 }
 
+type UpdateCredentialStatusDto struct {
+	CredentialId string  `param:"credential_id" validate:"required"`
+	Reason       *string `json:"reason" validate:"omitempty,max=255"`
+}
+
 type WebauthnRequests interface {
 	InitRegistrationDto | InitTransactionDto | InitLoginDto | InitMfaLoginDto
 }
 
 type InitRegistrationDto struct {
-	UserId      string  `json:"user_id" validate:"required"`
-	Username    string  `json:"username" validate:"required,max=128"`
-	DisplayName *string `json:"display_name" validate:"omitempty,max=128"`
-	Icon        *string `json:"icon" validate:"omitempty,url"`
+	UserId      string   `json:"user_id" validate:"required"`
+	Username    string   `json:"username" validate:"required,max=128"`
+	DisplayName *string  `json:"display_name" validate:"omitempty,max=128"`
+	Icon        *string  `json:"icon" validate:"omitempty,url"`
+	Hints       []string `json:"hints" validate:"omitempty,unique,dive,oneof=security-key client-device hybrid"`
 }
 
 func (initRegistration *InitRegistrationDto) ToModel() *models.WebauthnUser {
@@ -96,10 +113,25 @@ func (initTransaction *InitTransactionDto) ToModel() (*models.Transaction, error
 	}, nil
 }
 
+// StepUpDto contains the optional requirements for a (re-)authentication before sensitive actions
+type StepUpDto struct {
+	UserVerification *protocol.UserVerificationRequirement `json:"user_verification" validate:"omitempty,oneof=required preferred discouraged"`
+	CredentialId     *string                               `json:"credential_id" validate:"omitempty,min=1"`
+	// Attachment is derived from the transports the client reported at registration, which are not signed. The
+	// attachment of credentials without stored transports is unknown, so they never satisfy it.
+	Attachment *protocol.AuthenticatorAttachment `json:"attachment" validate:"omitempty,oneof=platform cross-platform"`
+}
+
 type InitLoginDto struct {
-	UserId *string `json:"user_id" validate:"omitempty,min=1"`
+	StepUpDto
+	UserId *string  `json:"user_id" validate:"omitempty,min=1"`
+	Hints  []string `json:"hints" validate:"omitempty,unique,dive,oneof=security-key client-device hybrid"`
 }
 
 type InitMfaLoginDto struct {
-	UserId *string `json:"user_id" validate:"required,min=1"`
+	StepUpDto
+	UserId *string  `json:"user_id" validate:"required,min=1"`
+	Hints  []string `json:"hints" validate:"omitempty,unique,dive,oneof=security-key client-device hybrid"`
+	// MfaToken is the intermediate token of a passkey login which requires a MFA login to complete
+	MfaToken *string `json:"mfa_token" validate:"omitempty,min=1"`
 }
@@ -2,6 +2,7 @@ package response
 
 import (
 	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/mapper"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"time"
 )
@@ -18,6 +19,16 @@ type CredentialDto struct {
 	BackupEligible  bool       `json:"backup_eligible"`
 	BackupState     bool       `json:"backup_state"`
 	IsMFA           bool       `json:"is_mfa"`
+	RPId            *string    `json:"rp_id,omitempty"`
+	Status          string     `json:"status"`
+	StatusReason    *string    `json:"status_reason,omitempty"`
+	DisabledAt      *time.Time `json:"disabled_at,omitempty"`
+	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
+	// Device summarizes the browser and operating system the credential was created with, e.g. "Safari on macOS"
+	Device            *string `json:"device,omitempty"`
+	AuthenticatorName *string `json:"authenticator_name,omitempty"`
+	IconLight         *string `json:"icon_light,omitempty"`
+	IconDark          *string `json:"icon_dark,omitempty"`
 }
 
 type CredentialDtoList []CredentialDto
@@ -26,8 +37,15 @@ type TokenDto struct {
 	Token string `json:"token"`
 }
 
-func CredentialDtoFromModel(credential models.WebauthnCredential) CredentialDto {
-	return CredentialDto{
+type MfaRequiredDto struct {
+	State    string `json:"state"`
+	MfaToken string `json:"mfa_token"`
+}
+
+// CredentialDtoFromModel converts the credential. When authenticator metadata is given, the name and icons of the
+// authenticator are added.
+func CredentialDtoFromModel(credential models.WebauthnCredential, authenticatorMetadata mapper.AuthenticatorMetadata) CredentialDto {
+	dto := CredentialDto{
 		ID:              credential.ID,
 		Name:            credential.Name,
 		PublicKey:       credential.PublicKey,
@@ -39,7 +57,25 @@ func CredentialDtoFromModel(credential models.WebauthnCredential) CredentialDto
 		BackupEligible:  credential.BackupEligible,
 		BackupState:     credential.BackupState,
 		IsMFA:           credential.IsMFA,
+		RPId:            credential.RPId,
+		Status:          string(credential.Status),
+		StatusReason:    credential.StatusReason,
+		DisabledAt:      credential.DisabledAt,
+		RevokedAt:       credential.RevokedAt,
+		Device:          credential.CreatedDevice,
 	}
+
+	if authenticator := authenticatorMetadata.GetForAaguid(credential.AAGUID); authenticator != nil {
+		dto.AuthenticatorName = &authenticator.Name
+		if authenticator.IconLight != "" {
+			dto.IconLight = &authenticator.IconLight
+		}
+		if authenticator.IconDark != "" {
+			dto.IconDark = &authenticator.IconDark
+		}
+	}
+
+	return dto
 }
 
 type TransactionDto struct {
@@ -0,0 +1,41 @@
+package response
+
+import "github.com/go-webauthn/webauthn/protocol"
+
+// CredentialCreationDto extends the credential creation options with the WebAuthn Level 3 'hints'
+type CredentialCreationDto struct {
+	Response PublicKeyCredentialCreationOptionsDto `json:"publicKey"`
+}
+
+type PublicKeyCredentialCreationOptionsDto struct {
+	protocol.PublicKeyCredentialCreationOptions
+	Hints []string `json:"hints,omitempty"`
+}
+
+func ToCredentialCreationDto(credentialCreation *protocol.CredentialCreation, hints []string) CredentialCreationDto {
+	return CredentialCreationDto{
+		Response: PublicKeyCredentialCreationOptionsDto{
+			PublicKeyCredentialCreationOptions: credentialCreation.Response,
+			Hints:                              hints,
+		},
+	}
+}
+
+// CredentialAssertionDto extends the credential request options with the WebAuthn Level 3 'hints'
+type CredentialAssertionDto struct {
+	Response PublicKeyCredentialRequestOptionsDto `json:"publicKey"`
+}
+
+type PublicKeyCredentialRequestOptionsDto struct {
+	protocol.PublicKeyCredentialRequestOptions
+	Hints []string `json:"hints,omitempty"`
+}
+
+func ToCredentialAssertionDto(credentialAssertion *protocol.CredentialAssertion, hints []string) CredentialAssertionDto {
+	return CredentialAssertionDto{
+		Response: PublicKeyCredentialRequestOptionsDto{
+			PublicKeyCredentialRequestOptions: credentialAssertion.Response,
+			Hints:                             hints,
+		},
+	}
+}
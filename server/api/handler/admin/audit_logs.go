@@ -0,0 +1,105 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/pagination"
+	"github.com/teamhanko/passkey-server/api/services/admin"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type AuditLogHandler struct {
+	persister persistence.Persister
+}
+
+func NewAuditLogHandler(persister persistence.Persister) *AuditLogHandler {
+	return &AuditLogHandler{
+		persister: persister,
+	}
+}
+
+// List returns the audit logs of admin operations of all tenants, including operations like the deletion of a tenant
+// which are not attached to any tenant.
+func (ah *AuditLogHandler) List(ctx echo.Context) error {
+	var dto request.ListAdminAuditLogDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to list audit logs").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to list audit logs").SetInternal(err)
+	}
+
+	if dto.Page == 0 {
+		dto.Page = 1
+	}
+
+	if dto.PerPage == 0 {
+		dto.PerPage = 20
+	}
+
+	service := admin.NewAuditLogService(ctx, ah.persister.GetAuditLogPersister(nil))
+	u, _ := url.Parse(fmt.Sprintf("%s://%s%s", ctx.Scheme(), ctx.Request().Host, ctx.Request().RequestURI))
+
+	if dto.UsesCursor() {
+		auditLogs, nextCursor, err := service.ListAfter(dto)
+		if err != nil {
+			return err
+		}
+
+		setCursorHeader(ctx, u, nextCursor, dto.PerPage)
+
+		return ctx.JSON(http.StatusOK, auditLogs)
+	}
+
+	auditLogs, logCount, err := service.List(dto)
+	if err != nil {
+		return err
+	}
+
+	ctx.Response().Header().Set("Link", pagination.CreateHeader(u, logCount, dto.Page, dto.PerPage))
+	ctx.Response().Header().Set("X-Total-Count", strconv.FormatInt(int64(logCount), 10))
+
+	return ctx.JSON(http.StatusOK, auditLogs)
+}
+
+// Verify checks the chain of the audit logs which are not attached to a tenant
+func (ah *AuditLogHandler) Verify(ctx echo.Context) error {
+	return verifyAuditLogChain(ctx, ah.persister, nil)
+}
+
+func setCursorHeader(ctx echo.Context, u *url.URL, nextCursor string, perPage int) {
+	if nextCursor == "" {
+		return
+	}
+
+	ctx.Response().Header().Set("Link", pagination.CreateCursorHeader(u, nextCursor, perPage))
+	ctx.Response().Header().Set("X-Next-Cursor", nextCursor)
+}
+
+func verifyAuditLogChain(ctx echo.Context, persister persistence.Persister, tenant *models.Tenant) error {
+	var dto request.VerifyAuditLogDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to verify audit logs").SetInternal(err)
+	}
+
+	verification, err := auditlog.VerifyChain(persister, tenant, dto.StartTime, dto.EndTime)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to verify audit logs").SetInternal(err)
+	}
+
+	return ctx.JSON(http.StatusOK, verification)
+}
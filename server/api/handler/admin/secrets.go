@@ -1,11 +1,14 @@
 package admin
 
 import (
+	"github.com/gobuffalo/pop/v6"
 	"github.com/labstack/echo/v4"
 	adminRequest "github.com/teamhanko/passkey-server/api/dto/admin/request"
 	"github.com/teamhanko/passkey-server/api/helper"
 	"github.com/teamhanko/passkey-server/api/services/admin"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
 	"net/http"
 )
 
@@ -52,13 +55,28 @@ func (s *SecretsHandler) createKey(ctx echo.Context, isApiKey bool) error {
 		return err
 	}
 
-	service := admin.NewSecretService(ctx, *h.Tenant, s.persister.GetSecretsPersister(nil))
-	secretDto, err := service.Create(dto, isApiKey)
-	if err != nil {
-		return err
-	}
-
-	return ctx.JSON(http.StatusCreated, secretDto)
+	return s.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service := admin.NewSecretService(ctx, *h.Tenant, s.persister.GetSecretsPersister(tx))
+		secretDto, err := service.Create(dto, isApiKey)
+		if err != nil {
+			return err
+		}
+
+		auditLogType, targetType := secretAuditLogType(isApiKey, models.AuditLogAdminApiKeyCreated, models.AuditLogAdminJwkCreated)
+		err = helper.NewAdminAuditLogger(ctx, s.persister, tx).Create(auditlog.AdminEntry{
+			Type:       auditLogType,
+			TenantId:   &h.Tenant.ID,
+			TargetType: targetType,
+			TargetId:   secretDto.Id.String(),
+			After:      secretDto,
+		})
+		if err != nil {
+			ctx.Logger().Error(err)
+			return err
+		}
+
+		return ctx.JSON(http.StatusCreated, secretDto)
+	})
 }
 
 func (s *SecretsHandler) RemoveAPIKey(ctx echo.Context) error {
@@ -85,13 +103,27 @@ func (s *SecretsHandler) removeKey(ctx echo.Context, isApiKey bool) error {
 		return err
 	}
 
-	service := admin.NewSecretService(ctx, *h.Tenant, s.persister.GetSecretsPersister(nil))
-	err = service.Remove(dto, isApiKey)
-	if err != nil {
-		return err
-	}
-
-	return ctx.NoContent(http.StatusNoContent)
+	return s.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service := admin.NewSecretService(ctx, *h.Tenant, s.persister.GetSecretsPersister(tx))
+		err := service.Remove(dto, isApiKey)
+		if err != nil {
+			return err
+		}
+
+		auditLogType, targetType := secretAuditLogType(isApiKey, models.AuditLogAdminApiKeyDeleted, models.AuditLogAdminJwkDeleted)
+		err = helper.NewAdminAuditLogger(ctx, s.persister, tx).Create(auditlog.AdminEntry{
+			Type:       auditLogType,
+			TenantId:   &h.Tenant.ID,
+			TargetType: targetType,
+			TargetId:   dto.SecretId,
+		})
+		if err != nil {
+			ctx.Logger().Error(err)
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
 }
 
 func (s *SecretsHandler) ListJWKKeys(ctx echo.Context) error {
@@ -106,6 +138,14 @@ func (s *SecretsHandler) RemoveJWKKey(ctx echo.Context) error {
 	return s.removeKey(ctx, false)
 }
 
+func secretAuditLogType(isApiKey bool, apiKeyType models.AuditLogType, jwkType models.AuditLogType) (models.AuditLogType, models.AuditLogTargetType) {
+	if isApiKey {
+		return apiKeyType, models.AuditLogTargetApiKey
+	}
+
+	return jwkType, models.AuditLogTargetJwk
+}
+
 func NewSecretsHandler(persister persistence.Persister) SecretsHandler {
 	return SecretsHandler{
 		persister: persister,
@@ -0,0 +1,62 @@
+package admin
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/helper"
+	"github.com/teamhanko/passkey-server/api/services/admin"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/persistence"
+	"net/http"
+)
+
+type StatsHandler struct {
+	persister             persistence.Persister
+	cfg                   config.Stats
+	authenticatorMetadata mapper.AuthenticatorMetadata
+}
+
+func NewStatsHandler(persister persistence.Persister, cfg config.Stats, authenticatorMetadata mapper.AuthenticatorMetadata) *StatsHandler {
+	return &StatsHandler{
+		persister:             persister,
+		cfg:                   cfg,
+		authenticatorMetadata: authenticatorMetadata,
+	}
+}
+
+func (sh *StatsHandler) Get(ctx echo.Context) error {
+	var dto request.GetStatsDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to get stats").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to get stats").SetInternal(err)
+	}
+
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	service := admin.NewStatsService(admin.CreateStatsServiceParams{
+		Ctx:                   ctx,
+		Tenant:                h.Tenant,
+		StatsPersister:        sh.persister.GetStatsPersister(nil),
+		AuthenticatorMetadata: sh.authenticatorMetadata,
+		UseRollups:            sh.cfg.Rollup,
+	})
+
+	stats, err := service.Get(dto)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, stats)
+}
@@ -1,18 +1,27 @@
 package admin
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/admin/request"
 	"github.com/teamhanko/passkey-server/api/dto/admin/response"
 	"github.com/teamhanko/passkey-server/api/helper"
 	"github.com/teamhanko/passkey-server/api/pagination"
 	"github.com/teamhanko/passkey-server/api/services/admin"
+	"github.com/teamhanko/passkey-server/archive"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 )
 
 type TenantHandler struct {
@@ -40,8 +49,15 @@ func (th *TenantHandler) List(ctx echo.Context) error {
 }
 
 func (th *TenantHandler) Create(ctx echo.Context) error {
+	body, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to create tenant").SetInternal(err)
+	}
+	ctx.Request().Body = io.NopCloser(bytes.NewReader(body))
+
 	var dto request.CreateTenantDto
-	err := ctx.Bind(&dto)
+	err = ctx.Bind(&dto)
 	if err != nil {
 		ctx.Logger().Error(err)
 		return echo.NewHTTPError(http.StatusBadRequest, "unable to create tenant").SetInternal(err)
@@ -53,9 +69,25 @@ func (th *TenantHandler) Create(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "unable to create tenant").SetInternal(err)
 	}
 
-	return th.persister.Transaction(func(tx *pop.Connection) error {
+	idempotencyKey := strings.TrimSpace(ctx.Request().Header.Get(admin.IdempotencyKeyHeader))
+
+	err = th.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		idempotencyService := admin.NewIdempotencyService(ctx, "create_tenant", th.persister.GetIdempotencyKeyPersister(tx))
+		if idempotencyKey != "" {
+			storedResponse, err := idempotencyService.Lookup(idempotencyKey, body)
+			if err != nil {
+				return err
+			}
+
+			if storedResponse != nil {
+				return ctx.JSONBlob(storedResponse.ResponseStatus, []byte(storedResponse.ResponseBody))
+			}
+		}
+
 		service := admin.NewTenantService(admin.CreateTenantServiceParams{
-			Ctx: ctx,
+			Ctx:         ctx,
+			Actor:       helper.GetActor(ctx),
+			AuditLogger: helper.NewAdminAuditLogger(ctx, th.persister, tx),
 
 			TenantPersister:         th.persister.GetTenantPersister(tx),
 			ConfigPersister:         th.persister.GetConfigPersister(tx),
@@ -66,6 +98,7 @@ func (th *TenantHandler) Create(ctx echo.Context) error {
 			SecretPersister:         th.persister.GetSecretsPersister(tx),
 			JwkPersister:            th.persister.GetJwkPersister(tx),
 			MFAConfigPersister:      th.persister.GetMFAConfigPersister(tx),
+			ConfigVersionPersister:  th.persister.GetConfigVersionPersister(tx),
 		})
 
 		createResponse, err := service.Create(dto)
@@ -73,8 +106,31 @@ func (th *TenantHandler) Create(ctx echo.Context) error {
 			return err
 		}
 
+		if idempotencyKey != "" {
+			// the secret of the API key is only returned by the original response, so it is not kept in the database
+			err = idempotencyService.Store(idempotencyKey, body, http.StatusCreated, createResponse.WithoutSecrets())
+			if err != nil {
+				return err
+			}
+		}
+
 		return ctx.JSON(http.StatusCreated, createResponse)
 	})
+
+	// a concurrent request with the same key was processed first, so its response is replayed
+	if errors.Is(err, admin.ErrIdempotencyKeyNotStored) {
+		idempotencyService := admin.NewIdempotencyService(ctx, "create_tenant", th.persister.GetIdempotencyKeyPersister(nil))
+		storedResponse, lookupErr := idempotencyService.Lookup(idempotencyKey, body)
+		if lookupErr != nil {
+			return lookupErr
+		}
+
+		if storedResponse != nil {
+			return ctx.JSONBlob(storedResponse.ResponseStatus, []byte(storedResponse.ResponseBody))
+		}
+	}
+
+	return err
 }
 
 func (th *TenantHandler) Get(ctx echo.Context) error {
@@ -107,18 +163,22 @@ func (th *TenantHandler) Update(ctx echo.Context) error {
 		return err
 	}
 
-	service := admin.NewTenantService(admin.CreateTenantServiceParams{
-		Ctx:             ctx,
-		Tenant:          h.Tenant,
-		TenantPersister: th.persister.GetTenantPersister(nil),
-	})
+	return th.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service := admin.NewTenantService(admin.CreateTenantServiceParams{
+			Ctx:         ctx,
+			Tenant:      h.Tenant,
+			AuditLogger: helper.NewAdminAuditLogger(ctx, th.persister, tx),
 
-	err = service.Update(dto)
-	if err != nil {
-		return err
-	}
+			TenantPersister: th.persister.GetTenantPersister(tx),
+		})
+
+		err = service.Update(dto)
+		if err != nil {
+			return err
+		}
 
-	return ctx.NoContent(http.StatusNoContent)
+		return ctx.NoContent(http.StatusNoContent)
+	})
 }
 
 func (th *TenantHandler) Remove(ctx echo.Context) error {
@@ -128,12 +188,22 @@ func (th *TenantHandler) Remove(ctx echo.Context) error {
 		return err
 	}
 
-	err = th.persister.GetTenantPersister(nil).Delete(h.Tenant)
-	if err != nil {
-		return err
-	}
+	return th.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service := admin.NewTenantService(admin.CreateTenantServiceParams{
+			Ctx:         ctx,
+			Tenant:      h.Tenant,
+			AuditLogger: helper.NewAdminAuditLogger(ctx, th.persister, tx),
+
+			TenantPersister: th.persister.GetTenantPersister(tx),
+		})
+
+		err := service.Delete()
+		if err != nil {
+			return err
+		}
 
-	return ctx.NoContent(http.StatusNoContent)
+		return ctx.NoContent(http.StatusNoContent)
+	})
 }
 
 func (th *TenantHandler) UpdateConfig(ctx echo.Context) error {
@@ -150,27 +220,120 @@ func (th *TenantHandler) UpdateConfig(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "unable to update tenant config").SetInternal(err)
 	}
 
-	h, err := helper.GetHandlerContext(ctx)
+	return th.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service, err := th.createConfigService(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = service.UpdateConfig(dto, models.ConfigChangeUpdate)
+		if err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
+}
+
+func (th *TenantHandler) PatchConfig(ctx echo.Context) error {
+	patch, err := io.ReadAll(ctx.Request().Body)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to patch tenant config").SetInternal(err)
+	}
+
+	return th.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service, err := th.createConfigService(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		dto, err := service.MergeConfigPatch(patch)
+		if err != nil {
+			return err
+		}
+
+		err = ctx.Validate(dto)
+		if err != nil {
+			ctx.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusBadRequest, "unable to patch tenant config").SetInternal(err)
+		}
+
+		err = service.UpdateConfig(*dto, models.ConfigChangePatch)
+		if err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
+}
+
+func (th *TenantHandler) ListConfigVersions(ctx echo.Context) error {
+	service, err := th.createConfigService(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	versions, err := service.ListConfigVersions()
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, response.ToListConfigVersionResponses(versions))
+}
+
+func (th *TenantHandler) DiffConfigVersions(ctx echo.Context) error {
+	var dto request.DiffConfigVersionsDto
+	err := ctx.Bind(&dto)
 	if err != nil {
 		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to compare config versions").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to compare config versions").SetInternal(err)
+	}
+
+	service, err := th.createConfigService(ctx, nil)
+	if err != nil {
 		return err
 	}
 
-	return th.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
-		service := admin.NewTenantService(admin.CreateTenantServiceParams{
-			Ctx:    ctx,
-			Tenant: h.Tenant,
+	changes, err := service.DiffConfigVersions(dto.From, dto.To)
+	if err != nil {
+		return err
+	}
 
-			ConfigPersister:         th.persister.GetConfigPersister(tx),
-			CorsPersister:           th.persister.GetCorsPersister(tx),
-			WebauthnConfigPersister: th.persister.GetWebauthnConfigPersister(tx),
-			RelyingPartyPerister:    th.persister.GetWebauthnRelyingPartyPersister(tx),
-			AuditConfigPersister:    th.persister.GetAuditLogConfigPersister(tx),
-			SecretPersister:         th.persister.GetSecretsPersister(tx),
-			MFAConfigPersister:      th.persister.GetMFAConfigPersister(tx),
-		})
+	return ctx.JSON(http.StatusOK, response.DiffConfigVersionsResponse{
+		From:    dto.From,
+		To:      dto.To,
+		Changes: changes,
+	})
+}
 
-		err := service.UpdateConfig(dto)
+func (th *TenantHandler) RollbackConfig(ctx echo.Context) error {
+	var dto request.RollbackConfigDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to rollback tenant config").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to rollback tenant config").SetInternal(err)
+	}
+
+	return th.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service, err := th.createConfigService(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = service.RollbackConfig(dto.Version)
 		if err != nil {
 			return err
 		}
@@ -179,6 +342,30 @@ func (th *TenantHandler) UpdateConfig(ctx echo.Context) error {
 	})
 }
 
+func (th *TenantHandler) createConfigService(ctx echo.Context, tx *pop.Connection) (admin.TenantService, error) {
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return nil, err
+	}
+
+	return admin.NewTenantService(admin.CreateTenantServiceParams{
+		Ctx:         ctx,
+		Tenant:      h.Tenant,
+		Actor:       helper.GetActor(ctx),
+		AuditLogger: helper.NewAdminAuditLogger(ctx, th.persister, tx),
+
+		ConfigPersister:         th.persister.GetConfigPersister(tx),
+		CorsPersister:           th.persister.GetCorsPersister(tx),
+		WebauthnConfigPersister: th.persister.GetWebauthnConfigPersister(tx),
+		RelyingPartyPerister:    th.persister.GetWebauthnRelyingPartyPersister(tx),
+		AuditConfigPersister:    th.persister.GetAuditLogConfigPersister(tx),
+		SecretPersister:         th.persister.GetSecretsPersister(tx),
+		MFAConfigPersister:      th.persister.GetMFAConfigPersister(tx),
+		ConfigVersionPersister:  th.persister.GetConfigVersionPersister(tx),
+	}), nil
+}
+
 func (th *TenantHandler) ListAuditLog(ctx echo.Context) error {
 	var dto request.ListAuditLogDto
 	err := ctx.Bind(&dto)
@@ -214,15 +401,226 @@ func (th *TenantHandler) ListAuditLog(ctx echo.Context) error {
 		AuditLogPersister: th.persister.GetAuditLogPersister(nil),
 	})
 
+	u, _ := url.Parse(fmt.Sprintf("%s://%s%s", ctx.Scheme(), ctx.Request().Host, ctx.Request().RequestURI))
+
+	if dto.UsesCursor() {
+		auditLogs, nextCursor, err := service.ListAuditLogsAfter(dto)
+		if err != nil {
+			return err
+		}
+
+		setCursorHeader(ctx, u, nextCursor, dto.PerPage)
+
+		return ctx.JSON(http.StatusOK, auditLogs)
+	}
+
 	auditLogs, logCount, err := service.ListAuditLogs(dto)
 	if err != nil {
 		return err
 	}
 
-	u, _ := url.Parse(fmt.Sprintf("%s://%s%s", ctx.Scheme(), ctx.Request().Host, ctx.Request().RequestURI))
-
 	ctx.Response().Header().Set("Link", pagination.CreateHeader(u, logCount, dto.Page, dto.PerPage))
 	ctx.Response().Header().Set("X-Total-Count", strconv.FormatInt(int64(logCount), 10))
 
 	return ctx.JSON(http.StatusOK, auditLogs)
 }
+
+// ExportAuditLog streams all matching audit logs of the tenant as NDJSON or CSV
+func (th *TenantHandler) ExportAuditLog(ctx echo.Context) error {
+	var dto request.ExportAuditLogDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to export audit logs").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to export audit logs").SetInternal(err)
+	}
+
+	if dto.Format == "" {
+		dto.Format = admin.AuditLogExportFormatNdjson
+	}
+
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	service := admin.NewTenantService(admin.CreateTenantServiceParams{
+		Ctx:    ctx,
+		Tenant: h.Tenant,
+
+		AuditLogPersister: th.persister.GetAuditLogPersister(nil),
+	})
+
+	contentType := "application/x-ndjson"
+	if dto.Format == admin.AuditLogExportFormatCsv {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentType, contentType)
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"audit_logs_%s.%s\"", h.Tenant.ID, dto.Format))
+	ctx.Response().WriteHeader(http.StatusOK)
+
+	// the response is already committed, so a failure can only be logged and the export ends incomplete
+	return service.ExportAuditLogs(dto, ctx.Response())
+}
+
+// VerifyAuditLog checks the hash chain and the signed checkpoints of the audit logs of the tenant
+func (th *TenantHandler) VerifyAuditLog(ctx echo.Context) error {
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	return verifyAuditLogChain(ctx, th.persister, h.Tenant)
+}
+
+func (th *TenantHandler) Export(ctx echo.Context) error {
+	var dto request.ExportTenantDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to export tenant").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to export tenant").SetInternal(err)
+	}
+
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	tenantArchive, err := archive.Export(th.persister, h.Tenant.ID, dto.Passphrase, archive.ExportOptions{
+		IncludeJwks:      dto.IncludeJwks,
+		IncludeAuditLogs: dto.IncludeAuditLogs,
+	})
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to export tenant").SetInternal(err)
+	}
+
+	err = helper.NewAdminAuditLogger(ctx, th.persister, nil).Create(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminTenantExported,
+		TenantId:   &h.Tenant.ID,
+		TargetType: models.AuditLogTargetTenant,
+		TargetId:   h.Tenant.ID.String(),
+		After:      dto,
+	})
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"tenant-%s.json\"", h.Tenant.ID))
+
+	return ctx.JSON(http.StatusOK, tenantArchive)
+}
+
+func (th *TenantHandler) Import(ctx echo.Context) error {
+	var dto request.ImportTenantDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to import tenant").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to import tenant").SetInternal(err)
+	}
+
+	var tenantArchive archive.Archive
+	err = json.Unmarshal(dto.Archive, &tenantArchive)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to parse archive").SetInternal(err)
+	}
+
+	options := archive.ImportOptions{}
+	if dto.TenantId != nil {
+		tenantId := uuid.FromStringOrNil(*dto.TenantId)
+		options.TenantId = &tenantId
+	}
+
+	result, err := archive.Import(ctx, th.persister, &tenantArchive, dto.Passphrase, options)
+	if err != nil {
+		ctx.Logger().Error(err)
+		switch {
+		case errors.Is(err, archive.ErrInvalidArchive):
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		case errors.Is(err, archive.ErrConflict):
+			return echo.NewHTTPError(http.StatusConflict, err.Error()).SetInternal(err)
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, "unable to import tenant").SetInternal(err)
+		}
+	}
+
+	err = helper.NewAdminAuditLogger(ctx, th.persister, nil).Create(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminTenantImported,
+		TenantId:   &result.TenantId,
+		TargetType: models.AuditLogTargetTenant,
+		TargetId:   result.TenantId.String(),
+		After:      result,
+	})
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	return ctx.JSON(http.StatusCreated, result)
+}
+
+func (th *TenantHandler) GetAssetLinks(ctx echo.Context) error {
+	relyingParty, err := th.getRelyingParty(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, response.ToAssetLinksResponse(relyingParty))
+}
+
+func (th *TenantHandler) GetAppleAppSiteAssociation(ctx echo.Context) error {
+	relyingParty, err := th.getRelyingParty(ctx)
+	if err != nil {
+		return err
+	}
+
+	return ctx.JSON(http.StatusOK, response.ToAppleAppSiteAssociationResponse(relyingParty))
+}
+
+func (th *TenantHandler) getRelyingParty(ctx echo.Context) (*models.RelyingParty, error) {
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return nil, err
+	}
+
+	rpId := ctx.QueryParam("rp_id")
+	if rpId == "" {
+		relyingParty := h.Config.WebauthnConfig.GetDefaultRelyingParty()
+		if relyingParty == nil {
+			return nil, echo.NewHTTPError(http.StatusNotFound, "tenant has no relying party configured")
+		}
+
+		return relyingParty, nil
+	}
+
+	relyingParty := h.Config.WebauthnConfig.FindRelyingPartyById(rpId)
+	if relyingParty == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "relying party not found")
+	}
+
+	return relyingParty, nil
+}
@@ -6,10 +6,14 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	adminRequest "github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/dto/response"
 	"github.com/teamhanko/passkey-server/api/helper"
 	"github.com/teamhanko/passkey-server/api/pagination"
 	"github.com/teamhanko/passkey-server/api/services/admin"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -20,6 +24,19 @@ type UserHandler interface {
 	List(ctx echo.Context) error
 	Get(ctx echo.Context) error
 	Remove(ctx echo.Context) error
+	ExportData(ctx echo.Context) error
+	Erase(ctx echo.Context) error
+	Create(ctx echo.Context) error
+	Update(ctx echo.Context) error
+	Disable(ctx echo.Context) error
+	Enable(ctx echo.Context) error
+	ListCredentials(ctx echo.Context) error
+	UpdateCredential(ctx echo.Context) error
+	EnableCredential(ctx echo.Context) error
+	DisableCredential(ctx echo.Context) error
+	RevokeCredential(ctx echo.Context) error
+	RemoveCredential(ctx echo.Context) error
+	ImportCredentials(ctx echo.Context) error
 }
 
 type userHandler struct {
@@ -55,13 +72,30 @@ func (uh *userHandler) List(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "sort_direction must be desc or asc")
 	}
 
+	switch persisters.WebauthnUserSortField(request.SortBy) {
+	case "", persisters.WebauthnUserSortCreatedAt, persisters.WebauthnUserSortLastLoginAt, persisters.WebauthnUserSortUserId,
+		persisters.WebauthnUserSortName, persisters.WebauthnUserSortDisplayName:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "sort_by must be one of created_at, last_login_at, user_id, name or display_name")
+	}
+
+	switch persisters.WebauthnUserCredentialFilter(request.Credentials) {
+	case "", persisters.WebauthnUserWithoutCredentials, persisters.WebauthnUserOnlyMFACredentials:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "credentials must be none or mfa_only")
+	}
+
+	if request.UnusedForDays < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "unused_for_days must not be negative")
+	}
+
 	h, err := helper.GetHandlerContext(ctx)
 	if err != nil {
 		ctx.Logger().Error(err)
 		return err
 	}
 
-	return uh.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
+	return uh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := uh.persister.GetWebauthnUserPersister(tx)
 		userService := admin.NewUserService(admin.CreateUserServiceParams{
 			Ctx:           ctx,
@@ -100,7 +134,7 @@ func (uh *userHandler) Get(ctx echo.Context) error {
 		return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
 	}
 
-	return uh.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
+	return uh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := uh.persister.GetWebauthnUserPersister(tx)
 		userService := admin.NewUserService(admin.CreateUserServiceParams{
 			Ctx:           ctx,
@@ -118,35 +152,329 @@ func (uh *userHandler) Get(ctx echo.Context) error {
 }
 
 func (uh *userHandler) Remove(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		err := userService.Delete(userId)
+		if err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
+}
+
+// ExportData returns all data stored about the user as JSON file
+func (uh *userHandler) ExportData(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		export, err := userService.ExportData(userId)
+		if err != nil {
+			return err
+		}
+
+		ctx.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"user-%s.json\"", userId))
+		return ctx.JSON(http.StatusOK, export)
+	})
+}
+
+// Erase deletes the user and pseudonymizes its audit logs
+func (uh *userHandler) Erase(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		erasure, err := userService.Erase(userId)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, erasure)
+	})
+}
+
+func (uh *userHandler) Create(ctx echo.Context) error {
+	var dto adminRequest.CreateUserDto
+	err := ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to create user").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to create user").SetInternal(err)
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		user, err := userService.Create(dto)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusCreated, user)
+	})
+}
+
+func (uh *userHandler) Update(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	var dto adminRequest.UpdateUserDto
+	err = ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to update user").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to update user").SetInternal(err)
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		user, err := userService.Update(userId, dto)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, user)
+	})
+}
+
+func (uh *userHandler) Disable(ctx echo.Context) error {
+	return uh.setDisabled(ctx, true)
+}
+
+func (uh *userHandler) Enable(ctx echo.Context) error {
+	return uh.setDisabled(ctx, false)
+}
+
+func (uh *userHandler) setDisabled(ctx echo.Context, disabled bool) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		err := userService.SetDisabled(userId, disabled)
+		if err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
+}
+
+func (uh *userHandler) ListCredentials(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		credentials, err := userService.ListCredentials(userId)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, credentials)
+	})
+}
+
+func (uh *userHandler) UpdateCredential(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	var dto adminRequest.UpdateUserCredentialDto
+	err = ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to update credential").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to update credential").SetInternal(err)
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		credential, err := userService.UpdateCredential(userId, ctx.Param("credential_id"), dto)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, credential)
+	})
+}
+
+func (uh *userHandler) EnableCredential(ctx echo.Context) error {
+	return uh.setCredentialStatus(ctx, models.CredentialStatusActive)
+}
+
+func (uh *userHandler) DisableCredential(ctx echo.Context) error {
+	return uh.setCredentialStatus(ctx, models.CredentialStatusDisabled)
+}
+
+func (uh *userHandler) RevokeCredential(ctx echo.Context) error {
+	return uh.setCredentialStatus(ctx, models.CredentialStatusRevoked)
+}
+
+func (uh *userHandler) setCredentialStatus(ctx echo.Context, status models.CredentialStatus) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	var dto adminRequest.UpdateUserCredentialStatusDto
+	err = ctx.Bind(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to update credential").SetInternal(err)
+	}
+
+	err = ctx.Validate(&dto)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusBadRequest, "unable to update credential").SetInternal(err)
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		credential, err := userService.SetCredentialStatus(userId, ctx.Param("credential_id"), status, dto)
+		if err != nil {
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, response.CredentialDtoFromModel(*credential, nil))
+	})
+}
+
+func (uh *userHandler) RemoveCredential(ctx echo.Context) error {
+	userId, err := getUserIdParam(ctx)
+	if err != nil {
+		return err
+	}
+
+	return uh.withUserService(ctx, func(userService admin.UserService, tx *pop.Connection) error {
+		err := userService.DeleteCredential(userId, ctx.Param("credential_id"))
+		if err != nil {
+			return err
+		}
+
+		return ctx.NoContent(http.StatusNoContent)
+	})
+}
+
+// withUserService runs the function in a transaction with a user service of the tenant of the request
+func (uh *userHandler) withUserService(ctx echo.Context, fn func(userService admin.UserService, tx *pop.Connection) error) error {
 	h, err := helper.GetHandlerContext(ctx)
 	if err != nil {
 		ctx.Logger().Error(err)
 		return err
 	}
 
+	return uh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		return fn(admin.NewUserService(admin.CreateUserServiceParams{
+			Ctx:         ctx,
+			Tenant:      *h.Tenant,
+			AuditLogger: helper.NewAdminAuditLogger(ctx, uh.persister, tx),
+
+			UserPersister:        uh.persister.GetWebauthnUserPersister(tx),
+			CredentialPersister:  uh.persister.GetWebauthnCredentialPersister(tx),
+			SessionDataPersister: uh.persister.GetWebauthnSessionDataPersister(tx),
+			AuditLogPersister:    uh.persister.GetAuditLogPersister(tx),
+		}), tx)
+	})
+}
+
+func getUserIdParam(ctx echo.Context) (uuid.UUID, error) {
 	userIdString := ctx.Param("user_id")
 	if userIdString == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "missing user_id")
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "missing user_id")
 	}
 
 	userId, err := uuid.FromString(userIdString)
 	if err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
+		return uuid.Nil, echo.NewHTTPError(http.StatusBadRequest, "invalid user_id")
 	}
 
-	return uh.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
-		userPersister := uh.persister.GetWebauthnUserPersister(tx)
-		userService := admin.NewUserService(admin.CreateUserServiceParams{
-			Ctx:           ctx,
-			Tenant:        *h.Tenant,
-			UserPersister: userPersister,
+	return userId, nil
+}
+
+func (uh *userHandler) ImportCredentials(ctx echo.Context) error {
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	var credentials []adminRequest.ImportCredentialDto
+	if strings.HasPrefix(ctx.Request().Header.Get(echo.HeaderContentType), "text/csv") {
+		credentials, err = adminRequest.ParseImportCredentialsCsv(ctx.Request().Body)
+		if err != nil {
+			ctx.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error()).SetInternal(err)
+		}
+	} else {
+		var dto adminRequest.ImportCredentialsDto
+		err = ctx.Bind(&dto)
+		if err != nil {
+			ctx.Logger().Error(err)
+			return echo.NewHTTPError(http.StatusBadRequest, "unable to import credentials").SetInternal(err)
+		}
+
+		// the credentials are validated one by one by the service, so they can be reported per row
+		if len(dto.Credentials) == 0 || len(dto.Credentials) > adminRequest.MaxImportCredentials {
+			return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("credentials must contain between 1 and %d entries", adminRequest.MaxImportCredentials))
+		}
+
+		credentials = dto.Credentials
+	}
+
+	return uh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		service := admin.NewCredentialImportService(admin.CreateCredentialImportServiceParams{
+			Ctx:    ctx,
+			Tenant: *h.Tenant,
+
+			UserPersister:       uh.persister.GetWebauthnUserPersister(tx),
+			CredentialPersister: uh.persister.GetWebauthnCredentialPersister(tx),
 		})
 
-		err := userService.Delete(userId)
+		result, err := service.Import(credentials)
 		if err != nil {
 			return err
 		}
 
-		return ctx.NoContent(http.StatusNoContent)
+		err = helper.NewAdminAuditLogger(ctx, uh.persister, tx).Create(auditlog.AdminEntry{
+			Type:       models.AuditLogAdminCredentialsImported,
+			TenantId:   &h.Tenant.ID,
+			TargetType: models.AuditLogTargetCredential,
+			After: map[string]int{
+				"imported": result.Imported,
+				"failed":   result.Failed,
+			},
+		})
+		if err != nil {
+			ctx.Logger().Error(err)
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, result)
 	})
 }
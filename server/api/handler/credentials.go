@@ -1,15 +1,20 @@
 package handler
 
 import (
+	"fmt"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/request"
 	"github.com/teamhanko/passkey-server/api/dto/response"
 	"github.com/teamhanko/passkey-server/api/helper"
+	"github.com/teamhanko/passkey-server/api/pagination"
 	"github.com/teamhanko/passkey-server/api/services"
+	"github.com/teamhanko/passkey-server/mapper"
 	"github.com/teamhanko/passkey-server/persistence"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"net/http"
+	"net/url"
+	"strconv"
 )
 
 type CredentialsHandler interface {
@@ -17,17 +22,22 @@ type CredentialsHandler interface {
 	Get(ctx echo.Context) error
 	Update(ctx echo.Context) error
 	Delete(ctx echo.Context) error
+	Enable(ctx echo.Context) error
+	Disable(ctx echo.Context) error
+	Revoke(ctx echo.Context) error
 }
 
 type credentialsHandler struct {
 	*webauthnHandler
+	authenticatorMetadata mapper.AuthenticatorMetadata
 }
 
-func NewCredentialsHandler(persister persistence.Persister) CredentialsHandler {
+func NewCredentialsHandler(persister persistence.Persister, authenticatorMetadata mapper.AuthenticatorMetadata) CredentialsHandler {
 	webauthnHandler := newWebAuthnHandler(persister, false)
 
 	return &credentialsHandler{
 		webauthnHandler,
+		authenticatorMetadata,
 	}
 }
 
@@ -38,18 +48,35 @@ func (credHandler *credentialsHandler) List(ctx echo.Context) error {
 		return err
 	}
 
+	// requests without pagination parameters return all credentials, as they did before pagination was supported
+	paginated := requestDto.Page != 0 || requestDto.PerPage != 0
+	if paginated && requestDto.Page == 0 {
+		requestDto.Page = 1
+	}
+
+	if paginated && requestDto.PerPage == 0 {
+		requestDto.PerPage = 20
+	}
+
 	h, err := helper.GetHandlerContext(ctx)
 	if err != nil {
 		ctx.Logger().Error(err)
 		return err
 	}
 
-	service := services.NewCredentialService(ctx, *h.Tenant, credHandler.persister.GetWebauthnCredentialPersister(nil))
-	dtos, err := service.List(*requestDto)
+	service := services.NewCredentialService(ctx, *h.Tenant, credHandler.persister.GetWebauthnCredentialPersister(nil), credHandler.authenticatorMetadata)
+	dtos, count, err := service.List(*requestDto)
 	if err != nil {
 		return err
 	}
 
+	if paginated {
+		u, _ := url.Parse(fmt.Sprintf("%s://%s%s", ctx.Scheme(), ctx.Request().Host, ctx.Request().RequestURI))
+		ctx.Response().Header().Set("Link", pagination.CreateHeader(u, count, requestDto.Page, requestDto.PerPage))
+	}
+
+	ctx.Response().Header().Set("X-Total-Count", strconv.FormatInt(int64(count), 10))
+
 	return ctx.JSON(http.StatusOK, dtos)
 }
 
@@ -66,13 +93,13 @@ func (credHandler *credentialsHandler) Get(ctx echo.Context) error {
 		return err
 	}
 
-	service := services.NewCredentialService(ctx, *h.Tenant, credHandler.persister.GetWebauthnCredentialPersister(nil))
+	service := services.NewCredentialService(ctx, *h.Tenant, credHandler.persister.GetWebauthnCredentialPersister(nil), credHandler.authenticatorMetadata)
 	credential, err := service.Get(*requestDto)
 	if err != nil {
 		return err
 	}
 
-	credentialDto := response.CredentialDtoFromModel(*credential)
+	credentialDto := response.CredentialDtoFromModel(*credential, credHandler.authenticatorMetadata)
 
 	return ctx.JSON(http.StatusOK, credentialDto)
 }
@@ -89,10 +116,10 @@ func (credHandler *credentialsHandler) Update(ctx echo.Context) error {
 		return err
 	}
 
-	return credHandler.persister.Transaction(func(tx *pop.Connection) error {
+	return credHandler.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		credentialPersister := credHandler.persister.GetWebauthnCredentialPersister(tx)
 
-		service := services.NewCredentialService(ctx, *h.Tenant, credentialPersister)
+		service := services.NewCredentialService(ctx, *h.Tenant, credentialPersister, credHandler.authenticatorMetadata)
 		credential, err := service.Update(*requestDto)
 		if err != nil {
 			return err
@@ -120,10 +147,10 @@ func (credHandler *credentialsHandler) Delete(ctx echo.Context) error {
 		return err
 	}
 
-	return credHandler.persister.Transaction(func(tx *pop.Connection) error {
+	return credHandler.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		credentialPersister := credHandler.persister.GetWebauthnCredentialPersister(tx)
 
-		service := services.NewCredentialService(ctx, *h.Tenant, credentialPersister)
+		service := services.NewCredentialService(ctx, *h.Tenant, credentialPersister, credHandler.authenticatorMetadata)
 		err := service.Delete(*requestDto)
 		if err != nil {
 			return err
@@ -138,3 +165,46 @@ func (credHandler *credentialsHandler) Delete(ctx echo.Context) error {
 		return ctx.NoContent(http.StatusNoContent)
 	})
 }
+
+func (credHandler *credentialsHandler) Enable(ctx echo.Context) error {
+	return credHandler.setStatus(ctx, models.CredentialStatusActive, models.AuditLogWebAuthnCredentialEnabled)
+}
+
+func (credHandler *credentialsHandler) Disable(ctx echo.Context) error {
+	return credHandler.setStatus(ctx, models.CredentialStatusDisabled, models.AuditLogWebAuthnCredentialDisabled)
+}
+
+func (credHandler *credentialsHandler) Revoke(ctx echo.Context) error {
+	return credHandler.setStatus(ctx, models.CredentialStatusRevoked, models.AuditLogWebAuthnCredentialRevoked)
+}
+
+func (credHandler *credentialsHandler) setStatus(ctx echo.Context, status models.CredentialStatus, auditLogType models.AuditLogType) error {
+	requestDto, err := BindAndValidateRequest[request.UpdateCredentialStatusDto](ctx)
+	if err != nil {
+		return err
+	}
+
+	h, err := helper.GetHandlerContext(ctx)
+	if err != nil {
+		ctx.Logger().Error(err)
+		return err
+	}
+
+	return credHandler.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
+		credentialPersister := credHandler.persister.GetWebauthnCredentialPersister(tx)
+
+		service := services.NewCredentialService(ctx, *h.Tenant, credentialPersister, credHandler.authenticatorMetadata)
+		credential, err := service.SetStatus(*requestDto, status)
+		if err != nil {
+			return err
+		}
+
+		err = h.AuditLog.CreateWithConnection(tx, auditLogType, &credential.UserId, nil, nil)
+		if err != nil {
+			ctx.Logger().Error(err)
+			return err
+		}
+
+		return ctx.JSON(http.StatusOK, response.CredentialDtoFromModel(*credential, credHandler.authenticatorMetadata))
+	})
+}
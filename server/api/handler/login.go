@@ -10,6 +10,7 @@ import (
 	"github.com/teamhanko/passkey-server/api/helper"
 	"github.com/teamhanko/passkey-server/api/services"
 	auditlog "github.com/teamhanko/passkey-server/audit_log"
+	"github.com/teamhanko/passkey-server/crypto/jwt"
 	"github.com/teamhanko/passkey-server/persistence"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"net/http"
@@ -52,7 +53,7 @@ func (lh *loginHandler) Init(ctx echo.Context) error {
 		}
 	}
 
-	return lh.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
+	return lh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := lh.persister.GetWebauthnUserPersister(tx)
 		sessionPersister := lh.persister.GetWebauthnSessionDataPersister(tx)
 		credentialPersister := lh.persister.GetWebauthnCredentialPersister(tx)
@@ -61,13 +62,18 @@ func (lh *loginHandler) Init(ctx echo.Context) error {
 			Ctx:                 ctx,
 			Tenant:              *h.Tenant,
 			WebauthnClient:      *h.WebauthnClient,
+			RelyingParty:        h.RelyingParty,
 			UserId:              dto.UserId,
 			UserPersister:       userPersister,
 			SessionPersister:    sessionPersister,
 			CredentialPersister: credentialPersister,
 		})
 
-		credentialAssertion, err := service.Initialize()
+		credentialAssertion, err := service.Initialize(services.StepUpOptions{
+			UserVerification: dto.UserVerification,
+			CredentialId:     dto.CredentialId,
+			Attachment:       dto.Attachment,
+		})
 		err = lh.handleError(h.AuditLog, models.AuditLogWebAuthnAuthenticationInitFailed, tx, ctx, dto.UserId, nil, err)
 		if err != nil {
 			return err
@@ -79,7 +85,7 @@ func (lh *loginHandler) Init(ctx echo.Context) error {
 			return fmt.Errorf(auditlog.CreationFailureFormat, auditErr)
 		}
 
-		return ctx.JSON(http.StatusOK, credentialAssertion)
+		return ctx.JSON(http.StatusOK, response.ToCredentialAssertionDto(credentialAssertion, dto.Hints))
 	})
 }
 
@@ -96,7 +102,7 @@ func (lh *loginHandler) Finish(ctx echo.Context) error {
 		return err
 	}
 
-	return lh.persister.Transaction(func(tx *pop.Connection) error {
+	return lh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := lh.persister.GetWebauthnUserPersister(tx)
 		sessionPersister := lh.persister.GetWebauthnSessionDataPersister(tx)
 		credentialPersister := lh.persister.GetWebauthnCredentialPersister(tx)
@@ -105,18 +111,29 @@ func (lh *loginHandler) Finish(ctx echo.Context) error {
 			Ctx:                 ctx,
 			Tenant:              *h.Tenant,
 			WebauthnClient:      *h.WebauthnClient,
+			RelyingParty:        h.RelyingParty,
 			UserPersister:       userPersister,
 			SessionPersister:    sessionPersister,
 			CredentialPersister: credentialPersister,
 			Generator:           h.Generator,
 		})
 
-		token, userId, err := service.Finalize(parsedRequest)
+		token, userId, mfaRequired, err := service.Finalize(parsedRequest)
 		err = lh.handleError(h.AuditLog, models.AuditLogWebAuthnAuthenticationFinalFailed, tx, ctx, &userId, nil, err)
 		if err != nil {
 			return err
 		}
 
+		if mfaRequired {
+			auditErr := h.AuditLog.CreateWithConnection(tx, models.AuditLogWebAuthnAuthenticationMfaRequired, &userId, nil, nil)
+			if auditErr != nil {
+				ctx.Logger().Error(auditErr)
+				return fmt.Errorf(auditlog.CreationFailureFormat, auditErr)
+			}
+
+			return ctx.JSON(http.StatusOK, &response.MfaRequiredDto{State: jwt.MfaRequiredState, MfaToken: token})
+		}
+
 		auditErr := h.AuditLog.CreateWithConnection(tx, models.AuditLogWebAuthnAuthenticationFinalSucceeded, &userId, nil, nil)
 		if auditErr != nil {
 			ctx.Logger().Error(auditErr)
@@ -39,7 +39,7 @@ func (lh *mfaLoginHandler) Init(ctx echo.Context) error {
 		return err
 	}
 
-	return lh.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
+	return lh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := lh.persister.GetWebauthnUserPersister(tx)
 		sessionPersister := lh.persister.GetWebauthnSessionDataPersister(tx)
 		credentialPersister := lh.persister.GetWebauthnCredentialPersister(tx)
@@ -48,14 +48,21 @@ func (lh *mfaLoginHandler) Init(ctx echo.Context) error {
 			Ctx:                 ctx,
 			Tenant:              *h.Tenant,
 			WebauthnClient:      *h.WebauthnClient,
+			RelyingParty:        h.RelyingParty,
 			UserId:              dto.UserId,
+			MfaToken:            dto.MfaToken,
 			UserPersister:       userPersister,
 			SessionPersister:    sessionPersister,
 			CredentialPersister: credentialPersister,
+			Generator:           h.Generator,
 			UseMFA:              true,
 		})
 
-		credentialAssertion, err := service.Initialize()
+		credentialAssertion, err := service.Initialize(services.StepUpOptions{
+			UserVerification: dto.UserVerification,
+			CredentialId:     dto.CredentialId,
+			Attachment:       dto.Attachment,
+		})
 		err = lh.handleError(h.AuditLog, models.AuditLogMfaAuthenticationInitFailed, tx, ctx, dto.UserId, nil, err)
 		if err != nil {
 			return err
@@ -67,7 +74,7 @@ func (lh *mfaLoginHandler) Init(ctx echo.Context) error {
 			return fmt.Errorf(auditlog.CreationFailureFormat, auditErr)
 		}
 
-		return ctx.JSON(http.StatusOK, credentialAssertion)
+		return ctx.JSON(http.StatusOK, response.ToCredentialAssertionDto(credentialAssertion, dto.Hints))
 	})
 }
 
@@ -84,7 +91,7 @@ func (lh *mfaLoginHandler) Finish(ctx echo.Context) error {
 		return err
 	}
 
-	return lh.persister.Transaction(func(tx *pop.Connection) error {
+	return lh.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := lh.persister.GetWebauthnUserPersister(tx)
 		sessionPersister := lh.persister.GetWebauthnSessionDataPersister(tx)
 		credentialPersister := lh.persister.GetWebauthnCredentialPersister(tx)
@@ -93,6 +100,7 @@ func (lh *mfaLoginHandler) Finish(ctx echo.Context) error {
 			Ctx:                 ctx,
 			Tenant:              *h.Tenant,
 			WebauthnClient:      *h.WebauthnClient,
+			RelyingParty:        h.RelyingParty,
 			UserPersister:       userPersister,
 			SessionPersister:    sessionPersister,
 			CredentialPersister: credentialPersister,
@@ -100,7 +108,7 @@ func (lh *mfaLoginHandler) Finish(ctx echo.Context) error {
 			UseMFA:              true,
 		})
 
-		token, userId, err := service.Finalize(parsedRequest)
+		token, userId, _, err := service.Finalize(parsedRequest)
 		err = lh.handleError(h.AuditLog, models.AuditLogMfaAuthenticationFinalFailed, tx, ctx, &userId, nil, err)
 		if err != nil {
 			return err
@@ -48,7 +48,7 @@ func (r *registrationHandler) Init(ctx echo.Context) error {
 		return err
 	}
 
-	return r.persister.Transaction(func(tx *pop.Connection) error {
+	return r.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := r.persister.GetWebauthnUserPersister(tx)
 		sessionPersister := r.persister.GetWebauthnSessionDataPersister(tx)
 		credentialPersister := r.persister.GetWebauthnCredentialPersister(tx)
@@ -57,6 +57,7 @@ func (r *registrationHandler) Init(ctx echo.Context) error {
 			Ctx:                 ctx,
 			Tenant:              *h.Tenant,
 			WebauthnClient:      *h.WebauthnClient,
+			RelyingParty:        h.RelyingParty,
 			UserPersister:       userPersister,
 			SessionPersister:    sessionPersister,
 			CredentialPersister: credentialPersister,
@@ -84,7 +85,7 @@ func (r *registrationHandler) Init(ctx echo.Context) error {
 			return err
 		}
 
-		return ctx.JSON(http.StatusOK, credentialCreation)
+		return ctx.JSON(http.StatusOK, response.ToCredentialCreationDto(credentialCreation, dto.Hints))
 	})
 }
 
@@ -108,7 +109,7 @@ func (r *registrationHandler) Finish(ctx echo.Context) error {
 		return err
 	}
 
-	return r.persister.Transaction(func(tx *pop.Connection) error {
+	return r.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		userPersister := r.persister.GetWebauthnUserPersister(tx)
 		sessionPersister := r.persister.GetWebauthnSessionDataPersister(tx)
 		credentialPersister := r.persister.GetWebauthnCredentialPersister(tx)
@@ -117,6 +118,7 @@ func (r *registrationHandler) Finish(ctx echo.Context) error {
 			Ctx:                   ctx,
 			Tenant:                *h.Tenant,
 			WebauthnClient:        *h.WebauthnClient,
+			RelyingParty:          h.RelyingParty,
 			UserPersister:         userPersister,
 			SessionPersister:      sessionPersister,
 			CredentialPersister:   credentialPersister,
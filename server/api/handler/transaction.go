@@ -52,18 +52,21 @@ func (t *transactionHandler) Init(ctx echo.Context) error {
 		return err
 	}
 
-	return t.persister.GetConnection().Transaction(func(tx *pop.Connection) error {
+	return t.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		sessionDataPersister := t.persister.GetWebauthnSessionDataPersister(tx)
 		webauthnUserPersister := t.persister.GetWebauthnUserPersister(tx)
+		credentialPersister := t.persister.GetWebauthnCredentialPersister(tx)
 		transactionPersister := t.persister.GetTransactionPersister(tx)
 
 		service := services.NewTransactionService(services.TransactionServiceCreateParams{
 			WebauthnServiceCreateParams: &services.WebauthnServiceCreateParams{
-				Ctx:              ctx,
-				Tenant:           *h.Tenant,
-				WebauthnClient:   *h.WebauthnClient,
-				UserPersister:    webauthnUserPersister,
-				SessionPersister: sessionDataPersister,
+				Ctx:                 ctx,
+				Tenant:              *h.Tenant,
+				WebauthnClient:      *h.WebauthnClient,
+				RelyingParty:        h.RelyingParty,
+				UserPersister:       webauthnUserPersister,
+				SessionPersister:    sessionDataPersister,
+				CredentialPersister: credentialPersister,
 			},
 			TransactionPersister: transactionPersister,
 		})
@@ -98,7 +101,7 @@ func (t *transactionHandler) Finish(ctx echo.Context) error {
 		return err
 	}
 
-	return t.persister.Transaction(func(tx *pop.Connection) error {
+	return t.persister.TransactionContext(ctx.Request().Context(), func(tx *pop.Connection) error {
 		sessionDataPersister := t.persister.GetWebauthnSessionDataPersister(tx)
 		webauthnUserPersister := t.persister.GetWebauthnUserPersister(tx)
 		credentialPersister := t.persister.GetWebauthnCredentialPersister(tx)
@@ -109,6 +112,7 @@ func (t *transactionHandler) Finish(ctx echo.Context) error {
 				Ctx:                 ctx,
 				Tenant:              *h.Tenant,
 				WebauthnClient:      *h.WebauthnClient,
+				RelyingParty:        h.RelyingParty,
 				UserPersister:       webauthnUserPersister,
 				SessionPersister:    sessionDataPersister,
 				CredentialPersister: credentialPersister,
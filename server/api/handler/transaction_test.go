@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/api/validators"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+)
+
+type fakePersister struct {
+	persistence.Persister
+	users        *fakeUserPersister
+	sessionData  *fakeSessionDataPersister
+	credentials  *fakeCredentialPersister
+	transactions *fakeTransactionPersister
+}
+
+func (p *fakePersister) TransactionContext(_ context.Context, fn func(tx *pop.Connection) error) error {
+	return fn(nil)
+}
+
+func (p *fakePersister) GetWebauthnUserPersister(_ *pop.Connection) persisters.WebauthnUserPersister {
+	return p.users
+}
+
+func (p *fakePersister) GetWebauthnSessionDataPersister(_ *pop.Connection) persisters.WebauthnSessionDataPersister {
+	return p.sessionData
+}
+
+func (p *fakePersister) GetWebauthnCredentialPersister(_ *pop.Connection) persisters.WebauthnCredentialPersister {
+	return p.credentials
+}
+
+func (p *fakePersister) GetTransactionPersister(_ *pop.Connection) persisters.TransactionPersister {
+	return p.transactions
+}
+
+type fakeUserPersister struct {
+	persisters.WebauthnUserPersister
+	user *models.WebauthnUser
+}
+
+func (p *fakeUserPersister) GetByUserId(userId string, _ uuid.UUID) (*models.WebauthnUser, error) {
+	if p.user == nil || p.user.UserID != userId {
+		return nil, nil
+	}
+
+	return p.user, nil
+}
+
+func (p *fakeUserPersister) WithContext(_ context.Context) persisters.WebauthnUserPersister {
+	return p
+}
+
+type fakeSessionDataPersister struct {
+	persisters.WebauthnSessionDataPersister
+	created []models.WebauthnSessionData
+}
+
+func (p *fakeSessionDataPersister) Create(sessionData models.WebauthnSessionData) error {
+	p.created = append(p.created, sessionData)
+	return nil
+}
+
+func (p *fakeSessionDataPersister) WithContext(_ context.Context) persisters.WebauthnSessionDataPersister {
+	return p
+}
+
+type fakeCredentialPersister struct {
+	persisters.WebauthnCredentialPersister
+	bound bool
+}
+
+func (p *fakeCredentialPersister) WithContext(_ context.Context) persisters.WebauthnCredentialPersister {
+	p.bound = true
+	return p
+}
+
+type fakeTransactionPersister struct {
+	persisters.TransactionPersister
+	created []models.Transaction
+}
+
+func (p *fakeTransactionPersister) GetByIdentifier(_ string, _ uuid.UUID) (*models.Transactions, error) {
+	return &models.Transactions{}, nil
+}
+
+func (p *fakeTransactionPersister) Create(transaction *models.Transaction) error {
+	p.created = append(p.created, *transaction)
+	return nil
+}
+
+func (p *fakeTransactionPersister) WithContext(_ context.Context) persisters.TransactionPersister {
+	return p
+}
+
+type fakeAuditLogger struct {
+	types []models.AuditLogType
+}
+
+func (l *fakeAuditLogger) Create(logType models.AuditLogType, _ *string, _ *models.Transaction, _ error) error {
+	l.types = append(l.types, logType)
+	return nil
+}
+
+func (l *fakeAuditLogger) CreateWithConnection(_ *pop.Connection, logType models.AuditLogType, _ *string, _ *models.Transaction, _ error) error {
+	l.types = append(l.types, logType)
+	return nil
+}
+
+func TestTransactionInit(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	userId, _ := uuid.NewV4()
+	credentialUserId, _ := uuid.NewV4()
+
+	persister := &fakePersister{
+		users: &fakeUserPersister{user: &models.WebauthnUser{
+			ID:       credentialUserId,
+			UserID:   userId.String(),
+			Name:     "john.doe",
+			TenantID: tenantId,
+			WebauthnCredentials: models.WebauthnCredentials{
+				{ID: "Y3JlZGVudGlhbA", WebauthnUserID: credentialUserId, Status: models.CredentialStatusActive},
+			},
+		}},
+		sessionData:  &fakeSessionDataPersister{},
+		credentials:  &fakeCredentialPersister{},
+		transactions: &fakeTransactionPersister{},
+	}
+
+	webauthnClient, err := webauthn.New(&webauthn.Config{
+		RPID:          "localhost",
+		RPDisplayName: "Test",
+		RPOrigins:     []string{"http://localhost"},
+	})
+	require.NoError(t, err)
+
+	auditLogger := &fakeAuditLogger{}
+
+	e := echo.New()
+	e.Validator = validators.NewCustomValidator()
+	body := `{"user_id":"` + userId.String() + `","transaction_id":"transfer-1","transaction_data":{"amount":100}}`
+	req := httptest.NewRequest(http.MethodPost, "/transaction/initialize", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	ctx := e.NewContext(req, rec)
+	ctx.Set("tenant", &models.Tenant{ID: tenantId})
+	ctx.Set("webauthn_client", webauthnClient)
+	ctx.Set("audit_logger", auditLogger)
+
+	err = NewTransactionHandler(persister).Init(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, persister.credentials.bound)
+	require.Len(t, persister.transactions.created, 1)
+	assert.Equal(t, "transfer-1", persister.transactions.created[0].Identifier)
+	assert.Equal(t, credentialUserId, persister.transactions.created[0].WebauthnUserID)
+	require.Len(t, persister.sessionData.created, 1)
+	assert.Equal(t, models.WebauthnOperationTransaction, persister.sessionData.created[0].Operation)
+	assert.Equal(t, []models.AuditLogType{models.AuditLogWebAuthnTransactionInitSucceeded}, auditLogger.types)
+}
@@ -2,10 +2,13 @@ package handler
 
 import (
 	"errors"
+	"fmt"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/request"
+	"github.com/teamhanko/passkey-server/api/services"
 	auditlog "github.com/teamhanko/passkey-server/audit_log"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"io"
@@ -32,7 +35,19 @@ func newWebAuthnHandler(persister persistence.Persister, useMFAClient bool) *web
 
 func (w *webauthnHandler) handleError(logger auditlog.Logger, logType models.AuditLogType, tx *pop.Connection, ctx echo.Context, userId *string, transaction *models.Transaction, logError error) error {
 	if logError != nil {
-		auditErr := logger.CreateWithConnection(tx, logType, userId, transaction, logError)
+		auditLogError := logError
+		if errors.Is(logError, services.ErrUserDisabled) {
+			// the audit log type does not contain the rejected step anymore, so it is counted here
+			if tenant, ok := ctx.Get("tenant").(*models.Tenant); ok && tenant != nil {
+				metrics.ObserveCeremony(tenant.ID, logType, metrics.ErrorClassUserDisabled)
+			}
+
+			// keep the rejected operation, as the type does not contain it anymore
+			auditLogError = fmt.Errorf("%s: %w", logType, logError)
+			logType = models.AuditLogUserDisabledRejected
+		}
+
+		auditErr := logger.CreateWithConnection(tx, logType, userId, transaction, auditLogError)
 		if auditErr != nil {
 			ctx.Logger().Error(auditErr)
 			return auditErr
@@ -0,0 +1,34 @@
+package helper
+
+import (
+	"github.com/gobuffalo/pop/v6"
+	"github.com/labstack/echo/v4"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
+	"github.com/teamhanko/passkey-server/persistence"
+	"strings"
+)
+
+const ActorHeader = "X-Actor"
+
+// GetActor returns who is performing an admin request. The admin API has no authentication of its own, so the
+// caller may identify itself with the 'X-Actor' header. Otherwise, the IP of the caller is used.
+func GetActor(ctx echo.Context) *string {
+	actor := strings.TrimSpace(ctx.Request().Header.Get(ActorHeader))
+	if actor == "" {
+		actor = ctx.RealIP()
+	}
+
+	if actor == "" {
+		return nil
+	}
+
+	return &actor
+}
+
+// NewAdminAuditLogger creates an audit logger for the actor of an admin request, which also sends the entries to the
+// admin audit sinks
+func NewAdminAuditLogger(ctx echo.Context, persister persistence.Persister, tx *pop.Connection) auditlog.AdminLogger {
+	sinks, _ := ctx.Get("admin_audit_sinks").([]auditlog.Sink)
+
+	return auditlog.NewAdminLogger(ctx, persister.GetAuditLogPersister(tx), GetActor(ctx), sinks)
+}
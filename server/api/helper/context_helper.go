@@ -13,6 +13,7 @@ type WebauthnContext struct {
 	Tenant         *models.Tenant
 	WebauthnClient *webauthn.WebAuthn
 	Config         models.Config
+	RelyingParty   *models.RelyingParty
 	AuditLog       auditlog.Logger
 	Generator      jwt.Generator
 }
@@ -36,6 +37,12 @@ func getContext(ctx echo.Context, webauthnClientKey string) (*WebauthnContext, e
 		jwtGenerator = jwtGeneratorCtx.(jwt.Generator)
 	}
 
+	ctxRelyingParty := ctx.Get("relying_party")
+	var relyingParty *models.RelyingParty
+	if ctxRelyingParty != nil {
+		relyingParty = ctxRelyingParty.(*models.RelyingParty)
+	}
+
 	ctxAuditLog := ctx.Get("audit_logger")
 	var auditLogger auditlog.Logger
 	if ctxAuditLog != nil {
@@ -46,6 +53,7 @@ func getContext(ctx echo.Context, webauthnClientKey string) (*WebauthnContext, e
 		Tenant:         tenant,
 		WebauthnClient: webauthnClient,
 		Config:         tenant.Config,
+		RelyingParty:   relyingParty,
 		AuditLog:       auditLogger,
 		Generator:      jwtGenerator,
 	}, nil
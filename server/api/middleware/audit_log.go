@@ -8,7 +8,18 @@ import (
 	"net/http"
 )
 
-func AuditLogger(persister persistence.Persister) echo.MiddlewareFunc {
+// AdminAuditSinks provides the sinks which receive the audit logs of the admin API
+func AdminAuditSinks(sinks []auditlog.Sink) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			ctx.Set("admin_audit_sinks", sinks)
+
+			return next(ctx)
+		}
+	}
+}
+
+func AuditLogger(persister persistence.Persister, sinks *auditlog.Sinks, enricher *auditlog.Enricher) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(ctx echo.Context) error {
 			tenant := ctx.Get("tenant").(*models.Tenant)
@@ -19,7 +30,7 @@ func AuditLogger(persister persistence.Persister) echo.MiddlewareFunc {
 
 			auditLogConfig := tenant.Config.AuditLogConfig
 
-			auditLogger := auditlog.NewLogger(persister, auditLogConfig, ctx, tenant)
+			auditLogger := auditlog.NewLogger(persister, auditLogConfig, ctx, tenant, sinks.Select(auditLogConfig.Sinks), enricher)
 			ctx.Set("audit_logger", auditLogger)
 
 			return next(ctx)
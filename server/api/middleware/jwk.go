@@ -38,14 +38,16 @@ func JWKMiddleware(persister persistence.Persister) echo.MiddlewareFunc {
 }
 
 func instantiateJwtGenerator(ctx echo.Context, keys []string, tenant models.Tenant, persister persistence.Persister) error {
-	jwkManager, err := hankoJwk.NewDefaultManager(keys, tenant.ID, persister.GetJwkPersister(nil))
+	requestCtx := ctx.Request().Context()
+	jwkManager, err := hankoJwk.NewDefaultManager(requestCtx, keys, tenant.ID, persister.GetJwkPersister(persister.GetConnection().WithContext(requestCtx)))
 	if err != nil {
 		ctx.Logger().Error(err)
 		return err
 	}
 	ctx.Set("jwk_manager", jwkManager)
 
-	generator, err := jwt.NewGenerator(&tenant.Config.WebauthnConfig, jwkManager, tenant.ID)
+	relyingParty := ctx.Get("relying_party").(*models.RelyingParty)
+	generator, err := jwt.NewGenerator(relyingParty, jwkManager, tenant.ID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"net/http"
+	"strings"
+)
+
+const RelyingPartyQueryParam = "rp_id"
+
+// RelyingPartyMiddleware selects the relying party of the tenant which is used for the current request.
+// An explicitly requested RP ID (query parameter 'rp_id') takes precedence over the 'Origin' header. When neither
+// matches a relying party, the default relying party of the tenant is used.
+func RelyingPartyMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			tenant := ctx.Get("tenant").(*models.Tenant)
+			if tenant == nil {
+				ctx.Logger().Errorf("tenant for relying party middleware not found")
+				return echo.NewHTTPError(http.StatusNotFound, "tenant not found")
+			}
+
+			relyingParty, err := selectRelyingParty(ctx, tenant.Config.WebauthnConfig)
+			if err != nil {
+				return err
+			}
+
+			ctx.Set("relying_party", relyingParty)
+
+			return next(ctx)
+		}
+	}
+}
+
+func selectRelyingParty(ctx echo.Context, cfg models.WebauthnConfig) (*models.RelyingParty, error) {
+	requestedRpId := strings.TrimSpace(ctx.QueryParam(RelyingPartyQueryParam))
+	if requestedRpId != "" {
+		relyingParty := cfg.FindRelyingPartyById(requestedRpId)
+		if relyingParty == nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("relying party '%s' is not configured for this tenant", requestedRpId))
+		}
+
+		return relyingParty, nil
+	}
+
+	origin := strings.TrimSpace(ctx.Request().Header.Get(echo.HeaderOrigin))
+	if origin != "" {
+		relyingParty := cfg.FindRelyingPartyByOrigin(origin)
+		if relyingParty != nil {
+			return relyingParty, nil
+		}
+	}
+
+	relyingParty := cfg.GetDefaultRelyingParty()
+	if relyingParty == nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "tenant has no relying party configured")
+	}
+
+	return relyingParty, nil
+}
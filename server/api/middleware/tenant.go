@@ -4,6 +4,8 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/tracing"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 )
 
@@ -17,7 +19,7 @@ func TenantMiddleware(persister persistence.Persister) echo.MiddlewareFunc {
 				return echo.NewHTTPError(http.StatusBadRequest, "tenant_id must be a valid uuid4")
 			}
 
-			tenant, err := persister.GetTenantPersister(nil).Get(tenantId)
+			tenant, err := persister.GetTenantPersister(persister.GetConnection().WithContext(ctx.Request().Context())).Get(tenantId)
 			if err != nil {
 				ctx.Logger().Error(err)
 				return err
@@ -28,6 +30,7 @@ func TenantMiddleware(persister persistence.Persister) echo.MiddlewareFunc {
 			}
 
 			ctx.Set("tenant", tenant)
+			trace.SpanFromContext(ctx.Request().Context()).SetAttributes(tracing.TenantId(tenant.ID))
 
 			return next(ctx)
 		}
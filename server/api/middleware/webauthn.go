@@ -44,7 +44,9 @@ func WebauthnMiddleware(persister persistence.Persister) echo.MiddlewareFunc {
 }
 
 func setWebauthnClientCtx(ctx echo.Context, cfg models.Config, persister persistence.Persister) error {
-	err := createPasskeyClient(ctx, cfg.WebauthnConfig)
+	relyingParty := ctx.Get("relying_party").(*models.RelyingParty)
+
+	err := createPasskeyClient(ctx, cfg.WebauthnConfig, *relyingParty)
 	if err != nil {
 		ctx.Logger().Error(err)
 		return err
@@ -58,7 +60,7 @@ func setWebauthnClientCtx(ctx echo.Context, cfg models.Config, persister persist
 		}
 	}
 
-	err = createMFAClient(ctx, *cfg.MfaConfig, cfg.WebauthnConfig.RelyingParty)
+	err = createMFAClient(ctx, *cfg.MfaConfig, *relyingParty)
 	if err != nil {
 		ctx.Logger().Error(err)
 		return err
@@ -68,10 +70,7 @@ func setWebauthnClientCtx(ctx echo.Context, cfg models.Config, persister persist
 }
 
 func createClient(ctx echo.Context, ctxKey string, params clientParams) error {
-	var origins []string
-	for _, origin := range params.RP.Origins {
-		origins = append(origins, origin.Origin)
-	}
+	origins := params.RP.GetOrigins()
 
 	requireResidentKey := params.ResidentKeyRequirement == protocol.ResidentKeyRequirementRequired
 
@@ -113,9 +112,9 @@ func createClient(ctx echo.Context, ctxKey string, params clientParams) error {
 	return nil
 }
 
-func createPasskeyClient(ctx echo.Context, cfg models.WebauthnConfig) error {
+func createPasskeyClient(ctx echo.Context, cfg models.WebauthnConfig, rp models.RelyingParty) error {
 	params := clientParams{
-		RP:                     cfg.RelyingParty,
+		RP:                     rp,
 		Timeout:                cfg.Timeout,
 		UserVerification:       cfg.UserVerification,
 		Attachment:             cfg.Attachment,
@@ -0,0 +1,57 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"net/url"
+	"time"
+)
+
+// Cursor points to the last item of a page which is ordered by creation time and id. It is passed to clients as an
+// opaque string.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+}
+
+func EncodeCursor(createdAt time.Time, id uuid.UUID) string {
+	data, _ := json.Marshal(Cursor{CreatedAt: createdAt.UTC(), ID: id})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cursor: %w", err)
+	}
+
+	var cursor Cursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode cursor: %w", err)
+	}
+
+	if cursor.CreatedAt.IsZero() || cursor.ID.IsNil() {
+		return nil, errors.New("failed to decode cursor: cursor is incomplete")
+	}
+
+	return &cursor, nil
+}
+
+// CreateCursorHeader links to the next page. Without a next cursor, the current page is the last one and no link is
+// returned.
+func CreateCursorHeader(u *url.URL, nextCursor string, itemsPerPage int) string {
+	if nextCursor == "" {
+		return ""
+	}
+
+	q := u.Query()
+	q.Set("cursor", nextCursor)
+	q.Set("per_page", fmt.Sprintf("%d", itemsPerPage))
+	u.RawQuery = q.Encode()
+
+	return fmt.Sprintf("<%s>; rel=\"next\"", u.String())
+}
@@ -0,0 +1,39 @@
+package pagination
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestEncodeAndDecodeCursor(t *testing.T) {
+	id, _ := uuid.NewV4()
+	createdAt := time.Date(2026, 10, 19, 10, 0, 0, 123, time.UTC)
+
+	cursor, err := DecodeCursor(EncodeCursor(createdAt, id))
+	require.NoError(t, err)
+	assert.Equal(t, createdAt, cursor.CreatedAt)
+	assert.Equal(t, id, cursor.ID)
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	_, err := DecodeCursor("not a cursor")
+	assert.Error(t, err)
+
+	_, err = DecodeCursor("e30")
+	assert.EqualError(t, err, "failed to decode cursor: cursor is incomplete")
+}
+
+func TestCreateCursorHeader_NextPage(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8080?pagination=cursor&type=webauthn_registration_init_failed")
+	header := CreateCursorHeader(u, "abc", 10)
+	assert.Equal(t, "<http://localhost:8080?cursor=abc&pagination=cursor&per_page=10&type=webauthn_registration_init_failed>; rel=\"next\"", header)
+}
+
+func TestCreateCursorHeader_LastPage(t *testing.T) {
+	u, _ := url.Parse("http://localhost:8080?pagination=cursor")
+	assert.Empty(t, CreateCursorHeader(u, "", 10))
+}
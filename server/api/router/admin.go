@@ -8,11 +8,14 @@ import (
 	passkeyMiddleware "github.com/teamhanko/passkey-server/api/middleware"
 	"github.com/teamhanko/passkey-server/api/template"
 	"github.com/teamhanko/passkey-server/api/validators"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/mapper"
 	"github.com/teamhanko/passkey-server/persistence"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
-func NewAdminRouter(cfg *config.Config, persister persistence.Persister, prometheus echo.MiddlewareFunc) *echo.Echo {
+func NewAdminRouter(cfg *config.Config, persister persistence.Persister, prometheus echo.MiddlewareFunc, auditSinks []auditlog.Sink) *echo.Echo {
 	main := echo.New()
 	main.Renderer = template.NewTemplateRenderer()
 	main.HideBanner = true
@@ -25,6 +28,12 @@ func NewAdminRouter(cfg *config.Config, persister persistence.Persister, prometh
 	})
 
 	main.Use(middleware.RequestID())
+	main.Use(passkeyMiddleware.AdminAuditSinks(auditSinks))
+
+	// Trace requests, continuing the trace of an incoming traceparent header
+	if cfg.Tracing.Enabled {
+		main.Use(otelecho.Middleware(cfg.Tracing.ServiceName))
+	}
 	if cfg.Log.LogHealthAndMetrics {
 		main.Use(passkeyMiddleware.LoggerMiddleware())
 	} else {
@@ -56,13 +65,30 @@ func NewAdminRouter(cfg *config.Config, persister persistence.Persister, prometh
 	tenantsGroup := rootGroup.Group("/tenants")
 	tenantsGroup.GET("", tenantHandler.List)
 	tenantsGroup.POST("", tenantHandler.Create)
+	tenantsGroup.POST("/import", tenantHandler.Import)
 
 	singleGroup := tenantsGroup.Group("/:tenant_id", passkeyMiddleware.TenantMiddleware(persister))
 	singleGroup.GET("", tenantHandler.Get)
 	singleGroup.PUT("", tenantHandler.Update)
 	singleGroup.DELETE("", tenantHandler.Remove)
 	singleGroup.PUT("/config", tenantHandler.UpdateConfig)
+	singleGroup.PATCH("/config", tenantHandler.PatchConfig)
+	singleGroup.GET("/config/versions", tenantHandler.ListConfigVersions)
+	singleGroup.GET("/config/versions/diff", tenantHandler.DiffConfigVersions)
+	singleGroup.POST("/config/versions/:version/rollback", tenantHandler.RollbackConfig)
+	singleGroup.GET("/config/assetlinks.json", tenantHandler.GetAssetLinks)
+	singleGroup.GET("/config/apple-app-site-association", tenantHandler.GetAppleAppSiteAssociation)
 	singleGroup.GET("/audit_logs", tenantHandler.ListAuditLog)
+	singleGroup.GET("/audit_logs/verify", tenantHandler.VerifyAuditLog)
+	singleGroup.GET("/audit_logs/export", tenantHandler.ExportAuditLog)
+	singleGroup.POST("/export", tenantHandler.Export)
+
+	statsHandler := admin.NewStatsHandler(persister, cfg.Stats, mapper.LoadAuthenticatorMetadata(nil))
+	singleGroup.GET("/stats", statsHandler.Get)
+
+	auditLogHandler := admin.NewAuditLogHandler(persister)
+	rootGroup.GET("/audit_logs", auditLogHandler.List)
+	rootGroup.GET("/audit_logs/verify", auditLogHandler.Verify)
 
 	secretHandler := admin.NewSecretsHandler(persister)
 	apiKeyGroup := singleGroup.Group("/secrets/api")
@@ -76,11 +102,26 @@ func NewAdminRouter(cfg *config.Config, persister persistence.Persister, prometh
 	jwkKeyGroup.DELETE("/:secret_id", secretHandler.RemoveJWKKey)
 
 	userHandler := admin.NewUserHandler(persister)
+	singleGroup.POST("/credentials/import", userHandler.ImportCredentials)
+
 	userGroup := singleGroup.Group("/users")
 	userGroup.GET("", userHandler.List)
+	userGroup.POST("", userHandler.Create)
 
 	userGroup.GET("/:user_id", userHandler.Get)
+	userGroup.PUT("/:user_id", userHandler.Update)
 	userGroup.DELETE("/:user_id", userHandler.Remove)
+	userGroup.POST("/:user_id/export", userHandler.ExportData)
+	userGroup.POST("/:user_id/erase", userHandler.Erase)
+	userGroup.POST("/:user_id/disable", userHandler.Disable)
+	userGroup.POST("/:user_id/enable", userHandler.Enable)
+
+	userGroup.GET("/:user_id/credentials", userHandler.ListCredentials)
+	userGroup.PATCH("/:user_id/credentials/:credential_id", userHandler.UpdateCredential)
+	userGroup.DELETE("/:user_id/credentials/:credential_id", userHandler.RemoveCredential)
+	userGroup.POST("/:user_id/credentials/:credential_id/enable", userHandler.EnableCredential)
+	userGroup.POST("/:user_id/credentials/:credential_id/disable", userHandler.DisableCredential)
+	userGroup.POST("/:user_id/credentials/:credential_id/revoke", userHandler.RevokeCredential)
 
 	return main
 }
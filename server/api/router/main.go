@@ -2,15 +2,18 @@ package router
 
 import (
 	"fmt"
+	"github.com/labstack/echo-contrib/echoprometheus"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/teamhanko/passkey-server/api/handler"
 	passkeyMiddleware "github.com/teamhanko/passkey-server/api/middleware"
 	"github.com/teamhanko/passkey-server/api/template"
 	"github.com/teamhanko/passkey-server/api/validators"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/config"
 	"github.com/teamhanko/passkey-server/mapper"
 	"github.com/teamhanko/passkey-server/persistence"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/labstack/echo/otelecho"
 )
 
 const (
@@ -18,7 +21,7 @@ const (
 	FinishEndpoint = "/finalize"
 )
 
-func NewMainRouter(cfg *config.Config, persister persistence.Persister, authenticatorMetadata mapper.AuthenticatorMetadata) *echo.Echo {
+func NewMainRouter(cfg *config.Config, persister persistence.Persister, authenticatorMetadata mapper.AuthenticatorMetadata, auditSinks *auditlog.Sinks, auditEnricher *auditlog.Enricher) *echo.Echo {
 	main := echo.New()
 	main.Renderer = template.NewTemplateRenderer()
 	main.HideBanner = true
@@ -32,6 +35,18 @@ func NewMainRouter(cfg *config.Config, persister persistence.Persister, authenti
 	// Add Request ID to Header
 	main.Use(middleware.RequestID())
 
+	// Trace requests, continuing the trace of an incoming traceparent header
+	if cfg.Tracing.Enabled {
+		main.Use(otelecho.Middleware(cfg.Tracing.ServiceName))
+	}
+
+	if cfg.Metrics.Enabled {
+		main.Use(echoprometheus.NewMiddlewareWithConfig(echoprometheus.MiddlewareConfig{
+			Namespace: "hanko",
+			Subsystem: "public",
+		}))
+	}
+
 	// Validator
 	main.Validator = validators.NewCustomValidator()
 
@@ -42,14 +57,15 @@ func NewMainRouter(cfg *config.Config, persister persistence.Persister, authenti
 	tenantGroup := rootGroup.Group(
 		"",
 		passkeyMiddleware.CORSWithTenant(),
-		passkeyMiddleware.AuditLogger(persister),
+		passkeyMiddleware.AuditLogger(persister, auditSinks, auditEnricher),
+		passkeyMiddleware.RelyingPartyMiddleware(),
 		passkeyMiddleware.JWKMiddleware(persister),
 	)
 
 	logMetrics(cfg.Log.LogHealthAndMetrics, main, tenantGroup)
 
 	RouteWellKnown(tenantGroup)
-	RouteCredentials(tenantGroup, persister)
+	RouteCredentials(tenantGroup, persister, authenticatorMetadata)
 
 	webauthnGroup := tenantGroup.Group("", passkeyMiddleware.WebauthnMiddleware(persister))
 	RouteRegistration(webauthnGroup, persister, authenticatorMetadata)
@@ -75,14 +91,17 @@ func RouteWellKnown(parent *echo.Group) {
 	group.GET("/jwks.json", wellKnownHandler.GetPublicKeys)
 }
 
-func RouteCredentials(parent *echo.Group, persister persistence.Persister) {
-	credentialsHandler := handler.NewCredentialsHandler(persister)
+func RouteCredentials(parent *echo.Group, persister persistence.Persister, authenticatorMetadata mapper.AuthenticatorMetadata) {
+	credentialsHandler := handler.NewCredentialsHandler(persister, authenticatorMetadata)
 
 	group := parent.Group("/credentials", passkeyMiddleware.ApiKeyMiddleware())
 	group.GET("", credentialsHandler.List)
 	group.GET("/:credential_id", credentialsHandler.Get)
 	group.PATCH("/:credential_id", credentialsHandler.Update)
 	group.DELETE("/:credential_id", credentialsHandler.Delete)
+	group.POST("/:credential_id/enable", credentialsHandler.Enable)
+	group.POST("/:credential_id/disable", credentialsHandler.Disable)
+	group.POST("/:credential_id/revoke", credentialsHandler.Revoke)
 
 	return
 }
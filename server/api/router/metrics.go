@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/labstack/echo-contrib/echoprometheus"
+	"github.com/labstack/echo/v4"
+)
+
+// NewMetricsRouter serves the Prometheus metrics of the public server on their own address
+func NewMetricsRouter() *echo.Echo {
+	metrics := echo.New()
+	metrics.HideBanner = true
+	metrics.HidePort = true
+
+	metrics.GET("/metrics", echoprometheus.NewHandler())
+
+	return metrics
+}
@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	AuditLogExportFormatNdjson = "ndjson"
+	AuditLogExportFormatCsv    = "csv"
+
+	auditLogExportBatchSize = 1000
+)
+
+var auditLogCsvHeader = []string{
+	"id", "created_at", "type", "tenant_id", "actor_user_id", "transaction_id", "error", "meta_http_request_id",
+	"meta_source_ip", "meta_user_agent", "actor", "target_type", "target_id", "sequence", "hash",
+	"meta_browser", "meta_os", "meta_device", "meta_country", "meta_city", "meta_asn", "meta_as_organization",
+}
+
+// exportAuditLogs writes all matching entries ordered by creation time in batches, so the export does not have to be
+// held in memory. Entries created after the export started are not included, so the export is consistent.
+func exportAuditLogs(auditLogPersister persisters.AuditLogPersister, options persisters.AuditLogOptions, format string, w io.Writer) error {
+	if options.End == nil {
+		now := time.Now().UTC()
+		options.End = &now
+	}
+
+	encoder := newAuditLogEncoder(format, w)
+	err := encoder.begin()
+	if err != nil {
+		return err
+	}
+
+	cursor := persisters.AuditLogCursorOptions{
+		Limit:     auditLogExportBatchSize,
+		Ascending: true,
+	}
+
+	for {
+		auditLogs, err := auditLogPersister.ListAfter(options, cursor)
+		if err != nil {
+			return fmt.Errorf("failed to get audit logs for export: %w", err)
+		}
+
+		for _, auditLog := range auditLogs {
+			err = encoder.encode(auditLog)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = encoder.flush()
+		if err != nil {
+			return err
+		}
+
+		if len(auditLogs) < auditLogExportBatchSize {
+			return nil
+		}
+
+		last := auditLogs[len(auditLogs)-1]
+		cursor.AfterCreatedAt = &last.CreatedAt
+		cursor.AfterId = &last.ID
+	}
+}
+
+type auditLogEncoder struct {
+	json *json.Encoder
+	csv  *csv.Writer
+	// flushResponse sends the written batch to the client, if the writer is a http response
+	flushResponse func()
+}
+
+func newAuditLogEncoder(format string, w io.Writer) *auditLogEncoder {
+	encoder := &auditLogEncoder{
+		flushResponse: func() {},
+	}
+
+	if flusher, ok := w.(http.Flusher); ok {
+		encoder.flushResponse = flusher.Flush
+	}
+
+	if format == AuditLogExportFormatCsv {
+		encoder.csv = csv.NewWriter(w)
+	} else {
+		encoder.json = json.NewEncoder(w)
+	}
+
+	return encoder
+}
+
+func (e *auditLogEncoder) begin() error {
+	if e.csv == nil {
+		return nil
+	}
+
+	return e.csv.Write(auditLogCsvHeader)
+}
+
+func (e *auditLogEncoder) encode(auditLog models.AuditLog) error {
+	if e.csv == nil {
+		err := e.json.Encode(auditLog)
+		if err != nil {
+			return fmt.Errorf("failed to write audit log: %w", err)
+		}
+
+		return nil
+	}
+
+	sequence := ""
+	if auditLog.Sequence != nil {
+		sequence = strconv.Itoa(*auditLog.Sequence)
+	}
+
+	asn := ""
+	if auditLog.MetaAsn != nil {
+		asn = strconv.Itoa(*auditLog.MetaAsn)
+	}
+
+	tenantId := ""
+	if auditLog.TenantID != nil {
+		tenantId = auditLog.TenantID.String()
+	}
+
+	err := e.csv.Write([]string{
+		auditLog.ID.String(),
+		auditLog.CreatedAt.UTC().Format(time.RFC3339Nano),
+		string(auditLog.Type),
+		tenantId,
+		stringOrEmpty(auditLog.ActorUserId),
+		stringOrEmpty(auditLog.TransactionId),
+		stringOrEmpty(auditLog.Error),
+		auditLog.MetaHttpRequestId,
+		auditLog.MetaSourceIp,
+		auditLog.MetaUserAgent,
+		stringOrEmpty(auditLog.Actor),
+		stringOrEmpty(auditLog.TargetType),
+		stringOrEmpty(auditLog.TargetId),
+		sequence,
+		stringOrEmpty(auditLog.Hash),
+		stringOrEmpty(auditLog.MetaBrowser),
+		stringOrEmpty(auditLog.MetaOs),
+		stringOrEmpty(auditLog.MetaDevice),
+		stringOrEmpty(auditLog.MetaCountry),
+		stringOrEmpty(auditLog.MetaCity),
+		asn,
+		stringOrEmpty(auditLog.MetaAsOrganization),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (e *auditLogEncoder) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		err := e.csv.Error()
+		if err != nil {
+			return fmt.Errorf("failed to write audit logs: %w", err)
+		}
+	}
+
+	e.flushResponse()
+
+	return nil
+}
+
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+
+	return *value
+}
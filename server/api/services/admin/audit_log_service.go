@@ -0,0 +1,142 @@
+package admin
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/pagination"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"net/http"
+)
+
+// AuditLogService lists the audit logs of admin operations across all tenants
+type AuditLogService interface {
+	List(dto request.ListAdminAuditLogDto) (models.AuditLogs, int, error)
+	ListAfter(dto request.ListAdminAuditLogDto) (models.AuditLogs, string, error)
+}
+
+type auditLogService struct {
+	logger            echo.Logger
+	auditLogPersister persisters.AuditLogPersister
+}
+
+func NewAuditLogService(ctx echo.Context, auditLogPersister persisters.AuditLogPersister) AuditLogService {
+	return &auditLogService{
+		logger:            ctx.Logger(),
+		auditLogPersister: auditLogPersister,
+	}
+}
+
+func (as *auditLogService) List(dto request.ListAdminAuditLogDto) (models.AuditLogs, int, error) {
+	options := toAuditLogOptions(dto.ListAuditLogDto)
+	options.TenantId = dto.TenantId
+	options.AdminOnly = true
+
+	auditLogs, logCount, err := listAuditLogs(as.auditLogPersister, options)
+	if err != nil {
+		as.logger.Error(err)
+		return auditLogs, 0, err
+	}
+
+	return auditLogs, logCount, nil
+}
+
+func (as *auditLogService) ListAfter(dto request.ListAdminAuditLogDto) (models.AuditLogs, string, error) {
+	options := toAuditLogOptions(dto.ListAuditLogDto)
+	options.TenantId = dto.TenantId
+	options.AdminOnly = true
+
+	auditLogs, nextCursor, err := listAuditLogsAfter(as.auditLogPersister, options, dto.Cursor, dto.PerPage)
+	if err != nil {
+		as.logger.Error(err)
+		return auditLogs, "", err
+	}
+
+	return auditLogs, nextCursor, nil
+}
+
+func toAuditLogOptions(dto request.ListAuditLogDto) persisters.AuditLogOptions {
+	options := toAuditLogFilterOptions(dto.AuditLogFilterDto)
+	options.Page = dto.Page
+	options.PerPage = dto.PerPage
+
+	return options
+}
+
+func toAuditLogFilterOptions(dto request.AuditLogFilterDto) persisters.AuditLogOptions {
+	return persisters.AuditLogOptions{
+		Start:         dto.StartTime,
+		End:           dto.EndTime,
+		Types:         dto.Types,
+		UserId:        dto.UserId,
+		Ip:            dto.IP,
+		Search:        dto.SearchString,
+		Actor:         dto.Actor,
+		TargetType:    dto.TargetType,
+		TargetId:      dto.TargetId,
+		TransactionId: dto.TransactionId,
+		Error:         dto.Error,
+		Browser:       dto.Browser,
+		Os:            dto.Os,
+		Device:        dto.Device,
+		Country:       dto.Country,
+		City:          dto.City,
+		Asn:           dto.Asn,
+	}
+}
+
+func listAuditLogs(auditLogPersister persisters.AuditLogPersister, options persisters.AuditLogOptions) (models.AuditLogs, int, error) {
+	auditLogs := make(models.AuditLogs, 0)
+
+	auditLogEntries, err := auditLogPersister.List(options)
+	if err != nil {
+		return auditLogs, 0, fmt.Errorf("failed to get list of audit logs: %w", err)
+	}
+
+	auditLogs = append(auditLogs, auditLogEntries...)
+
+	logCount, err := auditLogPersister.Count(options)
+	if err != nil {
+		return auditLogs, 0, fmt.Errorf("failed to get total count of audit logs: %w", err)
+	}
+
+	return auditLogs, logCount, nil
+}
+
+// listAuditLogsAfter returns the page after the cursor and the cursor of the next page, which is empty on the last
+// page. No total count is queried, as counting gets slow on large tables.
+func listAuditLogsAfter(auditLogPersister persisters.AuditLogPersister, options persisters.AuditLogOptions, cursor string, perPage int) (models.AuditLogs, string, error) {
+	auditLogs := make(models.AuditLogs, 0)
+
+	cursorOptions := persisters.AuditLogCursorOptions{
+		// one more entry is fetched to know if there is a next page
+		Limit: perPage + 1,
+	}
+
+	if cursor != "" {
+		decoded, err := pagination.DecodeCursor(cursor)
+		if err != nil {
+			return auditLogs, "", echo.NewHTTPError(http.StatusBadRequest, "invalid cursor").SetInternal(err)
+		}
+
+		cursorOptions.AfterCreatedAt = &decoded.CreatedAt
+		cursorOptions.AfterId = &decoded.ID
+	}
+
+	auditLogEntries, err := auditLogPersister.ListAfter(options, cursorOptions)
+	if err != nil {
+		return auditLogs, "", fmt.Errorf("failed to get list of audit logs: %w", err)
+	}
+
+	nextCursor := ""
+	if len(auditLogEntries) > perPage {
+		auditLogEntries = auditLogEntries[:perPage]
+		last := auditLogEntries[perPage-1]
+		nextCursor = pagination.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	auditLogs = append(auditLogs, auditLogEntries...)
+
+	return auditLogs, nextCursor, nil
+}
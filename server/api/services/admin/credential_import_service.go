@@ -0,0 +1,282 @@
+package admin
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/dto/admin/response"
+	"github.com/teamhanko/passkey-server/api/validators"
+	"github.com/teamhanko/passkey-server/crypto/cose"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// importedAttestationType is stored for imported credentials, as the attestation of the original registration is unknown
+const importedAttestationType = "none"
+
+type CredentialImportService interface {
+	Import(credentials []request.ImportCredentialDto) (*response.CredentialImportResponse, error)
+}
+
+type CreateCredentialImportServiceParams struct {
+	Ctx    echo.Context
+	Tenant models.Tenant
+
+	UserPersister       persisters.WebauthnUserPersister
+	CredentialPersister persisters.WebauthnCredentialPersister
+}
+
+type credentialImportService struct {
+	ctx    echo.Context
+	tenant models.Tenant
+
+	userPersister       persisters.WebauthnUserPersister
+	credentialPersister persisters.WebauthnCredentialPersister
+}
+
+func NewCredentialImportService(params CreateCredentialImportServiceParams) CredentialImportService {
+	return &credentialImportService{
+		ctx:    params.Ctx,
+		tenant: params.Tenant,
+
+		userPersister:       params.UserPersister,
+		credentialPersister: params.CredentialPersister,
+	}
+}
+
+// Import validates every credential on its own and stores all valid ones. Invalid credentials are skipped and
+// reported with the reason in their row of the response.
+func (cs *credentialImportService) Import(credentials []request.ImportCredentialDto) (*response.CredentialImportResponse, error) {
+	result := &response.CredentialImportResponse{
+		Rows: make([]response.CredentialImportRow, len(credentials)),
+	}
+
+	validator := validators.NewCustomValidator()
+	credentialModels := make([]*models.WebauthnCredential, len(credentials))
+	rowsById := make(map[string]int)
+	for i, dto := range credentials {
+		result.Rows[i] = response.CredentialImportRow{
+			Row:          i + 1,
+			CredentialId: dto.CredentialId,
+			UserId:       dto.UserId,
+		}
+
+		credential, err := cs.toCredentialModel(validator, dto)
+		if err == nil {
+			if row, ok := rowsById[credential.ID]; ok {
+				err = fmt.Errorf("credential id is already used in row %d", row)
+			} else {
+				rowsById[credential.ID] = i + 1
+			}
+		}
+
+		if err != nil {
+			failRow(result, i, err)
+			continue
+		}
+
+		credentialModels[i] = credential
+	}
+
+	err := cs.rejectExistingCredentials(result, credentialModels)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]*models.WebauthnUser)
+	for i, credential := range credentialModels {
+		if credential == nil {
+			continue
+		}
+
+		user, ok := users[credentials[i].UserId]
+		if !ok {
+			user, err = cs.getOrCreateUser(credentials[i])
+			if err != nil {
+				return nil, err
+			}
+
+			users[credentials[i].UserId] = user
+		}
+
+		credential.UserId = user.UserID
+		credential.WebauthnUserID = user.ID
+
+		err = cs.credentialPersister.Create(credential)
+		if err != nil {
+			cs.ctx.Logger().Error(err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to store credential").SetInternal(err)
+		}
+
+		result.Rows[i].Status = response.CredentialImportStatusImported
+		result.Imported++
+	}
+
+	return result, nil
+}
+
+func (cs *credentialImportService) toCredentialModel(validator *validators.CustomValidator, dto request.ImportCredentialDto) (*models.WebauthnCredential, error) {
+	if dto.ParseError != nil {
+		return nil, dto.ParseError
+	}
+
+	err := validator.Validate(&dto)
+	if err != nil {
+		var httpError *echo.HTTPError
+		if errors.As(err, &httpError) {
+			return nil, fmt.Errorf("%v", httpError.Message)
+		}
+
+		return nil, err
+	}
+
+	credentialId, err := decodeBase64Url(dto.CredentialId)
+	if err != nil || len(credentialId) == 0 {
+		return nil, errors.New("credential_id must be base64url encoded")
+	}
+
+	publicKey, err := decodeBase64Url(dto.PublicKey)
+	if err != nil {
+		return nil, errors.New("public_key must be a base64url encoded COSE key")
+	}
+
+	err = cose.ValidatePublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if dto.RPId != nil && cs.tenant.Config.WebauthnConfig.FindRelyingPartyById(*dto.RPId) == nil {
+		return nil, fmt.Errorf("relying party '%s' is not configured for the tenant", *dto.RPId)
+	}
+
+	if dto.BackupState && !dto.BackupEligible {
+		return nil, errors.New("backup_state requires backup_eligible")
+	}
+
+	aaguid := uuid.Nil
+	if dto.AAGUID != nil {
+		aaguid = uuid.FromStringOrNil(*dto.AAGUID)
+	}
+
+	// the id is stored in the same encoding as the ids of registered credentials
+	id := base64.RawURLEncoding.EncodeToString(credentialId)
+	name := dto.Name
+	if name == nil {
+		genericName := fmt.Sprintf("cred-%s", id)
+		name = &genericName
+	}
+
+	now := time.Now().UTC()
+	credential := &models.WebauthnCredential{
+		ID:              id,
+		Name:            name,
+		PublicKey:       base64.RawURLEncoding.EncodeToString(publicKey),
+		AttestationType: importedAttestationType,
+		AAGUID:          aaguid,
+		SignCount:       int(dto.SignCount),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		BackupEligible:  dto.BackupEligible,
+		BackupState:     dto.BackupState,
+		IsMFA:           dto.IsMFA,
+		RPId:            dto.RPId,
+		Status:          models.CredentialStatusActive,
+	}
+
+	for _, transport := range dto.Transports {
+		transportId, _ := uuid.NewV4()
+		credential.Transports = append(credential.Transports, models.WebauthnCredentialTransport{
+			ID:                   transportId,
+			Name:                 transport,
+			WebauthnCredentialID: id,
+		})
+	}
+
+	return credential, nil
+}
+
+// rejectExistingCredentials fails all rows whose credential is already stored. Credential ids are unique across all
+// tenants.
+func (cs *credentialImportService) rejectExistingCredentials(result *response.CredentialImportResponse, credentials []*models.WebauthnCredential) error {
+	var ids []string
+	for _, credential := range credentials {
+		if credential != nil {
+			ids = append(ids, credential.ID)
+		}
+	}
+
+	existingCredentials, err := cs.credentialPersister.ListByIds(ids)
+	if err != nil {
+		cs.ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to check existing credentials").SetInternal(err)
+	}
+
+	existingIds := make(map[string]bool)
+	for _, credential := range existingCredentials {
+		existingIds[credential.ID] = true
+	}
+
+	for i, credential := range credentials {
+		if credential != nil && existingIds[credential.ID] {
+			failRow(result, i, errors.New("credential already exists"))
+			credentials[i] = nil
+		}
+	}
+
+	return nil
+}
+
+func (cs *credentialImportService) getOrCreateUser(dto request.ImportCredentialDto) (*models.WebauthnUser, error) {
+	user, err := cs.userPersister.GetByUserId(dto.UserId, cs.tenant.ID)
+	if err != nil {
+		cs.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to get user from db").SetInternal(err)
+	}
+
+	if user != nil {
+		return user, nil
+	}
+
+	displayName := dto.UserName
+	if dto.DisplayName != nil {
+		displayName = *dto.DisplayName
+	}
+
+	userId, _ := uuid.NewV4()
+	now := time.Now().UTC()
+	user = &models.WebauthnUser{
+		ID:          userId,
+		UserID:      dto.UserId,
+		Name:        dto.UserName,
+		DisplayName: displayName,
+		TenantID:    cs.tenant.ID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err = cs.userPersister.Create(user)
+	if err != nil {
+		cs.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to store user").SetInternal(err)
+	}
+
+	return user, nil
+}
+
+func failRow(result *response.CredentialImportResponse, index int, err error) {
+	message := err.Error()
+	result.Rows[index].Status = response.CredentialImportStatusFailed
+	result.Rows[index].Error = &message
+	result.Failed++
+}
+
+// decodeBase64Url accepts base64url with and without padding, as other servers export both
+func decodeBase64Url(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"log"
+	"net/http"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyKeyLifetime is the time after which a key can be used again for a different request
+const IdempotencyKeyLifetime = 24 * time.Hour
+
+// idempotencyKeyPurgeInterval is the interval in which expired keys are deleted
+const idempotencyKeyPurgeInterval = time.Hour
+
+// ErrIdempotencyKeyNotStored is returned when the response could not be stored, e.g. because a concurrent request
+// with the same key stored its response first. The caller should look up the key again after its transaction was
+// rolled back.
+var ErrIdempotencyKeyNotStored = errors.New("idempotency key was not stored")
+
+type IdempotencyService interface {
+	// Lookup returns the stored response for the key or nil when the request was not processed yet
+	Lookup(key string, requestBody []byte) (*models.IdempotencyKey, error)
+	Store(key string, requestBody []byte, status int, responseBody interface{}) error
+}
+
+type idempotencyService struct {
+	logger    echo.Logger
+	operation string
+
+	idempotencyKeyPersister persisters.IdempotencyKeyPersister
+}
+
+func NewIdempotencyService(ctx echo.Context, operation string, idempotencyKeyPersister persisters.IdempotencyKeyPersister) IdempotencyService {
+	return &idempotencyService{
+		logger:    ctx.Logger(),
+		operation: operation,
+
+		idempotencyKeyPersister: idempotencyKeyPersister,
+	}
+}
+
+func (is *idempotencyService) Lookup(key string, requestBody []byte) (*models.IdempotencyKey, error) {
+	if len(key) > 255 {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "idempotency key must not be longer than 255 characters")
+	}
+
+	storedKey, err := is.idempotencyKeyPersister.Get(key, is.operation)
+	if err != nil {
+		is.logger.Error(err)
+		return nil, err
+	}
+
+	if storedKey == nil {
+		return nil, nil
+	}
+
+	if storedKey.IsExpired(IdempotencyKeyLifetime) {
+		err = is.idempotencyKeyPersister.Delete(storedKey)
+		if err != nil {
+			is.logger.Error(err)
+			return nil, err
+		}
+
+		return nil, nil
+	}
+
+	if storedKey.RequestHash != hashRequest(requestBody) {
+		return nil, echo.NewHTTPError(http.StatusUnprocessableEntity, "idempotency key was already used for a different request")
+	}
+
+	return storedKey, nil
+}
+
+func (is *idempotencyService) Store(key string, requestBody []byte, status int, responseBody interface{}) error {
+	body, err := json.Marshal(responseBody)
+	if err != nil {
+		return fmt.Errorf("unable to serialize response: %w", err)
+	}
+
+	keyId, _ := uuid.NewV4()
+	now := time.Now()
+
+	err = is.idempotencyKeyPersister.Create(&models.IdempotencyKey{
+		ID:             keyId,
+		Key:            key,
+		Operation:      is.operation,
+		RequestHash:    hashRequest(requestBody),
+		ResponseStatus: status,
+		ResponseBody:   string(body),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	})
+	if err != nil {
+		is.logger.Error(err)
+		return fmt.Errorf("%w: %w", ErrIdempotencyKeyNotStored, err)
+	}
+
+	return nil
+}
+
+// WatchIdempotencyKeys deletes expired keys in a fixed interval, so stored responses are not kept longer than needed
+func WatchIdempotencyKeys(idempotencyKeyPersister persisters.IdempotencyKeyPersister) {
+	ticker := time.NewTicker(idempotencyKeyPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		count, err := idempotencyKeyPersister.DeleteCreatedBefore(time.Now().Add(-IdempotencyKeyLifetime))
+		if err != nil {
+			log.Printf("failed to delete expired idempotency keys: %v", err)
+		} else if count > 0 {
+			log.Printf("deleted %d expired idempotency keys", count)
+		}
+
+		<-ticker.C
+	}
+}
+
+func hashRequest(requestBody []byte) string {
+	hash := sha256.Sum256(requestBody)
+	return hex.EncodeToString(hash[:])
+}
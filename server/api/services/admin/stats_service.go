@@ -0,0 +1,187 @@
+package admin
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/dto/admin/response"
+	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/stats"
+	"net/http"
+	"time"
+)
+
+const defaultStatsRange = 7 * 24 * time.Hour
+
+type StatsService interface {
+	Get(dto request.GetStatsDto) (*response.StatsResponse, error)
+}
+
+type statsService struct {
+	logger                echo.Logger
+	tenant                *models.Tenant
+	statsPersister        persisters.StatsPersister
+	authenticatorMetadata mapper.AuthenticatorMetadata
+	useRollups            bool
+}
+
+type CreateStatsServiceParams struct {
+	Ctx                   echo.Context
+	Tenant                *models.Tenant
+	StatsPersister        persisters.StatsPersister
+	AuthenticatorMetadata mapper.AuthenticatorMetadata
+	// UseRollups counts the audit logs of full hours from the rollups instead of the audit logs
+	UseRollups bool
+}
+
+func NewStatsService(params CreateStatsServiceParams) StatsService {
+	return &statsService{
+		logger:                params.Ctx.Logger(),
+		tenant:                params.Tenant,
+		statsPersister:        params.StatsPersister,
+		authenticatorMetadata: params.AuthenticatorMetadata,
+		useRollups:            params.UseRollups,
+	}
+}
+
+func (ss *statsService) Get(dto request.GetStatsDto) (*response.StatsResponse, error) {
+	end := time.Now().UTC()
+	if dto.EndTime != nil {
+		end = dto.EndTime.UTC()
+	}
+
+	start := end.Add(-defaultStatsRange)
+	if dto.StartTime != nil {
+		start = dto.StartTime.UTC()
+	}
+
+	if !start.Before(end) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "start_time must be before end_time")
+	}
+
+	interval := dto.Interval
+	if interval == "" {
+		interval = stats.IntervalDay
+	}
+
+	counts, err := ss.countAuditLogs(start, end)
+	if err != nil {
+		ss.logger.Error(err)
+		return nil, err
+	}
+
+	activeUsers, err := ss.statsPersister.CountActiveUsers(ss.tenant.ID, start, end, stats.SucceededTypes())
+	if err != nil {
+		ss.logger.Error(err)
+		return nil, err
+	}
+
+	registrations, err := ss.countRegistrations(start, end)
+	if err != nil {
+		ss.logger.Error(err)
+		return nil, err
+	}
+
+	total, backupEligible, err := ss.statsPersister.CountBackupEligibleCredentials(ss.tenant.ID, start, end)
+	if err != nil {
+		ss.logger.Error(err)
+		return nil, err
+	}
+
+	backupEligibleStats := response.BackupEligibleStats{
+		Total:          total,
+		BackupEligible: backupEligible,
+	}
+	if total > 0 {
+		share := float64(backupEligible) / float64(total)
+		backupEligibleStats.Share = &share
+	}
+
+	return &response.StatsResponse{
+		StartTime:      start,
+		EndTime:        end,
+		Interval:       interval,
+		Buckets:        stats.BucketCounts(counts, interval),
+		Ceremonies:     stats.SummarizeCeremonies(counts),
+		ActiveUsers:    activeUsers,
+		Registrations:  registrations,
+		BackupEligible: backupEligibleStats,
+	}, nil
+}
+
+// countAuditLogs takes the counts of all rolled up hours from the rollups and counts the remaining audit logs. The
+// rollups only contain full hours, so the partial hours at the start and the end of the time range are counted from
+// the audit logs as well.
+func (ss *statsService) countAuditLogs(start time.Time, end time.Time) ([]models.AuditLogTypeCount, error) {
+	if !ss.useRollups {
+		return ss.statsPersister.CountAuditLogsByHour(ss.tenant.ID, start, end)
+	}
+
+	lastBucket, err := ss.statsPersister.GetLastRollupBucket()
+	if err != nil {
+		return nil, err
+	}
+
+	if lastBucket == nil {
+		return ss.statsPersister.CountAuditLogsByHour(ss.tenant.ID, start, end)
+	}
+
+	rollupStart := start.Truncate(time.Hour)
+	if rollupStart.Before(start) {
+		rollupStart = rollupStart.Add(time.Hour)
+	}
+
+	rollupEnd := end.Truncate(time.Hour)
+	rolledUpUntil := lastBucket.UTC().Add(time.Hour)
+	if rollupEnd.After(rolledUpUntil) {
+		rollupEnd = rolledUpUntil
+	}
+
+	if !rollupStart.Before(rollupEnd) {
+		return ss.statsPersister.CountAuditLogsByHour(ss.tenant.ID, start, end)
+	}
+
+	counts, err := ss.statsPersister.ListRollups(ss.tenant.ID, rollupStart, rollupEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	if start.Before(rollupStart) {
+		first, err := ss.statsPersister.CountAuditLogsByHour(ss.tenant.ID, start, rollupStart)
+		if err != nil {
+			return nil, err
+		}
+
+		counts = append(first, counts...)
+	}
+
+	if end.After(rollupEnd) {
+		latest, err := ss.statsPersister.CountAuditLogsByHour(ss.tenant.ID, rollupEnd, end)
+		if err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, latest...)
+	}
+
+	return counts, nil
+}
+
+func (ss *statsService) countRegistrations(start time.Time, end time.Time) ([]response.AuthenticatorRegistrations, error) {
+	counts, err := ss.statsPersister.CountCredentialsByAaguid(ss.tenant.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	registrations := make([]response.AuthenticatorRegistrations, 0, len(counts))
+	for _, count := range counts {
+		registrations = append(registrations, response.AuthenticatorRegistrations{
+			AAGUID: count.AAGUID,
+			Name:   ss.authenticatorMetadata.GetNameForAaguid(count.AAGUID),
+			Count:  count.Count,
+		})
+	}
+
+	return registrations, nil
+}
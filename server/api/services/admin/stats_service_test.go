@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+)
+
+type timeRange struct {
+	start time.Time
+	end   time.Time
+}
+
+type fakeStatsPersister struct {
+	persisters.StatsPersister
+	lastBucket *time.Time
+	counted    []timeRange
+	rolledUp   []timeRange
+}
+
+func (p *fakeStatsPersister) GetLastRollupBucket() (*time.Time, error) {
+	return p.lastBucket, nil
+}
+
+func (p *fakeStatsPersister) CountAuditLogsByHour(_ uuid.UUID, start time.Time, end time.Time) ([]models.AuditLogTypeCount, error) {
+	p.counted = append(p.counted, timeRange{start, end})
+	return nil, nil
+}
+
+func (p *fakeStatsPersister) ListRollups(_ uuid.UUID, start time.Time, end time.Time) ([]models.AuditLogTypeCount, error) {
+	p.rolledUp = append(p.rolledUp, timeRange{start, end})
+	return nil, nil
+}
+
+func TestCountAuditLogsCountsPartialHoursFromAuditLogs(t *testing.T) {
+	hour := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	lastBucket := hour.Add(5 * time.Hour)
+	persister := &fakeStatsPersister{lastBucket: &lastBucket}
+	service := &statsService{tenant: &models.Tenant{}, statsPersister: persister, useRollups: true}
+
+	start := hour.Add(30 * time.Minute)
+	end := hour.Add(3*time.Hour + 15*time.Minute)
+	_, err := service.countAuditLogs(start, end)
+	require.NoError(t, err)
+
+	assert.Equal(t, []timeRange{{hour.Add(time.Hour), hour.Add(3 * time.Hour)}}, persister.rolledUp)
+	assert.Equal(t, []timeRange{{start, hour.Add(time.Hour)}, {hour.Add(3 * time.Hour), end}}, persister.counted)
+}
+
+func TestCountAuditLogsCountsHoursWhichAreNotRolledUpFromAuditLogs(t *testing.T) {
+	hour := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	lastBucket := hour.Add(time.Hour)
+	persister := &fakeStatsPersister{lastBucket: &lastBucket}
+	service := &statsService{tenant: &models.Tenant{}, statsPersister: persister, useRollups: true}
+
+	end := hour.Add(4 * time.Hour)
+	_, err := service.countAuditLogs(hour, end)
+	require.NoError(t, err)
+
+	assert.Equal(t, []timeRange{{hour, hour.Add(2 * time.Hour)}}, persister.rolledUp)
+	assert.Equal(t, []timeRange{{hour.Add(2 * time.Hour), end}}, persister.counted)
+}
+
+func TestCountAuditLogsWithinOneHour(t *testing.T) {
+	hour := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	lastBucket := hour.Add(5 * time.Hour)
+	persister := &fakeStatsPersister{lastBucket: &lastBucket}
+	service := &statsService{tenant: &models.Tenant{}, statsPersister: persister, useRollups: true}
+
+	start := hour.Add(10 * time.Minute)
+	end := hour.Add(50 * time.Minute)
+	_, err := service.countAuditLogs(start, end)
+	require.NoError(t, err)
+
+	assert.Empty(t, persister.rolledUp)
+	assert.Equal(t, []timeRange{{start, end}}, persister.counted)
+}
@@ -1,29 +1,48 @@
 package admin
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/admin/request"
 	"github.com/teamhanko/passkey-server/api/dto/admin/response"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/crypto"
 	hankoJwk "github.com/teamhanko/passkey-server/crypto/jwk"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/utils"
+	"io"
+	"net/http"
+	"sort"
 	"time"
 )
 
 type TenantService interface {
 	List() (*response.ListTenantResponses, error)
 	Create(dto request.CreateTenantDto) (*response.CreateTenantResponse, error)
+	ImportTenant(dto request.CreateTenantDto, secrets models.Secrets) (*models.Tenant, error)
 	Update(dto request.UpdateTenantDto) error
-	UpdateConfig(dto request.UpdateConfigDto) error
+	Delete() error
+	UpdateConfig(dto request.UpdateConfigDto, changeType models.ConfigChangeType) error
+	MergeConfigPatch(patch []byte) (*request.UpdateConfigDto, error)
+	ListConfigVersions() (models.ConfigVersions, error)
+	DiffConfigVersions(from int, to int) ([]utils.JsonChange, error)
+	RollbackConfig(version int) error
+	DiffConfig(dto request.UpdateConfigDto) ([]utils.JsonChange, error)
 	ListAuditLogs(dto request.ListAuditLogDto) (models.AuditLogs, int, error)
+	ListAuditLogsAfter(dto request.ListAuditLogDto) (models.AuditLogs, string, error)
+	ExportAuditLogs(dto request.ExportAuditLogDto, w io.Writer) error
 }
 
 type tenantService struct {
 	logger echo.Logger
 	tenant *models.Tenant
+	actor  *string
+
+	auditLogger auditlog.AdminLogger
 
 	tenantPersister         persisters.TenantPersister
 	configPersister         persisters.ConfigPersister
@@ -35,11 +54,14 @@ type tenantService struct {
 	jwkPersister            persisters.JwkPersister
 	auditLogPersister       persisters.AuditLogPersister
 	mfaConfigPersister      persisters.MFAConfigPersister
+	configVersionPersister  persisters.ConfigVersionPersister
 }
 
 type CreateTenantServiceParams struct {
-	Ctx    echo.Context
-	Tenant *models.Tenant
+	Ctx         echo.Context
+	Tenant      *models.Tenant
+	Actor       *string
+	AuditLogger auditlog.AdminLogger
 
 	TenantPersister         persisters.TenantPersister
 	ConfigPersister         persisters.ConfigPersister
@@ -51,12 +73,16 @@ type CreateTenantServiceParams struct {
 	JwkPersister            persisters.JwkPersister
 	AuditLogPersister       persisters.AuditLogPersister
 	MFAConfigPersister      persisters.MFAConfigPersister
+	ConfigVersionPersister  persisters.ConfigVersionPersister
 }
 
 func NewTenantService(params CreateTenantServiceParams) TenantService {
 	return &tenantService{
 		logger: params.Ctx.Logger(),
 		tenant: params.Tenant,
+		actor:  params.Actor,
+		// only set for requests of the admin API, other callers like the CLI are not audited
+		auditLogger: params.AuditLogger,
 
 		tenantPersister:         params.TenantPersister,
 		configPersister:         params.ConfigPersister,
@@ -68,6 +94,7 @@ func NewTenantService(params CreateTenantServiceParams) TenantService {
 		jwkPersister:            params.JwkPersister,
 		auditLogPersister:       params.AuditLogPersister,
 		mfaConfigPersister:      params.MFAConfigPersister,
+		configVersionPersister:  params.ConfigVersionPersister,
 	}
 }
 
@@ -88,34 +115,11 @@ func (ts *tenantService) List() (*response.ListTenantResponses, error) {
 }
 
 func (ts *tenantService) Create(dto request.CreateTenantDto) (*response.CreateTenantResponse, error) {
-	// transform dto to model
-	tenantModel := dto.ToModel()
-	configModel := dto.Config.ToModel(tenantModel)
-	corsModel := dto.Config.Cors.ToModel(configModel)
-	passkeyConfigModel := dto.Config.Passkey.ToModel(configModel)
-	relyingPartyModel := dto.Config.Passkey.RelyingParty.ToModel(passkeyConfigModel)
-
-	var mfaConfigModel models.MfaConfig
-	if dto.Config.Mfa == nil {
-		mfaConfigModel = dto.Config.Passkey.ToMfaModel(configModel)
-	} else {
-		mfaConfigModel = dto.Config.Mfa.ToModel(configModel)
-	}
-
-	err := ts.tenantPersister.Create(&tenantModel)
+	tenantModel, configModel, err := ts.createTenantWithConfig(dto)
 	if err != nil {
-		ts.logger.Error(err)
 		return nil, err
 	}
 
-	err = ts.persistConfig(
-		&configModel,
-		&corsModel,
-		&passkeyConfigModel,
-		&relyingPartyModel,
-		&mfaConfigModel,
-	)
-
 	var apiSecretModel *models.Secret = nil
 	if dto.CreateApiKey {
 		apiSecretModel, err = ts.createSecret("Initial API Key", configModel.ID, true)
@@ -132,17 +136,115 @@ func (ts *tenantService) Create(dto request.CreateTenantDto) (*response.CreateTe
 	}
 
 	jwks := []string{jwkSecretModel.Key}
-	_, err = hankoJwk.NewDefaultManager(jwks, tenantModel.ID, ts.jwkPersister)
+	_, err = hankoJwk.NewDefaultManager(context.Background(), jwks, tenantModel.ID, ts.jwkPersister)
 	if err != nil {
 		ts.logger.Error(err)
 		return nil, fmt.Errorf("unable to initialize jwt generator: %w", err)
 	}
 
-	createResponse := response.ToCreateTenantResponse(&tenantModel, apiSecretModel)
+	createResponse := response.ToCreateTenantResponse(tenantModel, apiSecretModel)
 
 	return &createResponse, nil
 }
 
+// ImportTenant creates a tenant with the given secrets instead of generating new ones. The JWKs are not created, as
+// they might be imported as well.
+func (ts *tenantService) ImportTenant(dto request.CreateTenantDto, secrets models.Secrets) (*models.Tenant, error) {
+	tenantModel, configModel, err := ts.createTenantWithConfig(dto)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for _, secret := range secrets {
+		secretId, _ := uuid.NewV4()
+		secretModel := models.Secret{
+			ID:          secretId,
+			Name:        secret.Name,
+			Key:         secret.Key,
+			IsAPISecret: secret.IsAPISecret,
+			ConfigID:    configModel.ID,
+			CreatedAt:   secret.CreatedAt,
+			UpdatedAt:   now,
+		}
+
+		err = ts.secretPersister.Create(&secretModel)
+		if err != nil {
+			ts.logger.Error(err)
+			return nil, err
+		}
+
+		configModel.Secrets = append(configModel.Secrets, secretModel)
+	}
+
+	tenantModel.Config = *configModel
+
+	return tenantModel, nil
+}
+
+func (ts *tenantService) createTenantWithConfig(dto request.CreateTenantDto) (*models.Tenant, *models.Config, error) {
+	if !dto.Config.Passkey.HasUniqueRelyingPartyIds() {
+		return nil, nil, echo.NewHTTPError(http.StatusBadRequest, "relying party ids must be unique")
+	}
+
+	// transform dto to model
+	tenantModel := dto.ToModel()
+	configModel, corsModel, passkeyConfigModel, relyingPartyModels, mfaConfigModel := toConfigModels(dto.Config, tenantModel)
+
+	if dto.Id != nil {
+		existingTenant, err := ts.tenantPersister.Get(tenantModel.ID)
+		if err != nil {
+			ts.logger.Error(err)
+			return nil, nil, err
+		}
+
+		if existingTenant != nil {
+			return nil, nil, echo.NewHTTPError(http.StatusConflict, "tenant already exists")
+		}
+	}
+
+	err := ts.tenantPersister.Create(&tenantModel)
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, nil, err
+	}
+
+	err = ts.persistConfig(
+		&configModel,
+		&corsModel,
+		&passkeyConfigModel,
+		relyingPartyModels,
+		&mfaConfigModel,
+	)
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, nil, err
+	}
+
+	snapshot := snapshotConfig(configModel, corsModel, passkeyConfigModel, relyingPartyModels, mfaConfigModel)
+	err = ts.recordConfigVersion(tenantModel.ID, snapshot, models.ConfigChangeCreate)
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, nil, err
+	}
+
+	err = ts.audit(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminTenantCreated,
+		TenantId:   &tenantModel.ID,
+		TargetType: models.AuditLogTargetTenant,
+		TargetId:   tenantModel.ID.String(),
+		After: response.GetTenantResponse{
+			ListTenantResponse: response.ToListTenantResponse(&tenantModel),
+			Config:             normalizeConfigResponse(response.ToGetConfigResponse(&snapshot)),
+		},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &tenantModel, &configModel, nil
+}
+
 func (ts *tenantService) createSecret(name string, configId uuid.UUID, isAPIKey bool) (*models.Secret, error) {
 	secretId, err := uuid.NewV4()
 	if err != nil {
@@ -174,7 +276,7 @@ func (ts *tenantService) createSecret(name string, configId uuid.UUID, isAPIKey
 	return model, nil
 }
 
-func (ts *tenantService) persistConfig(config *models.Config, cors *models.Cors, webauthn *models.WebauthnConfig, rp *models.RelyingParty, mfaConfig *models.MfaConfig) error {
+func (ts *tenantService) persistConfig(config *models.Config, cors *models.Cors, webauthn *models.WebauthnConfig, relyingParties models.RelyingParties, mfaConfig *models.MfaConfig) error {
 	err := ts.configPersister.Create(config)
 	if err != nil {
 		return err
@@ -190,9 +292,11 @@ func (ts *tenantService) persistConfig(config *models.Config, cors *models.Cors,
 		return err
 	}
 
-	err = ts.relyingPartyPerister.Create(rp)
-	if err != nil {
-		return err
+	for i := range relyingParties {
+		err = ts.relyingPartyPerister.Create(&relyingParties[i])
+		if err != nil {
+			return err
+		}
 	}
 
 	err = ts.mfaConfigPersister.Create(mfaConfig)
@@ -209,6 +313,8 @@ func (ts *tenantService) persistConfig(config *models.Config, cors *models.Cors,
 }
 
 func (ts *tenantService) Update(dto request.UpdateTenantDto) error {
+	before := response.ToListTenantResponse(ts.tenant)
+
 	ts.tenant.DisplayName = dto.DisplayName
 	ts.tenant.UpdatedAt = time.Now()
 
@@ -218,28 +324,52 @@ func (ts *tenantService) Update(dto request.UpdateTenantDto) error {
 		return err
 	}
 
-	return nil
+	return ts.audit(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminTenantUpdated,
+		TenantId:   &ts.tenant.ID,
+		TargetType: models.AuditLogTargetTenant,
+		TargetId:   ts.tenant.ID.String(),
+		Before:     before,
+		After:      response.ToListTenantResponse(ts.tenant),
+	})
 }
 
-func (ts *tenantService) UpdateConfig(dto request.UpdateConfigDto) error {
-	config := ts.tenant.Config
-	newConfig := dto.ToModel(*ts.tenant)
-	corsModel := dto.Cors.ToModel(newConfig)
-	webauthnConfigModel := dto.Passkey.ToModel(newConfig)
-	relyingPartyModel := dto.Passkey.RelyingParty.ToModel(webauthnConfigModel)
+// Delete removes the tenant with all its data. The audit log entry is not attached to the tenant, else it would be
+// deleted as well.
+func (ts *tenantService) Delete() error {
+	err := ts.tenantPersister.Delete(ts.tenant)
+	if err != nil {
+		ts.logger.Error(err)
+		return err
+	}
 
-	var mfaConfigModel models.MfaConfig
-	if dto.Mfa == nil {
-		mfaConfigModel = dto.Passkey.ToMfaModel(newConfig)
-	} else {
-		mfaConfigModel = dto.Mfa.ToModel(newConfig)
+	tenant := response.ToGetTenantResponse(ts.tenant)
+	tenant.Config = normalizeConfigResponse(tenant.Config)
+
+	return ts.audit(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminTenantDeleted,
+		TargetType: models.AuditLogTargetTenant,
+		TargetId:   ts.tenant.ID.String(),
+		Before:     tenant,
+	})
+}
+
+func (ts *tenantService) UpdateConfig(dto request.UpdateConfigDto, changeType models.ConfigChangeType) error {
+	if !dto.Passkey.HasUniqueRelyingPartyIds() {
+		return echo.NewHTTPError(http.StatusBadRequest, "relying party ids must be unique")
+	}
+
+	config := ts.tenant.Config
+	newConfig, corsModel, webauthnConfigModel, relyingPartyModels, mfaConfigModel := toConfigModels(dto.CreateConfigDto, *ts.tenant)
+	if config.AuditLogConfig.IpHashSalt != "" {
+		newConfig.AuditLogConfig.IpHashSalt = config.AuditLogConfig.IpHashSalt
 	}
 
 	err := ts.persistConfig(
 		&newConfig,
 		&corsModel,
 		&webauthnConfigModel,
-		&relyingPartyModel,
+		relyingPartyModels,
 		&mfaConfigModel,
 	)
 
@@ -264,37 +394,265 @@ func (ts *tenantService) UpdateConfig(dto request.UpdateConfigDto) error {
 		return err
 	}
 
+	snapshot := snapshotConfig(newConfig, corsModel, webauthnConfigModel, relyingPartyModels, mfaConfigModel)
+	err = ts.recordConfigVersion(ts.tenant.ID, snapshot, changeType)
+	if err != nil {
+		ts.logger.Error(err)
+		return err
+	}
+
+	auditLogType := models.AuditLogAdminConfigUpdated
+	if changeType == models.ConfigChangeRollback {
+		auditLogType = models.AuditLogAdminConfigRolledBack
+	}
+
+	return ts.audit(auditlog.AdminEntry{
+		Type:       auditLogType,
+		TenantId:   &ts.tenant.ID,
+		TargetType: models.AuditLogTargetConfig,
+		TargetId:   newConfig.ID.String(),
+		Before:     normalizeConfigResponse(response.ToGetConfigResponse(&config)),
+		After:      normalizeConfigResponse(response.ToGetConfigResponse(&snapshot)),
+	})
+}
+
+// DiffConfig compares the current config of the tenant with the config described by the dto without storing anything
+func (ts *tenantService) DiffConfig(dto request.UpdateConfigDto) ([]utils.JsonChange, error) {
+	newConfig, corsModel, webauthnConfigModel, relyingPartyModels, mfaConfigModel := toConfigModels(dto.CreateConfigDto, *ts.tenant)
+
+	currentConfig, err := json.Marshal(normalizeConfigResponse(response.ToGetConfigResponse(&ts.tenant.Config)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize current config: %w", err)
+	}
+
+	snapshot := snapshotConfig(newConfig, corsModel, webauthnConfigModel, relyingPartyModels, mfaConfigModel)
+	desiredConfig, err := json.Marshal(normalizeConfigResponse(response.ToGetConfigResponse(&snapshot)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize config: %w", err)
+	}
+
+	return utils.Diff(currentConfig, desiredConfig)
+}
+
+// MergeConfigPatch applies a JSON merge patch (RFC 7386) to the current config of the tenant. The result still needs
+// to be validated before it is stored.
+func (ts *tenantService) MergeConfigPatch(patch []byte) (*request.UpdateConfigDto, error) {
+	currentConfig, err := json.Marshal(response.ToGetConfigResponse(&ts.tenant.Config))
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, fmt.Errorf("unable to serialize current config: %w", err)
+	}
+
+	patchedConfig, err := utils.MergePatch(currentConfig, patch)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid merge patch").SetInternal(err)
+	}
+
+	var dto request.UpdateConfigDto
+	err = json.Unmarshal(patchedConfig, &dto)
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "patched config is invalid").SetInternal(err)
+	}
+
+	return &dto, nil
+}
+
+func (ts *tenantService) ListConfigVersions() (models.ConfigVersions, error) {
+	versions, err := ts.configVersionPersister.List(ts.tenant.ID)
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (ts *tenantService) DiffConfigVersions(from int, to int) ([]utils.JsonChange, error) {
+	fromVersion, err := ts.getConfigVersion(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toVersion, err := ts.getConfigVersion(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := utils.Diff([]byte(fromVersion.Config), []byte(toVersion.Config))
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, fmt.Errorf("unable to compare config versions: %w", err)
+	}
+
+	return changes, nil
+}
+
+// RollbackConfig restores the config of the given version. The restored config is stored as a new version, so the
+// history is kept intact.
+func (ts *tenantService) RollbackConfig(version int) error {
+	configVersion, err := ts.getConfigVersion(version)
+	if err != nil {
+		return err
+	}
+
+	var dto request.UpdateConfigDto
+	err = json.Unmarshal([]byte(configVersion.Config), &dto)
+	if err != nil {
+		ts.logger.Error(err)
+		return fmt.Errorf("unable to restore config version %d: %w", version, err)
+	}
+
+	return ts.UpdateConfig(dto, models.ConfigChangeRollback)
+}
+
+func (ts *tenantService) getConfigVersion(version int) (*models.ConfigVersion, error) {
+	configVersion, err := ts.configVersionPersister.Get(ts.tenant.ID, version)
+	if err != nil {
+		ts.logger.Error(err)
+		return nil, err
+	}
+
+	if configVersion == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("config version %d not found", version))
+	}
+
+	return configVersion, nil
+}
+
+// recordConfigVersion stores a snapshot of the config as the next version of the tenant. Tenants created before
+// versioning existed get their current config recorded as a baseline first.
+func (ts *tenantService) recordConfigVersion(tenantId uuid.UUID, config models.Config, changeType models.ConfigChangeType) error {
+	latestVersion, err := ts.configVersionPersister.GetLatestVersion(tenantId)
+	if err != nil {
+		return err
+	}
+
+	if latestVersion == 0 && changeType != models.ConfigChangeCreate && ts.tenant != nil {
+		err = ts.createConfigVersion(tenantId, 1, ts.tenant.Config, models.ConfigChangeCreate, nil)
+		if err != nil {
+			return err
+		}
+
+		latestVersion = 1
+	}
+
+	return ts.createConfigVersion(tenantId, latestVersion+1, config, changeType, ts.actor)
+}
+
+func (ts *tenantService) createConfigVersion(tenantId uuid.UUID, version int, config models.Config, changeType models.ConfigChangeType, changedBy *string) error {
+	snapshot, err := json.Marshal(response.ToGetConfigResponse(&config))
+	if err != nil {
+		return fmt.Errorf("unable to serialize config: %w", err)
+	}
+
+	versionId, _ := uuid.NewV4()
+	now := time.Now()
+
+	return ts.configVersionPersister.Create(&models.ConfigVersion{
+		ID:         versionId,
+		TenantID:   tenantId,
+		Version:    version,
+		ChangeType: changeType,
+		ChangedBy:  changedBy,
+		Config:     string(snapshot),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+}
+
+// audit stores an admin audit log entry. Services which are not used by the admin API have no audit logger.
+func (ts *tenantService) audit(entry auditlog.AdminEntry) error {
+	if ts.auditLogger == nil {
+		return nil
+	}
+
+	err := ts.auditLogger.Create(entry)
+	if err != nil {
+		ts.logger.Error(err)
+		return err
+	}
+
 	return nil
 }
 
+func toConfigModels(dto request.CreateConfigDto, tenant models.Tenant) (models.Config, models.Cors, models.WebauthnConfig, models.RelyingParties, models.MfaConfig) {
+	configModel := dto.ToModel(tenant)
+	corsModel := dto.Cors.ToModel(configModel)
+	webauthnConfigModel := dto.Passkey.ToModel(configModel)
+	relyingPartyModels := dto.Passkey.ToRelyingPartyModels(webauthnConfigModel)
+
+	var mfaConfigModel models.MfaConfig
+	if dto.Mfa == nil {
+		mfaConfigModel = dto.Passkey.ToMfaModel(configModel)
+	} else {
+		mfaConfigModel = dto.Mfa.ToModel(configModel)
+	}
+
+	return configModel, corsModel, webauthnConfigModel, relyingPartyModels, mfaConfigModel
+}
+
+// normalizeConfigResponse sorts all lists whose order has no meaning, so they are not reported as changes
+func normalizeConfigResponse(config response.GetConfigResponse) response.GetConfigResponse {
+	sort.Strings(config.Cors.AllowedOrigins)
+	sort.Strings(config.Webauthn.TransportStripping.UserAgentPatterns)
+	sort.Strings(config.Webauthn.RelyingParty.Origins)
+	for i := range config.Webauthn.AdditionalRelyingParties {
+		sort.Strings(config.Webauthn.AdditionalRelyingParties[i].Origins)
+	}
+
+	sort.Slice(config.Webauthn.AdditionalRelyingParties, func(i, j int) bool {
+		return config.Webauthn.AdditionalRelyingParties[i].Id < config.Webauthn.AdditionalRelyingParties[j].Id
+	})
+
+	return config
+}
+
+// snapshotConfig assembles a config with all its children for serialization. The children are persisted separately,
+// so they are only attached to a copy of the config.
+func snapshotConfig(config models.Config, cors models.Cors, webauthnConfig models.WebauthnConfig, relyingParties models.RelyingParties, mfaConfig models.MfaConfig) models.Config {
+	webauthnConfig.RelyingParties = relyingParties
+	config.Cors = cors
+	config.WebauthnConfig = webauthnConfig
+	config.MfaConfig = &mfaConfig
+
+	return config
+}
+
 func (ts *tenantService) ListAuditLogs(dto request.ListAuditLogDto) (models.AuditLogs, int, error) {
-	options := persisters.AuditLogOptions{
-		Page:     dto.Page,
-		PerPage:  dto.PerPage,
-		Start:    dto.StartTime,
-		End:      dto.EndTime,
-		Types:    dto.Types,
-		UserId:   dto.UserId,
-		Ip:       dto.IP,
-		Search:   dto.SearchString,
-		TenantId: ts.tenant.ID.String(),
+	options := toAuditLogOptions(dto)
+	options.TenantId = ts.tenant.ID.String()
+
+	auditLogs, logCount, err := listAuditLogs(ts.auditLogPersister, options)
+	if err != nil {
+		ts.logger.Error(err)
+		return auditLogs, 0, err
 	}
 
-	auditLogs := make(models.AuditLogs, 0)
+	return auditLogs, logCount, nil
+}
 
-	auditLogEntries, err := ts.auditLogPersister.List(options)
+func (ts *tenantService) ListAuditLogsAfter(dto request.ListAuditLogDto) (models.AuditLogs, string, error) {
+	options := toAuditLogOptions(dto)
+	options.TenantId = ts.tenant.ID.String()
+
+	auditLogs, nextCursor, err := listAuditLogsAfter(ts.auditLogPersister, options, dto.Cursor, dto.PerPage)
 	if err != nil {
 		ts.logger.Error(err)
-		return auditLogs, 0, fmt.Errorf("failed to get list of audit logs: %w", err)
+		return auditLogs, "", err
 	}
 
-	auditLogs = append(auditLogs, auditLogEntries...)
+	return auditLogs, nextCursor, nil
+}
+
+func (ts *tenantService) ExportAuditLogs(dto request.ExportAuditLogDto, w io.Writer) error {
+	options := toAuditLogFilterOptions(dto.AuditLogFilterDto)
+	options.TenantId = ts.tenant.ID.String()
 
-	logCount, err := ts.auditLogPersister.Count(options)
+	err := exportAuditLogs(ts.auditLogPersister, options, dto.Format, w)
 	if err != nil {
 		ts.logger.Error(err)
-		return auditLogs, 0, fmt.Errorf("failed to get total count of audit logs: %w", err)
+		return err
 	}
 
-	return auditLogs, logCount, nil
+	return nil
 }
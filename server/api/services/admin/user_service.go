@@ -1,52 +1,97 @@
 package admin
 
 import (
+	"errors"
 	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/admin/request"
 	"github.com/teamhanko/passkey-server/api/dto/admin/response"
+	publicResponse "github.com/teamhanko/passkey-server/api/dto/response"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type UserService interface {
 	List(request request.UserListRequest) ([]response.UserListDto, int, error)
 	Get(userId uuid.UUID) (*response.UserGetDto, error)
+	Create(dto request.CreateUserDto) (*response.UserGetDto, error)
+	Update(userId uuid.UUID, dto request.UpdateUserDto) (*response.UserGetDto, error)
+	SetDisabled(userId uuid.UUID, disabled bool) error
 	Delete(userId uuid.UUID) error
+	ExportData(userId uuid.UUID) (*response.UserDataExport, error)
+	Erase(userId uuid.UUID) (*response.UserErasureDto, error)
+	ListCredentials(userId uuid.UUID) ([]publicResponse.CredentialDto, error)
+	UpdateCredential(userId uuid.UUID, credentialId string, dto request.UpdateUserCredentialDto) (*publicResponse.CredentialDto, error)
+	SetCredentialStatus(userId uuid.UUID, credentialId string, status models.CredentialStatus, dto request.UpdateUserCredentialStatusDto) (*models.WebauthnCredential, error)
+	DeleteCredential(userId uuid.UUID, credentialId string) error
 }
 
 type CreateUserServiceParams struct {
-	Ctx    echo.Context
-	Tenant models.Tenant
+	Ctx         echo.Context
+	Tenant      models.Tenant
+	AuditLogger auditlog.AdminLogger
 
-	UserPersister persisters.WebauthnUserPersister
+	UserPersister       persisters.WebauthnUserPersister
+	CredentialPersister persisters.WebauthnCredentialPersister
+	// SessionDataPersister and AuditLogPersister are only required to export or erase the data of a user
+	SessionDataPersister persisters.WebauthnSessionDataPersister
+	AuditLogPersister    persisters.AuditLogPersister
 }
 
 type userService struct {
-	ctx           echo.Context
-	tenant        models.Tenant
-	userPersister persisters.WebauthnUserPersister
+	ctx                  echo.Context
+	tenant               models.Tenant
+	auditLogger          auditlog.AdminLogger
+	userPersister        persisters.WebauthnUserPersister
+	credentialPersister  persisters.WebauthnCredentialPersister
+	sessionDataPersister persisters.WebauthnSessionDataPersister
+	auditLogPersister    persisters.AuditLogPersister
 }
 
 func NewUserService(params CreateUserServiceParams) UserService {
 	return &userService{
-		ctx:           params.Ctx,
-		tenant:        params.Tenant,
-		userPersister: params.UserPersister,
+		ctx:                  params.Ctx,
+		tenant:               params.Tenant,
+		auditLogger:          params.AuditLogger,
+		userPersister:        params.UserPersister,
+		credentialPersister:  params.CredentialPersister,
+		sessionDataPersister: params.SessionDataPersister,
+		auditLogPersister:    params.AuditLogPersister,
 	}
 }
 
 func (us *userService) List(listRequest request.UserListRequest) ([]response.UserListDto, int, error) {
 	list := make([]response.UserListDto, 0)
 
-	count, err := us.userPersister.Count(us.tenant.ID)
+	options := persisters.WebauthnUserOptions{
+		TenantId:      us.tenant.ID,
+		Page:          listRequest.Page,
+		PerPage:       listRequest.PerPage,
+		SortBy:        persisters.WebauthnUserSortField(listRequest.SortBy),
+		SortDirection: listRequest.SortDirection,
+		UserId:        strings.TrimSpace(listRequest.UserId),
+		Name:          strings.TrimSpace(listRequest.Name),
+		DisplayName:   strings.TrimSpace(listRequest.DisplayName),
+		Search:        strings.TrimSpace(listRequest.Query),
+		Credentials:   persisters.WebauthnUserCredentialFilter(listRequest.Credentials),
+	}
+
+	if listRequest.UnusedForDays > 0 {
+		unusedSince := time.Now().UTC().AddDate(0, 0, -listRequest.UnusedForDays)
+		options.UnusedSince = &unusedSince
+	}
+
+	count, err := us.userPersister.Count(options)
 	if err != nil {
 		us.ctx.Logger().Error(err)
 		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError, "unable to count users").SetInternal(err)
 	}
 
-	users, err := us.userPersister.AllForTenant(us.tenant.ID, listRequest.Page, listRequest.PerPage, listRequest.SortDirection)
+	users, err := us.userPersister.AllForTenant(options)
 	if err != nil {
 		us.ctx.Logger().Error(err)
 		return nil, 0, echo.NewHTTPError(http.StatusInternalServerError, "unable to list users").SetInternal(err)
@@ -60,29 +105,114 @@ func (us *userService) List(listRequest request.UserListRequest) ([]response.Use
 }
 
 func (us *userService) Get(userId uuid.UUID) (*response.UserGetDto, error) {
-	user, err := us.userPersister.GetById(userId)
+	user, err := us.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := response.UserGetDtoFromModel(*user)
+	return &dto, nil
+}
+
+func (us *userService) Create(dto request.CreateUserDto) (*response.UserGetDto, error) {
+	existingUser, err := us.userPersister.GetByUserId(dto.UserId, us.tenant.ID)
 	if err != nil {
 		us.ctx.Logger().Error(err)
 		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to get user from db").SetInternal(err)
 	}
 
-	if user == nil {
-		return nil, echo.NewHTTPError(http.StatusNotFound, "user not found")
+	if existingUser != nil {
+		return nil, echo.NewHTTPError(http.StatusConflict, "user already exists")
 	}
 
-	dto := response.UserGetDtoFromModel(*user)
-	return &dto, nil
+	id, _ := uuid.NewV4()
+	now := time.Now().UTC()
+	user := models.WebauthnUser{
+		ID:        id,
+		UserID:    dto.UserId,
+		TenantID:  us.tenant.ID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	setUserProfile(&user, dto.Name, dto.DisplayName, dto.Icon)
+
+	err = us.userPersister.Create(&user)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to store user").SetInternal(err)
+	}
+
+	err = us.auditUser(models.AuditLogAdminUserCreated, nil, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	userDto := response.UserGetDtoFromModel(user)
+	return &userDto, nil
 }
 
-func (us *userService) Delete(userId uuid.UUID) error {
-	user, err := us.userPersister.GetById(userId)
+func (us *userService) Update(userId uuid.UUID, dto request.UpdateUserDto) (*response.UserGetDto, error) {
+	user, err := us.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *user
+	setUserProfile(user, dto.Name, dto.DisplayName, dto.Icon)
+	user.UpdatedAt = time.Now().UTC()
+
+	err = us.userPersister.Update(user)
 	if err != nil {
 		us.ctx.Logger().Error(err)
-		return echo.NewHTTPError(http.StatusInternalServerError, "unable to get user from db").SetInternal(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to update user").SetInternal(err)
 	}
 
-	if user == nil {
-		return echo.NewHTTPError(http.StatusNotFound, "user not found")
+	err = us.auditUser(models.AuditLogAdminUserUpdated, &before, user)
+	if err != nil {
+		return nil, err
+	}
+
+	userDto := response.UserGetDtoFromModel(*user)
+	return &userDto, nil
+}
+
+// SetDisabled disables or enables the user. Disabling a disabled user keeps the original time.
+func (us *userService) SetDisabled(userId uuid.UUID, disabled bool) error {
+	user, err := us.getUser(userId)
+	if err != nil {
+		return err
+	}
+
+	if user.IsDisabled() == disabled {
+		return nil
+	}
+
+	before := *user
+	now := time.Now().UTC()
+	user.DisabledAt = nil
+	if disabled {
+		user.DisabledAt = &now
+	}
+	user.UpdatedAt = now
+
+	err = us.userPersister.Update(user)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to update user").SetInternal(err)
+	}
+
+	auditLogType := models.AuditLogAdminUserEnabled
+	if disabled {
+		auditLogType = models.AuditLogAdminUserDisabled
+	}
+
+	return us.auditUser(auditLogType, &before, user)
+}
+
+func (us *userService) Delete(userId uuid.UUID) error {
+	user, err := us.getUser(userId)
+	if err != nil {
+		return err
 	}
 
 	err = us.userPersister.Delete(user)
@@ -91,5 +221,309 @@ func (us *userService) Delete(userId uuid.UUID) error {
 		return echo.NewHTTPError(http.StatusInternalServerError, "unable to delete user from db").SetInternal(err)
 	}
 
+	return us.auditUser(models.AuditLogAdminUserDeleted, user, nil)
+}
+
+func (us *userService) ExportData(userId uuid.UUID) (*response.UserDataExport, error) {
+	user, err := us.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionData, err := us.sessionDataPersister.ListByUserId(user.UserID, us.tenant.ID)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to get session data from db").SetInternal(err)
+	}
+
+	auditLogs, err := us.listUserAuditLogs(user)
+	if err != nil {
+		return nil, err
+	}
+
+	export := response.UserDataExportFromModels(*user, sessionData, auditLogs)
+
+	err = us.audit(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminUserExported,
+		TenantId:   &us.tenant.ID,
+		TargetType: models.AuditLogTargetUser,
+		TargetId:   user.ID.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &export, nil
+}
+
+// Erase deletes the user with its credentials, transactions and session data. The audit logs of the user are
+// pseudonymized instead of deleted, so the audit log chain of the tenant stays intact.
+func (us *userService) Erase(userId uuid.UUID) (*response.UserErasureDto, error) {
+	user, err := us.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLogs, err := us.listUserAuditLogs(user)
+	if err != nil {
+		return nil, err
+	}
+
+	pseudonymId, err := uuid.NewV4()
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to create pseudonym").SetInternal(err)
+	}
+
+	pseudonym := pseudonymId.String()
+	userIds := []string{user.UserID, user.ID.String()}
+	now := time.Now().UTC()
+	for i := range auditLogs {
+		auditlog.Pseudonymize(&auditLogs[i], pseudonym, userIds, now)
+		err = us.auditLogPersister.Pseudonymize(&auditLogs[i])
+		if err != nil {
+			us.ctx.Logger().Error(err)
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to pseudonymize audit logs").SetInternal(err)
+		}
+	}
+
+	err = us.sessionDataPersister.DeleteByUserId(user.UserID, us.tenant.ID)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to delete session data from db").SetInternal(err)
+	}
+
+	err = us.userPersister.Delete(user)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to delete user from db").SetInternal(err)
+	}
+
+	err = us.audit(auditlog.AdminEntry{
+		Type:       models.AuditLogAdminUserErased,
+		TenantId:   &us.tenant.ID,
+		TargetType: models.AuditLogTargetUser,
+		TargetId:   pseudonym,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.UserErasureDto{
+		Pseudonym:              pseudonym,
+		PseudonymizedAuditLogs: len(auditLogs),
+	}, nil
+}
+
+// listUserAuditLogs returns the entries of the user's ceremonies and of admin operations on the user or its
+// credentials, including credentials which were already deleted
+func (us *userService) listUserAuditLogs(user *models.WebauthnUser) (models.AuditLogs, error) {
+	targetIds := []string{user.ID.String()}
+	for _, credential := range user.WebauthnCredentials {
+		targetIds = append(targetIds, credential.ID)
+	}
+
+	auditLogs, err := us.auditLogPersister.ListForUser(us.tenant.ID, user.UserID, targetIds)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to get audit logs from db").SetInternal(err)
+	}
+
+	return auditLogs, nil
+}
+
+func (us *userService) ListCredentials(userId uuid.UUID) ([]publicResponse.CredentialDto, error) {
+	user, err := us.getUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.UserGetDtoFromModel(*user).Credentials, nil
+}
+
+func (us *userService) UpdateCredential(userId uuid.UUID, credentialId string, dto request.UpdateUserCredentialDto) (*publicResponse.CredentialDto, error) {
+	credential, err := us.getCredential(userId, credentialId)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *credential
+	credential.Name = &dto.Name
+	credential.UpdatedAt = time.Now().UTC()
+
+	err = us.credentialPersister.Update(credential)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to update credential").SetInternal(err)
+	}
+
+	err = us.auditCredential(models.AuditLogAdminCredentialUpdated, &before, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialDto := publicResponse.CredentialDtoFromModel(*credential, nil)
+	return &credentialDto, nil
+}
+
+func (us *userService) SetCredentialStatus(userId uuid.UUID, credentialId string, status models.CredentialStatus, dto request.UpdateUserCredentialStatusDto) (*models.WebauthnCredential, error) {
+	credential, err := us.getCredential(userId, credentialId)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *credential
+	err = credential.SetStatus(status, dto.Reason)
+	if errors.Is(err, models.ErrCredentialRevoked) {
+		return nil, echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+
+	err = us.credentialPersister.Update(credential)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to update credential").SetInternal(err)
+	}
+
+	auditLogType := models.AuditLogAdminCredentialEnabled
+	switch status {
+	case models.CredentialStatusDisabled:
+		auditLogType = models.AuditLogAdminCredentialDisabled
+	case models.CredentialStatusRevoked:
+		auditLogType = models.AuditLogAdminCredentialRevoked
+	}
+
+	err = us.auditCredential(auditLogType, &before, credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+func (us *userService) DeleteCredential(userId uuid.UUID, credentialId string) error {
+	credential, err := us.getCredential(userId, credentialId)
+	if err != nil {
+		return err
+	}
+
+	err = us.credentialPersister.Delete(credential)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to delete credential").SetInternal(err)
+	}
+
+	return us.auditCredential(models.AuditLogAdminCredentialDeleted, credential, nil)
+}
+
+// auditUser stores the profile of the user before and after the operation. Services which are not used by the admin
+// API have no audit logger.
+func (us *userService) auditUser(auditLogType models.AuditLogType, before *models.WebauthnUser, after *models.WebauthnUser) error {
+	entry := auditlog.AdminEntry{
+		Type:       auditLogType,
+		TenantId:   &us.tenant.ID,
+		TargetType: models.AuditLogTargetUser,
+	}
+
+	if before != nil {
+		entry.TargetId = before.ID.String()
+		entry.Before = response.UserListDtoFromModel(*before)
+	}
+
+	if after != nil {
+		entry.TargetId = after.ID.String()
+		entry.After = response.UserListDtoFromModel(*after)
+	}
+
+	return us.audit(entry)
+}
+
+// auditCredential links the entry to the owner of the credential, so it is pseudonymized with the user even after
+// the credential was deleted
+func (us *userService) auditCredential(auditLogType models.AuditLogType, before *models.WebauthnCredential, after *models.WebauthnCredential) error {
+	entry := auditlog.AdminEntry{
+		Type:       auditLogType,
+		TenantId:   &us.tenant.ID,
+		TargetType: models.AuditLogTargetCredential,
+	}
+
+	var ownerId uuid.UUID
+	if before != nil {
+		entry.TargetId = before.ID
+		entry.Before = publicResponse.CredentialDtoFromModel(*before, nil)
+		ownerId = before.WebauthnUserID
+	}
+
+	if after != nil {
+		entry.TargetId = after.ID
+		entry.After = publicResponse.CredentialDtoFromModel(*after, nil)
+		ownerId = after.WebauthnUserID
+	}
+
+	owner, err := us.userPersister.GetById(ownerId)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to get user from db").SetInternal(err)
+	}
+
+	if owner != nil {
+		entry.UserId = owner.UserID
+	}
+
+	return us.audit(entry)
+}
+
+func (us *userService) audit(entry auditlog.AdminEntry) error {
+	if us.auditLogger == nil {
+		return nil
+	}
+
+	err := us.auditLogger.Create(entry)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return echo.NewHTTPError(http.StatusInternalServerError, "unable to store audit log").SetInternal(err)
+	}
+
 	return nil
 }
+
+// getUser only returns users of the tenant of the service
+func (us *userService) getUser(userId uuid.UUID) (*models.WebauthnUser, error) {
+	user, err := us.userPersister.GetById(userId)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to get user from db").SetInternal(err)
+	}
+
+	if user == nil || user.TenantID != us.tenant.ID {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "user not found")
+	}
+
+	return user, nil
+}
+
+func (us *userService) getCredential(userId uuid.UUID, credentialId string) (*models.WebauthnCredential, error) {
+	credential, err := us.credentialPersister.Get(credentialId, us.tenant.ID)
+	if err != nil {
+		us.ctx.Logger().Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "unable to get credential from db").SetInternal(err)
+	}
+
+	if credential == nil || credential.WebauthnUserID != userId {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "credential not found")
+	}
+
+	return credential, nil
+}
+
+// setUserProfile sets the profile like a registration does, so the display name falls back to the name
+func setUserProfile(user *models.WebauthnUser, name string, displayName *string, icon *string) {
+	user.Name = name
+	user.DisplayName = name
+	if displayName != nil && len(strings.TrimSpace(*displayName)) > 0 {
+		user.DisplayName = *displayName
+	}
+
+	user.Icon = ""
+	if icon != nil {
+		user.Icon = *icon
+	}
+}
@@ -1,14 +1,25 @@
 package services
 
 import (
+	"context"
+
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type BaseService struct {
+	ctx    context.Context
 	logger echo.Logger
 	tenant models.Tenant
 
 	credentialPersister persisters.WebauthnCredentialPersister
 }
+
+// startSpan starts a span for a ceremony step as child of the request span. The returned context contains the span,
+// so the queries of the step can be traced as its children.
+func (bs *BaseService) startSpan(name string, operation models.Operation) (context.Context, trace.Span) {
+	return tracing.Start(bs.ctx, name, tracing.TenantId(bs.tenant.ID), tracing.Operation(string(operation)))
+}
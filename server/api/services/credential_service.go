@@ -1,49 +1,85 @@
 package services
 
 import (
+	"errors"
 	"fmt"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/request"
 	"github.com/teamhanko/passkey-server/api/dto/response"
+	"github.com/teamhanko/passkey-server/mapper"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
 	"net/http"
 )
 
 type CredentialService interface {
-	List(dto request.ListCredentialsDto) (response.CredentialDtoList, error)
+	List(dto request.ListCredentialsDto) (response.CredentialDtoList, int, error)
 	Get(dto request.GetCredentialDto) (*models.WebauthnCredential, error)
 	Update(dto request.UpdateCredentialsDto) (*models.WebauthnCredential, error)
 	Delete(dto request.DeleteCredentialsDto) error
+	SetStatus(dto request.UpdateCredentialStatusDto, status models.CredentialStatus) (*models.WebauthnCredential, error)
 }
 
 type credentialService struct {
 	*BaseService
+	authenticatorMetadata mapper.AuthenticatorMetadata
 }
 
-func NewCredentialService(ctx echo.Context, tenant models.Tenant, credentialPersister persisters.WebauthnCredentialPersister) CredentialService {
+func NewCredentialService(ctx echo.Context, tenant models.Tenant, credentialPersister persisters.WebauthnCredentialPersister, authenticatorMetadata mapper.AuthenticatorMetadata) CredentialService {
 	return &credentialService{
 		&BaseService{
+			ctx:                 ctx.Request().Context(),
 			logger:              ctx.Logger(),
 			tenant:              tenant,
 			credentialPersister: credentialPersister,
 		},
+		authenticatorMetadata,
 	}
 }
 
-func (cs *credentialService) List(dto request.ListCredentialsDto) (response.CredentialDtoList, error) {
-	credentialModels, err := cs.credentialPersister.GetFromUser(dto.UserId, cs.tenant.ID)
+func (cs *credentialService) List(dto request.ListCredentialsDto) (response.CredentialDtoList, int, error) {
+	options := persisters.WebauthnCredentialOptions{
+		TenantId:       cs.tenant.ID,
+		UserId:         dto.UserId,
+		Page:           dto.Page,
+		PerPage:        dto.PerPage,
+		SortBy:         persisters.WebauthnCredentialSortField(dto.SortBy),
+		SortDirection:  dto.SortDirection,
+		Type:           persisters.WebauthnCredentialType(dto.Type),
+		BackupEligible: dto.BackupEligible,
+		BackupState:    dto.BackupState,
+		LastUsedBefore: dto.LastUsedBefore,
+		LastUsedAfter:  dto.LastUsedAfter,
+	}
+
+	if dto.AAGUID != "" {
+		aaguid, err := uuid.FromString(dto.AAGUID)
+		if err != nil {
+			return nil, 0, echo.NewHTTPError(http.StatusBadRequest, "invalid aaguid").SetInternal(err)
+		}
+
+		options.AAGUID = &aaguid
+	}
+
+	credentialModels, err := cs.credentialPersister.List(options)
 	if err != nil {
 		cs.logger.Error(err)
-		return nil, err
+		return nil, 0, err
+	}
+
+	count, err := cs.credentialPersister.Count(options)
+	if err != nil {
+		cs.logger.Error(err)
+		return nil, 0, err
 	}
 
 	dtos := make(response.CredentialDtoList, len(credentialModels))
 	for i := range credentialModels {
-		dtos[i] = response.CredentialDtoFromModel(credentialModels[i])
+		dtos[i] = response.CredentialDtoFromModel(credentialModels[i], cs.authenticatorMetadata)
 	}
 
-	return dtos, nil
+	return dtos, count, nil
 }
 
 func (cs *credentialService) Get(dto request.GetCredentialDto) (*models.WebauthnCredential, error) {
@@ -101,3 +137,28 @@ func (cs *credentialService) Delete(dto request.DeleteCredentialsDto) error {
 
 	return nil
 }
+
+func (cs *credentialService) SetStatus(dto request.UpdateCredentialStatusDto, status models.CredentialStatus) (*models.WebauthnCredential, error) {
+	credential, err := cs.credentialPersister.Get(dto.CredentialId, cs.tenant.ID)
+	if err != nil {
+		cs.logger.Error(err)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, err)
+	}
+
+	if credential == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, fmt.Errorf("credential with id '%s' not found", dto.CredentialId))
+	}
+
+	err = credential.SetStatus(status, dto.Reason)
+	if errors.Is(err, models.ErrCredentialRevoked) {
+		return nil, echo.NewHTTPError(http.StatusConflict, err.Error())
+	}
+
+	err = cs.credentialPersister.Update(credential)
+	if err != nil {
+		cs.logger.Error(err)
+		return nil, err
+	}
+
+	return credential, nil
+}
@@ -2,23 +2,36 @@ package services
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/intern"
+	"github.com/teamhanko/passkey-server/crypto/jwt"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/tracing"
 	"net/http"
+	"time"
 )
 
+// errUnknownAttachment is returned when an attachment is required for a credential without stored transports
+const errUnknownAttachment = "the attachment of the credential is unknown, as no transports were stored at its registration"
+
 type LoginService interface {
-	Initialize() (*protocol.CredentialAssertion, error)
-	Finalize(req *protocol.ParsedCredentialAssertionData) (string, string, error)
+	Initialize(stepUp StepUpOptions) (*protocol.CredentialAssertion, error)
+	// Finalize returns the token, the user handle and whether the token is an intermediate token which has to be
+	// completed with a MFA login
+	Finalize(req *protocol.ParsedCredentialAssertionData) (string, string, bool, error)
 }
 
 type loginService struct {
 	WebauthnService
-	userId *string
+	userId   *string
+	mfaToken *string
 }
 
 func NewLoginService(params WebauthnServiceCreateParams) LoginService {
@@ -26,36 +39,108 @@ func NewLoginService(params WebauthnServiceCreateParams) LoginService {
 	return &loginService{
 		WebauthnService{
 			BaseService: &BaseService{
+				ctx:                 params.Ctx.Request().Context(),
 				logger:              params.Ctx.Logger(),
 				tenant:              params.Tenant,
 				credentialPersister: params.CredentialPersister,
 			},
 			webauthnClient: params.WebauthnClient,
 			generator:      params.Generator,
+			relyingParty:   params.RelyingParty,
+			userAgent:      params.Ctx.Request().UserAgent(),
 
 			userPersister:        params.UserPersister,
 			sessionDataPersister: params.SessionPersister,
 			useMFA:               params.UseMFA,
 		},
 		params.UserId,
+		params.MfaToken,
 	}
 }
 
-func (ls *loginService) Initialize() (*protocol.CredentialAssertion, error) {
+func (ls *loginService) Initialize(stepUp StepUpOptions) (_ *protocol.CredentialAssertion, err error) {
+	ctx, span := ls.startSpan("loginService.Initialize", models.WebauthnOperationAuthentication)
+	defer func() { tracing.End(span, err) }()
+	ls.bindPersisters(ctx)
+
 	var credentialAssertion *protocol.CredentialAssertion
 	var sessionData *webauthn.SessionData
-	var err error
 	isDiscoverable := true
 
+	var loginOptions []webauthn.LoginOption
+	if stepUp.UserVerification != nil {
+		loginOptions = append(loginOptions, webauthn.WithUserVerification(*stepUp.UserVerification))
+	}
+
+	if ls.mfaToken != nil {
+		if !ls.useMFA || ls.userId == nil {
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "mfa_token can only be used for a MFA login of a user")
+		}
+
+		_, tokenId, err := ls.generator.VerifyMfaRequired(*ls.mfaToken, *ls.userId)
+		if err != nil {
+			ls.logger.Error(err)
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "invalid mfa token").SetInternal(err)
+		}
+
+		pendingMfaLogin, err := ls.getPendingMfaLogin(tokenId, *ls.userId)
+		if err != nil {
+			ls.logger.Error(err)
+			return nil, err
+		}
+
+		if pendingMfaLogin == nil {
+			return nil, echo.NewHTTPError(http.StatusUnauthorized, "mfa token was already used")
+		}
+	} else if ls.useMFA && ls.isMfaRequiredForLogin() {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "a MFA login requires the mfa_token of a passkey login")
+	}
+
+	if stepUp.CredentialId != nil && ls.userId == nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "credential_id can only be used together with a user_id")
+	}
+
 	if ls.userId != nil {
 		user, err := ls.getWebauthnUserByUserHandle(*ls.userId)
+		if errors.Is(err, ErrUserDisabled) {
+			return nil, err
+		}
+
 		if err != nil {
 			ls.logger.Error(err)
 
 			return nil, echo.NewHTTPError(http.StatusNotFound, err)
 		}
 
-		credentialAssertion, sessionData, err = ls.webauthnClient.BeginLogin(user)
+		if stepUp.CredentialId != nil {
+			credential := user.FindCredentialById(*stepUp.CredentialId)
+			if credential == nil {
+				return nil, echo.NewHTTPError(http.StatusNotFound, "credential not found")
+			}
+
+			if stepUp.Attachment != nil {
+				attachment := credential.Transports.Attachment()
+				if attachment == "" {
+					return nil, echo.NewHTTPError(http.StatusBadRequest, errUnknownAttachment)
+				}
+
+				if attachment != *stepUp.Attachment {
+					return nil, echo.NewHTTPError(http.StatusBadRequest, "the credential does not have the required attachment")
+				}
+			}
+
+			allowedCredential := intern.WebauthnCredentialFromModel(credential).Descriptor()
+			loginOptions = append(loginOptions, webauthn.WithAllowedCredentials([]protocol.CredentialDescriptor{allowedCredential}))
+		} else if stepUp.Attachment != nil {
+			allowedCredentials := ls.filterCredentialsByAttachment(user, *stepUp.Attachment)
+			if len(allowedCredentials) == 0 {
+				return nil, echo.NewHTTPError(http.StatusNotFound, "no credential with the required attachment found, credentials without stored transports have an unknown attachment")
+			}
+
+			loginOptions = append(loginOptions, webauthn.WithAllowedCredentials(allowedCredentials))
+		}
+
+		credentialAssertion, sessionData, err = ls.webauthnClient.BeginLogin(user, loginOptions...)
 		if err != nil {
 			ls.logger.Error(err)
 			return nil, echo.NewHTTPError(
@@ -66,7 +151,7 @@ func (ls *loginService) Initialize() (*protocol.CredentialAssertion, error) {
 
 		isDiscoverable = false
 	} else {
-		credentialAssertion, sessionData, err = ls.webauthnClient.BeginDiscoverableLogin()
+		credentialAssertion, sessionData, err = ls.webauthnClient.BeginDiscoverableLogin(loginOptions...)
 
 		if err != nil {
 			ls.logger.Error(err)
@@ -77,27 +162,34 @@ func (ls *loginService) Initialize() (*protocol.CredentialAssertion, error) {
 		}
 	}
 
-	err = ls.sessionDataPersister.Create(*intern.WebauthnSessionDataToModel(sessionData, ls.tenant.ID, models.WebauthnOperationAuthentication, isDiscoverable))
+	sessionDataModel := intern.WebauthnSessionDataToModel(sessionData, ls.tenant.ID, models.WebauthnOperationAuthentication, isDiscoverable)
+	if stepUp.Attachment != nil {
+		requiredAttachment := string(*stepUp.Attachment)
+		sessionDataModel.RequiredAttachment = &requiredAttachment
+	}
+	sessionDataModel.MfaToken = ls.mfaToken
+
+	err = ls.sessionDataPersister.Create(*sessionDataModel)
 	if err != nil {
 		ls.logger.Error(err)
 		return nil, err
 	}
 
-	// Remove all transports, because of a bug in android and windows where the internal authenticator gets triggered,
-	// when the transports array contains the type 'internal' although the credential is not available on the device.
-	for i := range credentialAssertion.Response.AllowedCredentials {
-		credentialAssertion.Response.AllowedCredentials[i].Transport = nil
-	}
+	ls.stripTransports(credentialAssertion)
 
 	return credentialAssertion, nil
 }
 
-func (ls *loginService) Finalize(req *protocol.ParsedCredentialAssertionData) (string, string, error) {
+func (ls *loginService) Finalize(req *protocol.ParsedCredentialAssertionData) (_ string, _ string, _ bool, err error) {
+	ctx, span := ls.startSpan("loginService.Finalize", models.WebauthnOperationAuthentication)
+	defer func() { tracing.End(span, err) }()
+	ls.bindPersisters(ctx)
+
 	// backward compatibility
 	userHandle := ls.convertUserHandle(req.Response.UserHandle)
 	sessionData, dbSessionData, err := ls.getSessionByChallenge(req.Response.CollectedClientData.Challenge, models.WebauthnOperationAuthentication)
 	if err != nil {
-		return "", userHandle, echo.NewHTTPError(http.StatusUnauthorized, "failed to get session data").SetInternal(err)
+		return "", userHandle, false, echo.NewHTTPError(http.StatusUnauthorized, "failed to get session data").SetInternal(err)
 	}
 
 	// when using MFA or session was initialized for a non-discoverable cred
@@ -107,11 +199,16 @@ func (ls *loginService) Finalize(req *protocol.ParsedCredentialAssertionData) (s
 
 	req.Response.UserHandle = []byte(userHandle)
 	webauthnUser, err := ls.getWebauthnUserByUserHandle(userHandle)
+	if errors.Is(err, ErrUserDisabled) {
+		return "", userHandle, false, err
+	}
+
 	if err != nil {
-		return "", userHandle, echo.NewHTTPError(http.StatusUnauthorized, "failed to get user handle").SetInternal(err)
+		return "", userHandle, false, echo.NewHTTPError(http.StatusUnauthorized, "failed to get user handle").SetInternal(err)
 	}
 
 	var credential *webauthn.Credential
+	validationStart := time.Now()
 	if dbSessionData.IsDiscoverable {
 		credential, err = ls.webauthnClient.ValidateDiscoverableLogin(func(rawID, userHandle []byte) (user webauthn.User, err error) {
 			return webauthnUser, nil
@@ -120,33 +217,207 @@ func (ls *loginService) Finalize(req *protocol.ParsedCredentialAssertionData) (s
 		credential, err = ls.webauthnClient.ValidateLogin(webauthnUser, *sessionData, req)
 	}
 
+	ceremony := metrics.CeremonyAuthentication
+	if ls.useMFA {
+		ceremony = metrics.CeremonyMfaAuthentication
+	}
+	metrics.ObserveValidation(ls.tenant.ID, ceremony, validationStart)
+
 	if err != nil {
 		ls.logger.Error(err)
-		return "", userHandle, echo.NewHTTPError(http.StatusUnauthorized, "failed to validate assertion").SetInternal(err)
+		return "", userHandle, false, echo.NewHTTPError(http.StatusUnauthorized, "failed to validate assertion").SetInternal(err)
 	}
 	credentialId := base64.RawURLEncoding.EncodeToString(credential.ID)
 
 	dbCredential := webauthnUser.FindCredentialById(credentialId)
+
+	// the attachment reported by the client is not signed, so the attachment derived from the stored transports is used
+	if dbSessionData.RequiredAttachment != nil {
+		attachment := dbCredential.Transports.Attachment()
+		if attachment == "" {
+			return "", userHandle, false, echo.NewHTTPError(http.StatusUnauthorized, errUnknownAttachment)
+		}
+
+		if string(attachment) != *dbSessionData.RequiredAttachment {
+			return "", userHandle, false, echo.NewHTTPError(http.StatusUnauthorized, "credential does not have the required attachment")
+		}
+	}
+
 	if !ls.useMFA && dbCredential.IsMFA {
-		return "", userHandle, echo.NewHTTPError(http.StatusBadRequest, "MFA credentials are not usable for normal login")
+		return "", userHandle, false, echo.NewHTTPError(http.StatusBadRequest, "MFA credentials are not usable for normal login")
+	}
+
+	authentication := jwt.Authentication{
+		UserVerified: req.Response.AuthenticatorData.Flags.HasUserVerified(),
+		IsMFA:        dbCredential.IsMFA,
+		AuthTime:     time.Now(),
+	}
+
+	if dbSessionData.MfaToken != nil {
+		if !dbCredential.IsMFA {
+			return "", userHandle, false, echo.NewHTTPError(http.StatusBadRequest, "a MFA credential is required to complete the login")
+		}
+
+		var tokenId string
+		authentication.PrimaryFactor, tokenId, err = ls.generator.VerifyMfaRequired(*dbSessionData.MfaToken, webauthnUser.UserId)
+		if err != nil {
+			return "", userHandle, false, echo.NewHTTPError(http.StatusUnauthorized, "mfa token is not valid anymore").SetInternal(err)
+		}
+
+		err = ls.consumePendingMfaLogin(tokenId, webauthnUser.UserId)
+		if err != nil {
+			return "", userHandle, false, err
+		}
+	} else if ls.useMFA && ls.isMfaRequiredForLogin() {
+		return "", userHandle, false, echo.NewHTTPError(http.StatusBadRequest, "a MFA login requires the mfa_token of a passkey login")
 	}
 
 	err = ls.updateCredentialForUser(dbCredential, req.Response.AuthenticatorData.Flags)
 	if err != nil {
-		return "", userHandle, err
+		return "", userHandle, false, err
+	}
+
+	err = ls.userPersister.UpdateLastLogin(dbCredential.WebauthnUserID, *dbCredential.LastUsedAt)
+	if err != nil {
+		ls.logger.Error(err)
+		return "", userHandle, false, err
 	}
 
 	err = ls.sessionDataPersister.Delete(*dbSessionData)
 	if err != nil {
 		ls.logger.Error(err)
-		return "", userHandle, fmt.Errorf("failed to delete assertion session data: %w", err)
+		return "", userHandle, false, fmt.Errorf("failed to delete assertion session data: %w", err)
 	}
 
-	token, err := ls.createUserCredentialToken(webauthnUser.UserId, credentialId)
+	if !ls.useMFA && ls.isMfaRequired(webauthnUser) {
+		tokenId, err := ls.createPendingMfaLogin(webauthnUser.UserId)
+		if err != nil {
+			ls.logger.Error(err)
+			return "", userHandle, false, err
+		}
+
+		token, err := ls.generator.GenerateMfaRequired(webauthnUser.UserId, credentialId, tokenId, authentication)
+		if err != nil {
+			ls.logger.Error(err)
+			return "", userHandle, false, fmt.Errorf("failed to generate mfa token: %w", err)
+		}
+
+		return token, userHandle, true, nil
+	}
+
+	token, err := ls.createUserCredentialToken(webauthnUser.UserId, credentialId, authentication)
+	if err != nil {
+		ls.logger.Error(err)
+		return "", userHandle, false, err
+	}
+
+	return token, userHandle, false, nil
+}
+
+// filterCredentialsByAttachment returns the descriptors of the credentials of the user whose stored transports match
+// the attachment
+func (ls *loginService) filterCredentialsByAttachment(user *intern.WebauthnUser, attachment protocol.AuthenticatorAttachment) []protocol.CredentialDescriptor {
+	var descriptors []protocol.CredentialDescriptor
+	for i := range user.WebauthnCredentials {
+		credential := &user.WebauthnCredentials[i]
+		if !user.IsMfaUser && credential.IsMFA {
+			continue
+		}
+
+		if credential.Transports.Attachment() == attachment {
+			descriptors = append(descriptors, intern.WebauthnCredentialFromModel(credential).Descriptor())
+		}
+	}
+
+	return descriptors
+}
+
+// isMfaRequiredForLogin checks if the tenant requires users with a MFA credential to complete their passkey login
+// with it. A MFA login on its own is not a complete login then.
+func (ls *loginService) isMfaRequiredForLogin() bool {
+	mfaConfig := ls.tenant.Config.MfaConfig
+	return mfaConfig != nil && mfaConfig.RequireForLogin
+}
+
+// isMfaRequired checks if the tenant requires users with a MFA credential to complete their login with it
+func (ls *loginService) isMfaRequired(user *intern.WebauthnUser) bool {
+	if !ls.isMfaRequiredForLogin() {
+		return false
+	}
+
+	for _, credential := range user.WebauthnCredentials {
+		if credential.IsMFA {
+			return true
+		}
+	}
+
+	return false
+}
+
+// createPendingMfaLogin stores the id of a new intermediate token as pending MFA login, so the token can only complete
+// one MFA login. It expires together with the token.
+func (ls *loginService) createPendingMfaLogin(userId string) (string, error) {
+	id, _ := uuid.NewV4()
+	tokenId, _ := uuid.NewV4()
+	now := time.Now()
+
+	err := ls.sessionDataPersister.Create(models.WebauthnSessionData{
+		ID:        id,
+		UserId:    userId,
+		Challenge: tokenId.String(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Operation: models.WebauthnOperationMfaRequired,
+		ExpiresAt: nulls.NewTime(now.Add(jwt.JwtExpirationDuration * time.Second)),
+		TenantID:  ls.tenant.ID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store pending mfa login: %w", err)
+	}
+
+	return tokenId.String(), nil
+}
+
+// getPendingMfaLogin returns the pending MFA login of an intermediate token or nil if the token was already used
+func (ls *loginService) getPendingMfaLogin(tokenId string, userId string) (*models.WebauthnSessionData, error) {
+	pendingMfaLogin, err := ls.sessionDataPersister.GetByChallenge(tokenId, ls.tenant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingMfaLogin == nil || pendingMfaLogin.Operation != models.WebauthnOperationMfaRequired || pendingMfaLogin.UserId != userId {
+		return nil, nil
+	}
+
+	if pendingMfaLogin.ExpiresAt.Valid && pendingMfaLogin.ExpiresAt.Time.Before(time.Now()) {
+		return nil, nil
+	}
+
+	return pendingMfaLogin, nil
+}
+
+// consumePendingMfaLogin deletes the pending MFA login of an intermediate token, so the token cannot be used again
+func (ls *loginService) consumePendingMfaLogin(tokenId string, userId string) error {
+	pendingMfaLogin, err := ls.getPendingMfaLogin(tokenId, userId)
+	if err != nil {
+		ls.logger.Error(err)
+		return err
+	}
+
+	if pendingMfaLogin == nil {
+		return echo.NewHTTPError(http.StatusUnauthorized, "mfa token was already used")
+	}
+
+	deleted, err := ls.sessionDataPersister.DeleteByChallenge(tokenId, ls.tenant.ID, models.WebauthnOperationMfaRequired)
 	if err != nil {
 		ls.logger.Error(err)
-		return "", userHandle, err
+		return err
+	}
+
+	// a concurrent MFA login used the token first
+	if !deleted {
+		return echo.NewHTTPError(http.StatusUnauthorized, "mfa token was already used")
 	}
 
-	return token, userHandle, nil
+	return nil
 }
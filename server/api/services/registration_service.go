@@ -6,8 +6,12 @@ import (
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/intern"
+	"github.com/teamhanko/passkey-server/crypto/jwt"
 	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/tracing"
+	"github.com/teamhanko/passkey-server/utils"
 	"net/http"
 	"strings"
 	"time"
@@ -18,6 +22,9 @@ type RegistrationService interface {
 	Finalize(req *protocol.ParsedCredentialCreationData) (string, *string, error)
 }
 
+// maxStoredUserAgentLength matches the size of the column the user agent is stored in
+const maxStoredUserAgentLength = 512
+
 type registrationService struct {
 	WebauthnService
 	mapper.AuthenticatorMetadata
@@ -28,6 +35,7 @@ func NewRegistrationService(params WebauthnServiceCreateParams) RegistrationServ
 	return &registrationService{
 		WebauthnService{
 			BaseService: &BaseService{
+				ctx:                 params.Ctx.Request().Context(),
 				logger:              params.Ctx.Logger(),
 				tenant:              params.Tenant,
 				credentialPersister: params.CredentialPersister,
@@ -35,6 +43,9 @@ func NewRegistrationService(params WebauthnServiceCreateParams) RegistrationServ
 
 			webauthnClient: params.WebauthnClient,
 			generator:      params.Generator,
+			relyingParty:   params.RelyingParty,
+			userAgent:      params.Ctx.Request().UserAgent(),
+			remoteIp:       params.Ctx.RealIP(),
 
 			userPersister:        params.UserPersister,
 			sessionDataPersister: params.SessionPersister,
@@ -45,7 +56,11 @@ func NewRegistrationService(params WebauthnServiceCreateParams) RegistrationServ
 	}
 }
 
-func (rs *registrationService) Initialize(user *models.WebauthnUser) (*protocol.CredentialCreation, string, error) {
+func (rs *registrationService) Initialize(user *models.WebauthnUser) (_ *protocol.CredentialCreation, _ string, err error) {
+	ctx, span := rs.startSpan("registrationService.Initialize", models.WebauthnOperationRegistration)
+	defer func() { tracing.End(span, err) }()
+	rs.bindPersisters(ctx)
+
 	internalUser, err := rs.createOrUpdateUser(*user)
 	if err != nil {
 		return nil, user.UserID, err
@@ -74,6 +89,10 @@ func (rs *registrationService) createOrUpdateUser(user models.WebauthnUser) (*in
 		return nil, err
 	}
 
+	if dbUser != nil && dbUser.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+
 	if dbUser == nil {
 		rs.logger.Debugf("Creating user: %v", user)
 		err = rs.userPersister.Create(&user)
@@ -87,7 +106,7 @@ func (rs *registrationService) createOrUpdateUser(user models.WebauthnUser) (*in
 		return nil, err
 	}
 
-	return intern.NewWebauthnUser(user, rs.useMFA), err
+	return rs.newWebauthnUser(user), err
 }
 
 func (rs *registrationService) getDbUser(userId string) (*models.WebauthnUser, error) {
@@ -120,7 +139,11 @@ func (rs *registrationService) updateUser(dbUser *models.WebauthnUser, newUser *
 	return nil
 }
 
-func (rs *registrationService) Finalize(req *protocol.ParsedCredentialCreationData) (string, *string, error) {
+func (rs *registrationService) Finalize(req *protocol.ParsedCredentialCreationData) (_ string, _ *string, err error) {
+	ctx, span := rs.startSpan("registrationService.Finalize", models.WebauthnOperationRegistration)
+	defer func() { tracing.End(span, err) }()
+	rs.bindPersisters(ctx)
+
 	dbUser, dbSessionData, err := rs.geDbtUserAndSessionFromRequest(req)
 	if err != nil {
 		if dbSessionData != nil {
@@ -140,7 +163,11 @@ func (rs *registrationService) Finalize(req *protocol.ParsedCredentialCreationDa
 		rs.logger.Errorf("failed to delete attestation session data: %w", err)
 	}
 
-	token, err := rs.generator.Generate(dbUser.UserID, credential.ID)
+	token, err := rs.generator.Generate(dbUser.UserID, credential.ID, jwt.Authentication{
+		UserVerified: req.Response.AttestationObject.AuthData.Flags.UserVerified(),
+		IsMFA:        credential.IsMFA,
+		AuthTime:     time.Now(),
+	})
 	if err != nil {
 		rs.logger.Error(err)
 		return "", &dbUser.UserID, err
@@ -170,7 +197,15 @@ func (rs *registrationService) geDbtUserAndSessionFromRequest(req *protocol.Pars
 }
 
 func (rs *registrationService) createCredential(dbUser *models.WebauthnUser, session *models.WebauthnSessionData, req *protocol.ParsedCredentialCreationData) (*models.WebauthnCredential, error) {
-	credential, err := rs.webauthnClient.CreateCredential(intern.NewWebauthnUser(*dbUser, rs.useMFA), *intern.WebauthnSessionDataFromModel(session), req)
+	validationStart := time.Now()
+	credential, err := rs.webauthnClient.CreateCredential(rs.newWebauthnUser(*dbUser), *intern.WebauthnSessionDataFromModel(session), req)
+
+	ceremony := metrics.CeremonyRegistration
+	if rs.useMFA {
+		ceremony = metrics.CeremonyMfaRegistration
+	}
+	metrics.ObserveValidation(rs.tenant.ID, ceremony, validationStart)
+
 	if err != nil {
 		rs.logger.Error(err)
 
@@ -201,7 +236,9 @@ func (rs *registrationService) createCredential(dbUser *models.WebauthnUser, ses
 		flags.HasBackupState(),
 		rs.AuthenticatorMetadata,
 		rs.useMFA,
+		rs.rpId(),
 	)
+	rs.addCreationMetadata(dbCredential)
 
 	err = rs.credentialPersister.Create(dbCredential)
 	if err != nil {
@@ -211,3 +248,21 @@ func (rs *registrationService) createCredential(dbUser *models.WebauthnUser, ses
 
 	return dbCredential, nil
 }
+
+// addCreationMetadata records which device created the credential, so users can tell their credentials apart
+func (rs *registrationService) addCreationMetadata(credential *models.WebauthnCredential) {
+	if rs.userAgent != "" {
+		userAgent := utils.TruncateRunes(rs.userAgent, maxStoredUserAgentLength)
+		credential.CreatedUserAgent = &userAgent
+
+		device := utils.ParseUserAgent(rs.userAgent).String()
+		if device != "" {
+			credential.CreatedDevice = &device
+		}
+	}
+
+	if rs.tenant.Config.WebauthnConfig.StoreCreationIp && rs.remoteIp != "" {
+		remoteIp := rs.remoteIp
+		credential.CreatedIp = &remoteIp
+	}
+}
@@ -1,16 +1,22 @@
 package services
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/labstack/echo/v4"
 	"github.com/teamhanko/passkey-server/api/dto/intern"
+	"github.com/teamhanko/passkey-server/crypto/jwt"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/tracing"
 	"net/http"
+	"time"
 )
 
 type TransactionService interface {
@@ -34,6 +40,7 @@ func NewTransactionService(params TransactionServiceCreateParams) TransactionSer
 	return &transactionService{
 		WebauthnService: &WebauthnService{
 			BaseService: &BaseService{
+				ctx:                 params.Ctx.Request().Context(),
 				logger:              params.Ctx.Logger(),
 				tenant:              params.Tenant,
 				credentialPersister: params.CredentialPersister,
@@ -41,6 +48,8 @@ func NewTransactionService(params TransactionServiceCreateParams) TransactionSer
 
 			webauthnClient: params.WebauthnClient,
 			generator:      params.Generator,
+			relyingParty:   params.RelyingParty,
+			userAgent:      params.Ctx.Request().UserAgent(),
 
 			userPersister:        params.UserPersister,
 			sessionDataPersister: params.SessionPersister,
@@ -51,7 +60,11 @@ func NewTransactionService(params TransactionServiceCreateParams) TransactionSer
 	}
 }
 
-func (ts *transactionService) Initialize(userId string, transaction *models.Transaction) (*protocol.CredentialAssertion, error) {
+func (ts *transactionService) Initialize(userId string, transaction *models.Transaction) (_ *protocol.CredentialAssertion, err error) {
+	ctx, span := ts.startSpan("transactionService.Initialize", models.WebauthnOperationTransaction)
+	defer func() { tracing.End(span, err) }()
+	ts.bindPersisters(ctx)
+
 	webauthnUser, err := ts.userPersister.GetByUserId(userId, ts.tenant.ID)
 	if err != nil {
 		ts.logger.Error(err)
@@ -62,6 +75,10 @@ func (ts *transactionService) Initialize(userId string, transaction *models.Tran
 		return nil, echo.NewHTTPError(http.StatusNotFound, "unable to find user")
 	}
 
+	if webauthnUser.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+
 	foundTransaction, err := ts.transactionPersister.GetByIdentifier(transaction.Identifier, ts.tenant.ID)
 	if err != nil {
 		ts.logger.Error(err)
@@ -73,8 +90,10 @@ func (ts *transactionService) Initialize(userId string, transaction *models.Tran
 		return nil, echo.NewHTTPError(http.StatusConflict, "transaction already exists")
 	}
 
+	user := ts.newWebauthnUser(*webauthnUser)
+
 	// check for better error handling as BeginLogin can throw a BadRequestError AND normal errors (but same type)
-	if len(webauthnUser.WebauthnCredentials) == 0 {
+	if len(user.WebauthnCredentials) == 0 {
 		return nil, echo.NewHTTPError(
 			http.StatusBadRequest,
 			fmt.Errorf("user has no suitable credentials for this operation"),
@@ -82,7 +101,7 @@ func (ts *transactionService) Initialize(userId string, transaction *models.Tran
 	}
 
 	credentialAssertion, sessionData, err := ts.webauthnClient.BeginLogin(
-		intern.NewWebauthnUser(*webauthnUser, ts.useMFA),
+		user,
 		ts.withTransaction(transaction.Identifier, transaction.Data),
 	)
 	if err != nil {
@@ -110,15 +129,18 @@ func (ts *transactionService) Initialize(userId string, transaction *models.Tran
 		return nil, err
 	}
 
-	// Remove all transports, because of a bug in android and windows where the internal authenticator gets triggered,
-	// when the transports array contains the type 'internal' although the credential is not available on the device.
-	for i := range credentialAssertion.Response.AllowedCredentials {
-		credentialAssertion.Response.AllowedCredentials[i].Transport = nil
-	}
+	ts.stripTransports(credentialAssertion)
 
 	return credentialAssertion, nil
 }
 
+func (ts *transactionService) bindPersisters(ctx context.Context) {
+	ts.WebauthnService.bindPersisters(ctx)
+	if ts.transactionPersister != nil {
+		ts.transactionPersister = ts.transactionPersister.WithContext(ctx)
+	}
+}
+
 func (ts *transactionService) withTransaction(transactionId string, transactionDataJson string) webauthn.LoginOption {
 	return func(options *protocol.PublicKeyCredentialRequestOptions) {
 		transaction := []byte(transactionId)
@@ -131,7 +153,11 @@ func (ts *transactionService) withTransaction(transactionId string, transactionD
 	}
 }
 
-func (ts *transactionService) Finalize(req *protocol.ParsedCredentialAssertionData) (string, string, *models.Transaction, error) {
+func (ts *transactionService) Finalize(req *protocol.ParsedCredentialAssertionData) (_ string, _ string, _ *models.Transaction, err error) {
+	ctx, span := ts.startSpan("transactionService.Finalize", models.WebauthnOperationTransaction)
+	defer func() { tracing.End(span, err) }()
+	ts.bindPersisters(ctx)
+
 	// backward compatibility
 	userHandle := ts.convertUserHandle(req.Response.UserHandle)
 	req.Response.UserHandle = []byte(userHandle)
@@ -149,11 +175,17 @@ func (ts *transactionService) Finalize(req *protocol.ParsedCredentialAssertionDa
 	}
 
 	webauthnUser, err := ts.getWebauthnUserByUserHandle(userHandle)
+	if errors.Is(err, ErrUserDisabled) {
+		return "", userHandle, transaction, err
+	}
+
 	if err != nil {
 		return "", userHandle, transaction, echo.NewHTTPError(http.StatusUnauthorized, "failed to get user handle").SetInternal(err)
 	}
 
+	validationStart := time.Now()
 	credential, err := ts.webauthnClient.ValidateLogin(webauthnUser, *sessionData, req)
+	metrics.ObserveValidation(ts.tenant.ID, metrics.CeremonyTransaction, validationStart)
 	if err != nil {
 		ts.logger.Error(err)
 		return "", userHandle, transaction, echo.NewHTTPError(http.StatusUnauthorized, "failed to validate assertion").SetInternal(err)
@@ -176,7 +208,11 @@ func (ts *transactionService) Finalize(req *protocol.ParsedCredentialAssertionDa
 		return "", userHandle, transaction, fmt.Errorf("failed to delete assertion session data: %w", err)
 	}
 
-	token, err := ts.generator.GenerateForTransaction(webauthnUser.UserId, base64.RawURLEncoding.EncodeToString(credential.ID), transaction.Identifier)
+	token, err := ts.generator.GenerateForTransaction(webauthnUser.UserId, base64.RawURLEncoding.EncodeToString(credential.ID), transaction.Identifier, jwt.Authentication{
+		UserVerified: req.Response.AuthenticatorData.Flags.HasUserVerified(),
+		IsMFA:        dbCredential.IsMFA,
+		AuthTime:     time.Now(),
+	})
 	if err != nil {
 		ts.logger.Error(err)
 		return "", userHandle, transaction, fmt.Errorf("failed to generate jwt: %w", err)
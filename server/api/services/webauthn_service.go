@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"fmt"
 	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/go-webauthn/webauthn/webauthn"
@@ -20,6 +21,9 @@ type WebauthnService struct {
 
 	webauthnClient webauthn.WebAuthn
 	generator      jwt.Generator
+	relyingParty   *models.RelyingParty
+	userAgent      string
+	remoteIp       string
 
 	userPersister        persisters.WebauthnUserPersister
 	sessionDataPersister persisters.WebauthnSessionDataPersister
@@ -27,13 +31,25 @@ type WebauthnService struct {
 	useMFA bool
 }
 
+// ErrUserDisabled is returned when a user which was disabled through the admin API tries to register or authenticate
+var ErrUserDisabled = echo.NewHTTPError(http.StatusForbidden, "user is disabled")
+
+// StepUpOptions restrict which authenticators are accepted for a login, e.g. before a sensitive action
+type StepUpOptions struct {
+	UserVerification *protocol.UserVerificationRequirement
+	CredentialId     *string
+	Attachment       *protocol.AuthenticatorAttachment
+}
+
 type WebauthnServiceCreateParams struct {
 	Ctx                   echo.Context
 	Tenant                models.Tenant
 	WebauthnClient        webauthn.WebAuthn
 	Generator             jwt.Generator
+	RelyingParty          *models.RelyingParty
 	AuthenticatorMetadata mapper.AuthenticatorMetadata
 	UserId                *string
+	MfaToken              *string
 	UseMFA                bool
 
 	UserPersister       persisters.WebauthnUserPersister
@@ -41,6 +57,22 @@ type WebauthnServiceCreateParams struct {
 	CredentialPersister persisters.WebauthnCredentialPersister
 }
 
+// bindPersisters binds the persisters to the context of a span, so their queries are traced as its children. Not every
+// ceremony step needs all persisters, the ones which were not passed are skipped.
+func (ws *WebauthnService) bindPersisters(ctx context.Context) {
+	if ws.credentialPersister != nil {
+		ws.credentialPersister = ws.credentialPersister.WithContext(ctx)
+	}
+
+	if ws.userPersister != nil {
+		ws.userPersister = ws.userPersister.WithContext(ctx)
+	}
+
+	if ws.sessionDataPersister != nil {
+		ws.sessionDataPersister = ws.sessionDataPersister.WithContext(ctx)
+	}
+}
+
 func (ws *WebauthnService) getSessionByChallenge(challenge string, operation models.Operation) (*webauthn.SessionData, *models.WebauthnSessionData, error) {
 	sessionData, err := ws.sessionDataPersister.GetByChallenge(challenge, ws.tenant.ID)
 	if err != nil {
@@ -77,11 +109,40 @@ func (ws *WebauthnService) getWebauthnUserByUserHandle(userHandle string) (*inte
 		return nil, fmt.Errorf("user not found")
 	}
 
-	return intern.NewWebauthnUser(*user, ws.useMFA), nil
+	if user.IsDisabled() {
+		return nil, ErrUserDisabled
+	}
+
+	return ws.newWebauthnUser(*user), nil
+}
+
+func (ws *WebauthnService) newWebauthnUser(user models.WebauthnUser) *intern.WebauthnUser {
+	return intern.NewWebauthnUser(user, ws.useMFA, ws.rpId())
+}
+
+// stripTransports removes the transports of the allowed credentials when the tenant policy requires it for the
+// current user agent. Some platforms (e.g. android and windows) trigger the internal authenticator when the transports
+// array contains the type 'internal' although the credential is not available on the device.
+func (ws *WebauthnService) stripTransports(credentialAssertion *protocol.CredentialAssertion) {
+	if !ws.tenant.Config.WebauthnConfig.ShouldStripTransports(ws.userAgent) {
+		return
+	}
+
+	for i := range credentialAssertion.Response.AllowedCredentials {
+		credentialAssertion.Response.AllowedCredentials[i].Transport = nil
+	}
+}
+
+func (ws *WebauthnService) rpId() string {
+	if ws.relyingParty == nil {
+		return ""
+	}
+
+	return ws.relyingParty.RPId
 }
 
-func (ws *WebauthnService) createUserCredentialToken(userId string, credentialId string) (string, error) {
-	token, err := ws.generator.Generate(userId, credentialId)
+func (ws *WebauthnService) createUserCredentialToken(userId string, credentialId string, authentication jwt.Authentication) (string, error) {
+	token, err := ws.generator.Generate(userId, credentialId, authentication)
 	if err != nil {
 		ws.logger.Error(err)
 		return "", fmt.Errorf("failed to generate jwt: %w", err)
@@ -0,0 +1,19 @@
+package validators
+
+import (
+	"github.com/go-playground/validator/v10"
+	"regexp"
+)
+
+var (
+	sha256FingerprintRegex = regexp.MustCompile(`^[0-9A-Fa-f]{2}(:[0-9A-Fa-f]{2}){31}$`)
+	appleAppIdRegex        = regexp.MustCompile(`^[A-Z0-9]{10}\.[A-Za-z0-9.\-]+$`)
+)
+
+func validateSha256Fingerprint(fl validator.FieldLevel) bool {
+	return sha256FingerprintRegex.MatchString(fl.Field().String())
+}
+
+func validateAppleAppId(fl validator.FieldLevel) bool {
+	return appleAppIdRegex.MatchString(fl.Field().String())
+}
@@ -0,0 +1,11 @@
+package validators
+
+import (
+	"github.com/go-playground/validator/v10"
+	"regexp"
+)
+
+func validateRegexp(fl validator.FieldLevel) bool {
+	_, err := regexp.Compile(fl.Field().String())
+	return err == nil
+}
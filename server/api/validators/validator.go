@@ -30,6 +30,10 @@ func NewCustomValidator() *CustomValidator {
 		return name
 	})
 
+	_ = v.RegisterValidation("sha256_fingerprint", validateSha256Fingerprint)
+	_ = v.RegisterValidation("apple_app_id", validateAppleAppId)
+	_ = v.RegisterValidation("regexp", validateRegexp)
+
 	return &CustomValidator{Validator: v}
 }
 
@@ -54,6 +58,12 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 					vErrs[i] = fmt.Sprintf("%s entries are not unique", err.Field())
 				case "oneof":
 					vErrs[i] = fmt.Sprintf("%s must be one of '%s'", err.Field(), err.Param())
+				case "sha256_fingerprint":
+					vErrs[i] = fmt.Sprintf("%s must be a colon separated SHA-256 fingerprint", err.Field())
+				case "apple_app_id":
+					vErrs[i] = fmt.Sprintf("%s must be in the format '<team id>.<bundle id>'", err.Field())
+				case "regexp":
+					vErrs[i] = fmt.Sprintf("%s must be a valid regular expression", err.Field())
 				case "min":
 					vErrs[i] = cv.minMessage(err.Field(), err.Param())
 				case "max":
@@ -0,0 +1,177 @@
+package archive
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/teamhanko/passkey-server/crypto/aes_gcm"
+	"golang.org/x/crypto/argon2"
+	"time"
+)
+
+const (
+	Format  = "passkey-server-tenant-archive"
+	Version = 1
+
+	kdfAlgorithm  = "argon2id"
+	kdfTime       = 3
+	kdfMemory     = 64 * 1024
+	kdfThreads    = 4
+	maxKdfTime    = 10
+	maxKdfMemory  = 256 * 1024
+	maxKdfThreads = 16
+	saltLength    = 16
+
+	MinPassphraseLength = 12
+)
+
+var (
+	ErrInvalidSignature = errors.New("archive signature is invalid: the passphrase is wrong or the archive was modified")
+	ErrInvalidArchive   = errors.New("invalid archive")
+	ErrConflict         = errors.New("archive conflicts with existing data")
+)
+
+type KdfParams struct {
+	Algorithm string `json:"algorithm"`
+	Salt      string `json:"salt"`
+	Time      uint32 `json:"time"`
+	Memory    uint32 `json:"memory"`
+	Threads   uint8  `json:"threads"`
+}
+
+// Archive is the versioned container of a tenant export. The payload is signed with an HMAC and all secrets inside
+// the payload are encrypted, both with keys derived from the export passphrase.
+type Archive struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Kdf       KdfParams `json:"kdf"`
+	Payload   string    `json:"payload"`
+	Signature string    `json:"signature"`
+}
+
+// keys holds the keys derived from the passphrase of an archive
+type keys struct {
+	encrypter *aes_gcm.AESGCM
+	macKey    []byte
+}
+
+func newKdfParams() (*KdfParams, error) {
+	salt := make([]byte, saltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create salt: %w", err)
+	}
+
+	return &KdfParams{
+		Algorithm: kdfAlgorithm,
+		Salt:      base64.RawURLEncoding.EncodeToString(salt),
+		Time:      kdfTime,
+		Memory:    kdfMemory,
+		Threads:   kdfThreads,
+	}, nil
+}
+
+func deriveKeys(passphrase string, params KdfParams) (*keys, error) {
+	if len(passphrase) < MinPassphraseLength {
+		return nil, fmt.Errorf("passphrase must be at least %d characters long", MinPassphraseLength)
+	}
+
+	if params.Algorithm != kdfAlgorithm {
+		return nil, fmt.Errorf("unsupported key derivation '%s'", params.Algorithm)
+	}
+
+	// the parameters are part of the archive, so they are bounded to not exhaust the server on import
+	if params.Time == 0 || params.Time > maxKdfTime || params.Memory == 0 || params.Memory > maxKdfMemory || params.Threads == 0 || params.Threads > maxKdfThreads {
+		return nil, errors.New("invalid key derivation parameters")
+	}
+
+	salt, err := base64.RawURLEncoding.DecodeString(params.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid salt: %w", err)
+	}
+
+	derivedKey := argon2.IDKey([]byte(passphrase), salt, params.Time, params.Memory, params.Threads, 64)
+
+	encrypter, err := aes_gcm.NewAESGCM([]string{hex.EncodeToString(derivedKey[:32])})
+	if err != nil {
+		return nil, err
+	}
+
+	return &keys{
+		encrypter: encrypter,
+		macKey:    derivedKey[32:],
+	}, nil
+}
+
+func (a *Archive) sign(macKey []byte) string {
+	mac := hmac.New(sha256.New, macKey)
+	_, _ = fmt.Fprintf(mac, "%s\n%d\n%s\n", a.Format, a.Version, a.CreatedAt.UTC().Format(time.RFC3339Nano))
+	_, _ = fmt.Fprintf(mac, "%s\n%s\n%d\n%d\n%d\n", a.Kdf.Algorithm, a.Kdf.Salt, a.Kdf.Time, a.Kdf.Memory, a.Kdf.Threads)
+	mac.Write([]byte(a.Payload))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// seal serializes and signs the tenant export
+func seal(export *TenantExport, keys *keys, kdf KdfParams) (*Archive, error) {
+	payload, err := json.Marshal(export)
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize tenant export: %w", err)
+	}
+
+	archive := &Archive{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+		Kdf:       kdf,
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+	}
+	archive.Signature = archive.sign(keys.macKey)
+
+	return archive, nil
+}
+
+// open checks format, version and signature of the archive before the payload is decoded
+func (a *Archive) open(passphrase string) (*TenantExport, *keys, error) {
+	if a.Format != Format {
+		return nil, nil, fmt.Errorf("unknown archive format '%s'", a.Format)
+	}
+
+	if a.Version != Version {
+		return nil, nil, fmt.Errorf("unsupported archive version %d", a.Version)
+	}
+
+	keys, err := deriveKeys(passphrase, a.Kdf)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(a.Signature)
+	if err != nil {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	expectedSignature, _ := base64.RawURLEncoding.DecodeString(a.sign(keys.macKey))
+	if !hmac.Equal(signature, expectedSignature) {
+		return nil, nil, ErrInvalidSignature
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(a.Payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode archive payload: %w", err)
+	}
+
+	var export TenantExport
+	err = json.Unmarshal(payload, &export)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to decode archive payload: %w", err)
+	}
+
+	return &export, keys, nil
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"encoding/json"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+const testPassphrase = "correct horse battery staple"
+
+func createTestArchive(t *testing.T) *Archive {
+	kdf, err := newKdfParams()
+	assert.NoError(t, err)
+
+	// keep the tests fast
+	kdf.Memory = 1024
+	kdf.Time = 1
+
+	keys, err := deriveKeys(testPassphrase, *kdf)
+	assert.NoError(t, err)
+
+	encryptedKey, err := keys.encrypter.Encrypt([]byte("secret-key"))
+	assert.NoError(t, err)
+
+	archive, err := seal(&TenantExport{
+		TenantId:    uuid.Must(uuid.NewV4()),
+		DisplayName: "Test",
+		Config:      json.RawMessage(`{}`),
+		Secrets:     []SecretExport{{Name: "jwk", EncryptedKey: encryptedKey}},
+	}, keys, *kdf)
+	assert.NoError(t, err)
+
+	return archive
+}
+
+func TestArchive_Roundtrip(t *testing.T) {
+	archive := createTestArchive(t)
+
+	export, keys, err := archive.open(testPassphrase)
+	assert.NoError(t, err)
+	assert.Equal(t, "Test", export.DisplayName)
+
+	key, err := keys.encrypter.Decrypt(export.Secrets[0].EncryptedKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-key", string(key))
+}
+
+func TestArchive_WrongPassphrase(t *testing.T) {
+	archive := createTestArchive(t)
+
+	_, _, err := archive.open("wrong passphrase")
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestArchive_Modified(t *testing.T) {
+	archive := createTestArchive(t)
+	archive.CreatedAt = archive.CreatedAt.Add(time.Hour)
+
+	_, _, err := archive.open(testPassphrase)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+}
+
+func TestArchive_ShortPassphrase(t *testing.T) {
+	archive := createTestArchive(t)
+
+	_, _, err := archive.open("short")
+	assert.Error(t, err)
+}
+
+func TestArchive_UnboundedKdfParams(t *testing.T) {
+	archive := createTestArchive(t)
+	archive.Kdf.Threads = 255
+
+	_, _, err := archive.open(testPassphrase)
+	assert.Error(t, err)
+
+	archive = createTestArchive(t)
+	archive.Kdf.Memory = 1024 * 1024
+
+	_, _, err = archive.open(testPassphrase)
+	assert.Error(t, err)
+}
@@ -0,0 +1,265 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/api/dto/admin/response"
+	"github.com/teamhanko/passkey-server/crypto/aes_gcm"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"time"
+)
+
+// TenantExport is the payload of an archive
+type TenantExport struct {
+	TenantId     uuid.UUID           `json:"tenant_id"`
+	DisplayName  string              `json:"display_name"`
+	Config       json.RawMessage     `json:"config"`
+	Secrets      []SecretExport      `json:"secrets"`
+	Jwks         []JwkExport         `json:"jwks,omitempty"`
+	Users        []UserExport        `json:"users"`
+	Transactions []TransactionExport `json:"transactions"`
+	AuditLogs    models.AuditLogs    `json:"audit_logs,omitempty"`
+}
+
+type SecretExport struct {
+	Name         string    `json:"name"`
+	IsApiKey     bool      `json:"is_api_key"`
+	EncryptedKey string    `json:"encrypted_key"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type JwkExport struct {
+	EncryptedKeyData string    `json:"encrypted_key_data"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+type UserExport struct {
+	ID          uuid.UUID          `json:"id"`
+	UserID      string             `json:"user_id"`
+	Name        string             `json:"name"`
+	Icon        string             `json:"icon"`
+	DisplayName string             `json:"display_name"`
+	Credentials []CredentialExport `json:"credentials"`
+	CreatedAt   time.Time          `json:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	LastLoginAt *time.Time         `json:"last_login_at,omitempty"`
+	DisabledAt  *time.Time         `json:"disabled_at,omitempty"`
+}
+
+type CredentialExport struct {
+	ID               string     `json:"id"`
+	Name             *string    `json:"name,omitempty"`
+	PublicKey        string     `json:"public_key"`
+	AttestationType  string     `json:"attestation_type"`
+	AAGUID           uuid.UUID  `json:"aaguid"`
+	SignCount        int        `json:"sign_count"`
+	Transports       []string   `json:"transports"`
+	BackupEligible   bool       `json:"backup_eligible"`
+	BackupState      bool       `json:"backup_state"`
+	IsMFA            bool       `json:"is_mfa"`
+	RPId             *string    `json:"rp_id,omitempty"`
+	Status           string     `json:"status,omitempty"`
+	StatusReason     *string    `json:"status_reason,omitempty"`
+	DisabledAt       *time.Time `json:"disabled_at,omitempty"`
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`
+	CreatedUserAgent *string    `json:"created_user_agent,omitempty"`
+	CreatedDevice    *string    `json:"created_device,omitempty"`
+	CreatedIp        *string    `json:"created_ip,omitempty"`
+	LastUsedAt       *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type TransactionExport struct {
+	ID             uuid.UUID `json:"id"`
+	Identifier     string    `json:"identifier"`
+	Data           string    `json:"data"`
+	Challenge      string    `json:"challenge"`
+	WebauthnUserID uuid.UUID `json:"webauthn_user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type ExportOptions struct {
+	IncludeJwks      bool
+	IncludeAuditLogs bool
+}
+
+// Export reads the tenant with all its users, credentials and transactions and seals it into an archive
+func Export(persister persistence.Persister, tenantId uuid.UUID, passphrase string, options ExportOptions) (*Archive, error) {
+	tenant, err := persister.GetTenantPersister(nil).Get(tenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if tenant == nil {
+		return nil, fmt.Errorf("tenant '%s' not found", tenantId)
+	}
+
+	kdf, err := newKdfParams()
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := deriveKeys(passphrase, *kdf)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := json.Marshal(response.ToGetConfigResponse(&tenant.Config))
+	if err != nil {
+		return nil, fmt.Errorf("unable to serialize config: %w", err)
+	}
+
+	export := &TenantExport{
+		TenantId:     tenant.ID,
+		DisplayName:  tenant.DisplayName,
+		Config:       config,
+		Secrets:      make([]SecretExport, 0),
+		Users:        make([]UserExport, 0),
+		Transactions: make([]TransactionExport, 0),
+	}
+
+	var jwkKeys []string
+	for _, secret := range tenant.Config.Secrets {
+		encryptedKey, err := keys.encrypter.Encrypt([]byte(secret.Key))
+		if err != nil {
+			return nil, fmt.Errorf("unable to encrypt secret: %w", err)
+		}
+
+		export.Secrets = append(export.Secrets, SecretExport{
+			Name:         secret.Name,
+			IsApiKey:     secret.IsAPISecret,
+			EncryptedKey: encryptedKey,
+			CreatedAt:    secret.CreatedAt,
+		})
+
+		if !secret.IsAPISecret {
+			jwkKeys = append(jwkKeys, secret.Key)
+		}
+	}
+
+	if options.IncludeJwks {
+		export.Jwks, err = exportJwks(persister, tenant.ID, jwkKeys, keys)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := persister.GetWebauthnUserPersister(nil).GetAllForTenant(tenant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range users {
+		export.Users = append(export.Users, toUserExport(user))
+	}
+
+	transactions, err := persister.GetTransactionPersister(nil).GetAllForTenant(tenant.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, transaction := range transactions {
+		export.Transactions = append(export.Transactions, TransactionExport{
+			ID:             transaction.ID,
+			Identifier:     transaction.Identifier,
+			Data:           transaction.Data,
+			Challenge:      transaction.Challenge,
+			WebauthnUserID: transaction.WebauthnUserID,
+			CreatedAt:      transaction.CreatedAt,
+			UpdatedAt:      transaction.UpdatedAt,
+		})
+	}
+
+	if options.IncludeAuditLogs {
+		export.AuditLogs, err = persister.GetAuditLogPersister(nil).GetAllForTenant(tenant.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return seal(export, keys, *kdf)
+}
+
+// exportJwks re-encrypts the JWKs of the tenant with the key of the archive, so they can be decrypted without the
+// tenant secrets
+func exportJwks(persister persistence.Persister, tenantId uuid.UUID, jwkKeys []string, keys *keys) ([]JwkExport, error) {
+	jwks, err := persister.GetJwkPersister(nil).GetAllForTenant(tenantId)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(jwks) == 0 {
+		return nil, nil
+	}
+
+	decrypter, err := aes_gcm.NewAESGCM(jwkKeys)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt jwks: %w", err)
+	}
+
+	exportedJwks := make([]JwkExport, 0, len(jwks))
+	for _, jwk := range jwks {
+		keyData, err := decrypter.Decrypt(jwk.KeyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt jwk: %w", err)
+		}
+
+		encryptedKeyData, err := keys.encrypter.Encrypt(keyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to encrypt jwk: %w", err)
+		}
+
+		exportedJwks = append(exportedJwks, JwkExport{
+			EncryptedKeyData: encryptedKeyData,
+			CreatedAt:        jwk.CreatedAt,
+		})
+	}
+
+	return exportedJwks, nil
+}
+
+func toUserExport(user models.WebauthnUser) UserExport {
+	credentials := make([]CredentialExport, 0, len(user.WebauthnCredentials))
+	for _, credential := range user.WebauthnCredentials {
+		credentials = append(credentials, CredentialExport{
+			ID:               credential.ID,
+			Name:             credential.Name,
+			PublicKey:        credential.PublicKey,
+			AttestationType:  credential.AttestationType,
+			AAGUID:           credential.AAGUID,
+			SignCount:        credential.SignCount,
+			Transports:       credential.Transports.GetNames(),
+			BackupEligible:   credential.BackupEligible,
+			BackupState:      credential.BackupState,
+			IsMFA:            credential.IsMFA,
+			RPId:             credential.RPId,
+			Status:           string(credential.Status),
+			StatusReason:     credential.StatusReason,
+			DisabledAt:       credential.DisabledAt,
+			RevokedAt:        credential.RevokedAt,
+			CreatedUserAgent: credential.CreatedUserAgent,
+			CreatedDevice:    credential.CreatedDevice,
+			CreatedIp:        credential.CreatedIp,
+			LastUsedAt:       credential.LastUsedAt,
+			CreatedAt:        credential.CreatedAt,
+			UpdatedAt:        credential.UpdatedAt,
+		})
+	}
+
+	return UserExport{
+		ID:          user.ID,
+		UserID:      user.UserID,
+		Name:        user.Name,
+		Icon:        user.Icon,
+		DisplayName: user.DisplayName,
+		Credentials: credentials,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+		LastLoginAt: user.LastLoginAt,
+		DisabledAt:  user.DisabledAt,
+	}
+}
@@ -0,0 +1,381 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/services/admin"
+	"github.com/teamhanko/passkey-server/api/validators"
+	"github.com/teamhanko/passkey-server/crypto/aes_gcm"
+	hankoJwk "github.com/teamhanko/passkey-server/crypto/jwk"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"strings"
+	"time"
+)
+
+type ImportOptions struct {
+	// TenantId remaps the tenant to a new ID. The IDs of users, transactions and audit logs are regenerated as well.
+	// Credential IDs are kept, as they are chosen by the authenticator and unique across tenants, so a remapped
+	// tenant cannot be imported next to the original one. The ID of the exported tenant is used when empty.
+	TenantId *uuid.UUID
+}
+
+type ImportResult struct {
+	TenantId     uuid.UUID `json:"tenant_id"`
+	Users        int       `json:"users"`
+	Credentials  int       `json:"credentials"`
+	Transactions int       `json:"transactions"`
+	Jwks         int       `json:"jwks"`
+	AuditLogs    int       `json:"audit_logs"`
+}
+
+// preparedImport contains the validated and decrypted content of an archive
+type preparedImport struct {
+	export   *TenantExport
+	tenant   request.CreateTenantDto
+	secrets  models.Secrets
+	jwks     [][]byte
+	remapped bool
+}
+
+// Import validates the archive completely and then writes the tenant in a single transaction
+func Import(ctx echo.Context, persister persistence.Persister, archive *Archive, passphrase string, options ImportOptions) (*ImportResult, error) {
+	prepared, err := prepareImport(archive, passphrase, options)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+
+	err = checkConflicts(persister, prepared)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ImportResult
+	err = persister.Transaction(func(tx *pop.Connection) error {
+		result, err = writeImport(ctx, persister, tx, prepared)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func prepareImport(archive *Archive, passphrase string, options ImportOptions) (*preparedImport, error) {
+	export, keys, err := archive.open(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantId := export.TenantId
+	if options.TenantId != nil {
+		tenantId = *options.TenantId
+	}
+
+	var config request.CreateConfigDto
+	err = json.Unmarshal(export.Config, &config)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenant config: %w", err)
+	}
+
+	tenantIdString := tenantId.String()
+	prepared := &preparedImport{
+		export: export,
+		tenant: request.CreateTenantDto{
+			Id:          &tenantIdString,
+			DisplayName: export.DisplayName,
+			Config:      config,
+		},
+		remapped: tenantId != export.TenantId,
+	}
+
+	err = validators.NewCustomValidator().Validate(&prepared.tenant)
+	if err != nil {
+		var httpError *echo.HTTPError
+		if errors.As(err, &httpError) {
+			return nil, fmt.Errorf("invalid tenant: %v", httpError.Message)
+		}
+
+		return nil, fmt.Errorf("invalid tenant: %w", err)
+	}
+
+	for _, secret := range export.Secrets {
+		key, err := keys.encrypter.Decrypt(secret.EncryptedKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt secret '%s': %w", secret.Name, err)
+		}
+
+		prepared.secrets = append(prepared.secrets, models.Secret{
+			Name:        secret.Name,
+			Key:         string(key),
+			IsAPISecret: secret.IsApiKey,
+			CreatedAt:   secret.CreatedAt,
+		})
+	}
+
+	for _, jwk := range export.Jwks {
+		keyData, err := keys.encrypter.Decrypt(jwk.EncryptedKeyData)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decrypt jwk: %w", err)
+		}
+
+		if !json.Valid(keyData) {
+			return nil, errors.New("archive contains an invalid jwk")
+		}
+
+		prepared.jwks = append(prepared.jwks, keyData)
+	}
+
+	userIds := make(map[uuid.UUID]bool)
+	for _, user := range export.Users {
+		if strings.TrimSpace(user.UserID) == "" {
+			return nil, fmt.Errorf("user '%s' has no user id", user.ID)
+		}
+
+		userIds[user.ID] = true
+	}
+
+	for _, transaction := range export.Transactions {
+		if !userIds[transaction.WebauthnUserID] {
+			return nil, fmt.Errorf("transaction '%s' references an unknown user", transaction.Identifier)
+		}
+	}
+
+	return prepared, nil
+}
+
+func checkConflicts(persister persistence.Persister, prepared *preparedImport) error {
+	tenantId := uuid.FromStringOrNil(*prepared.tenant.Id)
+	tenant, err := persister.GetTenantPersister(nil).Get(tenantId)
+	if err != nil {
+		return err
+	}
+
+	if tenant != nil {
+		return fmt.Errorf("%w: tenant '%s' already exists", ErrConflict, tenantId)
+	}
+
+	if prepared.remapped {
+		originalTenant, err := persister.GetTenantPersister(nil).Get(prepared.export.TenantId)
+		if err != nil {
+			return err
+		}
+
+		if originalTenant != nil {
+			return fmt.Errorf("%w: a remapped tenant cannot be imported next to the original tenant '%s', as credential IDs are unique across tenants", ErrConflict, originalTenant.ID)
+		}
+	}
+
+	var credentialIds []string
+	for _, user := range prepared.export.Users {
+		for _, credential := range user.Credentials {
+			credentialIds = append(credentialIds, credential.ID)
+		}
+	}
+
+	existingCredentials, err := persister.GetWebauthnCredentialPersister(nil).ListByIds(credentialIds)
+	if err != nil {
+		return err
+	}
+
+	if len(existingCredentials) > 0 {
+		return fmt.Errorf("%w: %d credentials of the archive already exist, e.g. '%s'", ErrConflict, len(existingCredentials), existingCredentials[0].ID)
+	}
+
+	return nil
+}
+
+func writeImport(ctx echo.Context, persister persistence.Persister, tx *pop.Connection, prepared *preparedImport) (*ImportResult, error) {
+	tenantService := admin.NewTenantService(admin.CreateTenantServiceParams{
+		Ctx: ctx,
+
+		TenantPersister:         persister.GetTenantPersister(tx),
+		ConfigPersister:         persister.GetConfigPersister(tx),
+		CorsPersister:           persister.GetCorsPersister(tx),
+		WebauthnConfigPersister: persister.GetWebauthnConfigPersister(tx),
+		RelyingPartyPerister:    persister.GetWebauthnRelyingPartyPersister(tx),
+		AuditConfigPersister:    persister.GetAuditLogConfigPersister(tx),
+		SecretPersister:         persister.GetSecretsPersister(tx),
+		JwkPersister:            persister.GetJwkPersister(tx),
+		MFAConfigPersister:      persister.GetMFAConfigPersister(tx),
+		ConfigVersionPersister:  persister.GetConfigVersionPersister(tx),
+	})
+
+	tenant, err := tenantService.ImportTenant(prepared.tenant, prepared.secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{TenantId: tenant.ID}
+
+	result.Jwks, err = importJwks(persister.GetJwkPersister(tx), tenant, prepared.jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	userIds := make(map[uuid.UUID]uuid.UUID)
+	for _, user := range prepared.export.Users {
+		userModel := models.WebauthnUser{
+			ID:          prepared.mapId(user.ID),
+			UserID:      user.UserID,
+			Name:        user.Name,
+			Icon:        user.Icon,
+			DisplayName: user.DisplayName,
+			TenantID:    tenant.ID,
+			CreatedAt:   user.CreatedAt,
+			UpdatedAt:   user.UpdatedAt,
+			LastLoginAt: user.LastLoginAt,
+			DisabledAt:  user.DisabledAt,
+		}
+		userIds[user.ID] = userModel.ID
+
+		err = persister.GetWebauthnUserPersister(tx).Create(&userModel)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, credential := range user.Credentials {
+			err = persister.GetWebauthnCredentialPersister(tx).Create(toCredentialModel(credential, userModel))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		result.Users++
+		result.Credentials += len(user.Credentials)
+	}
+
+	for _, transaction := range prepared.export.Transactions {
+		err = persister.GetTransactionPersister(tx).Create(&models.Transaction{
+			ID:             prepared.mapId(transaction.ID),
+			Identifier:     transaction.Identifier,
+			Data:           transaction.Data,
+			Challenge:      transaction.Challenge,
+			WebauthnUserID: userIds[transaction.WebauthnUserID],
+			TenantID:       tenant.ID,
+			CreatedAt:      transaction.CreatedAt,
+			UpdatedAt:      transaction.UpdatedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		result.Transactions++
+	}
+
+	for _, auditLog := range prepared.export.AuditLogs {
+		auditLog.ID = prepared.mapId(auditLog.ID)
+		auditLog.TenantID = &tenant.ID
+
+		err = persister.GetAuditLogPersister(tx).Create(&auditLog)
+		if err != nil {
+			return nil, err
+		}
+
+		result.AuditLogs++
+	}
+
+	return result, nil
+}
+
+// importJwks encrypts the imported JWKs with the JWK secrets of the tenant. Missing JWKs are generated afterward.
+func importJwks(jwkPersister persisters.JwkPersister, tenant *models.Tenant, jwks [][]byte) (int, error) {
+	var jwkKeys []string
+	for _, secret := range tenant.Config.Secrets {
+		if !secret.IsAPISecret {
+			jwkKeys = append(jwkKeys, secret.Key)
+		}
+	}
+
+	if len(jwkKeys) == 0 {
+		return 0, errors.New("archive contains no jwk secret")
+	}
+
+	encrypter, err := aes_gcm.NewAESGCM(jwkKeys)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, keyData := range jwks {
+		encryptedKeyData, err := encrypter.Encrypt(keyData)
+		if err != nil {
+			return 0, fmt.Errorf("unable to encrypt jwk: %w", err)
+		}
+
+		err = jwkPersister.Create(models.Jwk{
+			TenantID:  tenant.ID,
+			KeyData:   encryptedKeyData,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	_, err = hankoJwk.NewDefaultManager(context.Background(), jwkKeys, tenant.ID, jwkPersister)
+	if err != nil {
+		return 0, fmt.Errorf("unable to initialize jwt generator: %w", err)
+	}
+
+	return len(jwks), nil
+}
+
+func toCredentialModel(credential CredentialExport, user models.WebauthnUser) *models.WebauthnCredential {
+	credentialModel := &models.WebauthnCredential{
+		ID:               credential.ID,
+		UserId:           user.UserID,
+		Name:             credential.Name,
+		PublicKey:        credential.PublicKey,
+		AttestationType:  credential.AttestationType,
+		AAGUID:           credential.AAGUID,
+		SignCount:        credential.SignCount,
+		LastUsedAt:       credential.LastUsedAt,
+		CreatedAt:        credential.CreatedAt,
+		UpdatedAt:        credential.UpdatedAt,
+		BackupEligible:   credential.BackupEligible,
+		BackupState:      credential.BackupState,
+		IsMFA:            credential.IsMFA,
+		RPId:             credential.RPId,
+		Status:           models.CredentialStatus(credential.Status),
+		StatusReason:     credential.StatusReason,
+		DisabledAt:       credential.DisabledAt,
+		RevokedAt:        credential.RevokedAt,
+		CreatedUserAgent: credential.CreatedUserAgent,
+		CreatedDevice:    credential.CreatedDevice,
+		CreatedIp:        credential.CreatedIp,
+		WebauthnUserID:   user.ID,
+	}
+
+	// archives of older versions do not contain the status of credentials
+	if credentialModel.Status == "" {
+		credentialModel.Status = models.CredentialStatusActive
+	}
+
+	for _, name := range credential.Transports {
+		transportId, _ := uuid.NewV4()
+		credentialModel.Transports = append(credentialModel.Transports, models.WebauthnCredentialTransport{
+			ID:                   transportId,
+			Name:                 name,
+			WebauthnCredentialID: credential.ID,
+		})
+	}
+
+	return credentialModel
+}
+
+func (prepared *preparedImport) mapId(id uuid.UUID) uuid.UUID {
+	if !prepared.remapped {
+		return id
+	}
+
+	newId, _ := uuid.NewV4()
+	return newId
+}
@@ -0,0 +1,208 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/utils"
+	"strings"
+)
+
+const RedactedValue = "[REDACTED]"
+
+// sensitiveKeys are redacted from the details of admin audit logs. Keys ending with one of them are redacted too,
+// e.g. `api_key`.
+var sensitiveKeys = []string{"secret", "key", "password", "passphrase", "token"}
+
+// AdminEntry describes an operation of the admin API
+type AdminEntry struct {
+	Type models.AuditLogType
+	// TenantId is nil for operations which must outlive the tenant
+	TenantId   *uuid.UUID
+	TargetType models.AuditLogTargetType
+	TargetId   string
+	// UserId is the user the target belongs to, e.g. the owner of a credential. It is stored as actor user id, so the
+	// entry is still found and pseudonymized with the user after the target was deleted.
+	UserId string
+	// Before and After are compared in their JSON representation to summarize the changes of the operation
+	Before interface{}
+	After  interface{}
+}
+
+// AdminLogger stores audit logs for the admin API. In contrast to the Logger, the entries are always stored, as
+// they are independent of the audit log config of a tenant. They are sent to the admin audit sinks of the server config
+// instead of the sinks of the tenant.
+type AdminLogger interface {
+	Create(entry AdminEntry) error
+}
+
+type adminLogger struct {
+	persister persisters.AuditLogPersister
+	ctx       echo.Context
+	actor     *string
+	sinks     []Sink
+}
+
+func NewAdminLogger(ctx echo.Context, persister persisters.AuditLogPersister, actor *string, sinks []Sink) AdminLogger {
+	return &adminLogger{
+		persister: persister,
+		ctx:       ctx,
+		actor:     actor,
+		sinks:     sinks,
+	}
+}
+
+func (l *adminLogger) Create(entry AdminEntry) error {
+	id, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("failed to create id: %w", err)
+	}
+
+	details, err := summarizeChanges(entry.Before, entry.After)
+	if err != nil {
+		return fmt.Errorf(CreationFailureFormat, err)
+	}
+
+	targetType := string(entry.TargetType)
+	al := models.AuditLog{
+		ID:                id,
+		TenantID:          entry.TenantId,
+		Type:              entry.Type,
+		MetaHttpRequestId: l.ctx.Response().Header().Get(echo.HeaderXRequestID),
+		MetaUserAgent:     l.ctx.Request().UserAgent(),
+		MetaSourceIp:      l.ctx.RealIP(),
+		Actor:             l.actor,
+		TargetType:        &targetType,
+		Details:           details,
+	}
+
+	// bulk operations like imports have no single target
+	if entry.TargetId != "" {
+		al.TargetId = &entry.TargetId
+	}
+
+	if entry.UserId != "" {
+		al.ActorUserId = &entry.UserId
+	}
+
+	err = l.persister.Create(&al)
+	if err != nil {
+		return fmt.Errorf(CreationFailureFormat, err)
+	}
+
+	for _, sink := range l.sinks {
+		sink.Send(al)
+	}
+
+	return nil
+}
+
+// summarizeChanges compares both values and redacts all secrets from the changes. A missing value is compared as an
+// empty object, so created and deleted resources are listed field by field.
+func summarizeChanges(before interface{}, after interface{}) (*models.AuditLogDetails, error) {
+	if before == nil && after == nil {
+		return nil, nil
+	}
+
+	from, err := marshalAuditValue(before)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := marshalAuditValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := utils.Diff(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	details := models.AuditLogDetails{
+		Changes: make([]models.AuditLogChange, 0, len(changes)),
+	}
+
+	for _, change := range changes {
+		details.Changes = append(details.Changes, redactChange(change))
+	}
+
+	return &details, nil
+}
+
+func marshalAuditValue(value interface{}) ([]byte, error) {
+	if value == nil {
+		return []byte("{}"), nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize audit log value: %w", err)
+	}
+
+	return data, nil
+}
+
+func redactChange(change utils.JsonChange) models.AuditLogChange {
+	for _, segment := range strings.Split(change.Path, "/") {
+		if isSensitiveKey(segment) {
+			return models.AuditLogChange{
+				Path: change.Path,
+				From: redactPresentValue(change.From),
+				To:   redactPresentValue(change.To),
+			}
+		}
+	}
+
+	return models.AuditLogChange{
+		Path: change.Path,
+		From: redactValue(change.From),
+		To:   redactValue(change.To),
+	}
+}
+
+// redactPresentValue keeps missing values, so it stays visible whether a secret was added, changed or removed
+func redactPresentValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	return RedactedValue
+}
+
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			if isSensitiveKey(key) {
+				redacted[key] = redactPresentValue(child)
+			} else {
+				redacted[key] = redactValue(child)
+			}
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, 0, len(v))
+		for _, child := range v {
+			redacted = append(redacted, redactValue(child))
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitiveKey := range sensitiveKeys {
+		if key == sensitiveKey || strings.HasSuffix(key, "_"+sensitiveKey) {
+			return true
+		}
+	}
+
+	return false
+}
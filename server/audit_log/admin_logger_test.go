@@ -0,0 +1,57 @@
+package auditlog
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"testing"
+)
+
+func TestSummarizeChanges(t *testing.T) {
+	before := map[string]interface{}{
+		"display_name": "Before",
+		"api_key": map[string]interface{}{
+			"name":   "Initial API Key",
+			"secret": "very-secret",
+		},
+	}
+	after := map[string]interface{}{
+		"display_name": "After",
+		"api_key": map[string]interface{}{
+			"name":   "Initial API Key",
+			"secret": "another-secret",
+		},
+		"keys": []interface{}{
+			map[string]interface{}{"name": "jwk", "private_key": "private"},
+		},
+	}
+
+	details, err := summarizeChanges(before, after)
+	require.NoError(t, err)
+	require.NotNil(t, details)
+
+	assert.Equal(t, []models.AuditLogChange{
+		{Path: "/api_key/secret", From: RedactedValue, To: RedactedValue},
+		{Path: "/display_name", From: "Before", To: "After"},
+		{Path: "/keys", To: []interface{}{
+			map[string]interface{}{"name": "jwk", "private_key": RedactedValue},
+		}},
+	}, details.Changes)
+}
+
+func TestSummarizeChangesOfCreatedResource(t *testing.T) {
+	details, err := summarizeChanges(nil, map[string]interface{}{"name": "user", "secret": "very-secret"})
+	require.NoError(t, err)
+	require.NotNil(t, details)
+
+	assert.Equal(t, []models.AuditLogChange{
+		{Path: "/name", To: "user"},
+		{Path: "/secret", To: RedactedValue},
+	}, details.Changes)
+}
+
+func TestSummarizeChangesWithoutValues(t *testing.T) {
+	details, err := summarizeChanges(nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, details)
+}
@@ -0,0 +1,48 @@
+package auditlog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+
+	"github.com/teamhanko/passkey-server/config"
+)
+
+var (
+	truncatedIpv4Mask = net.CIDRMask(24, 32)
+	truncatedIpv6Mask = net.CIDRMask(48, 128)
+)
+
+// AnonymizeIp truncates the IP to its /24 (IPv4) or /48 (IPv6) network or replaces it by an HMAC with the salt of the
+// tenant. Hashed IPs of a tenant can still be compared, but not be reversed without the salt. Values which are no IP
+// are hashed as well when truncating, so they cannot leak an address.
+func AnonymizeIp(ip string, mode string, salt string) string {
+	switch mode {
+	case config.IpAnonymizationTruncate:
+		parsed := net.ParseIP(ip)
+		if parsed == nil {
+			return hashIp(ip, salt)
+		}
+
+		if ipv4 := parsed.To4(); ipv4 != nil {
+			return ipv4.Mask(truncatedIpv4Mask).String()
+		}
+
+		return parsed.Mask(truncatedIpv6Mask).String()
+	case config.IpAnonymizationHash:
+		return hashIp(ip, salt)
+	default:
+		return ip
+	}
+}
+
+func hashIp(ip string, salt string) string {
+	if ip == "" {
+		return ""
+	}
+
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
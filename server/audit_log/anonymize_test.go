@@ -0,0 +1,40 @@
+package auditlog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/teamhanko/passkey-server/config"
+)
+
+func TestAnonymizeIp(t *testing.T) {
+	tests := []struct {
+		name     string
+		ip       string
+		mode     string
+		expected string
+	}{
+		{name: "none", ip: "81.2.69.142", mode: config.IpAnonymizationNone, expected: "81.2.69.142"},
+		{name: "empty mode", ip: "81.2.69.142", mode: "", expected: "81.2.69.142"},
+		{name: "truncate ipv4", ip: "81.2.69.142", mode: config.IpAnonymizationTruncate, expected: "81.2.69.0"},
+		{name: "truncate ipv6", ip: "2001:db8:85a3:8d3:1319:8a2e:370:7348", mode: config.IpAnonymizationTruncate, expected: "2001:db8:85a3::"},
+		{name: "truncate mapped ipv4", ip: "::ffff:81.2.69.142", mode: config.IpAnonymizationTruncate, expected: "81.2.69.0"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, AnonymizeIp(test.ip, test.mode, "salt"))
+		})
+	}
+}
+
+func TestAnonymizeIpWithHash(t *testing.T) {
+	hashed := AnonymizeIp("81.2.69.142", config.IpAnonymizationHash, "salt")
+
+	assert.Len(t, hashed, 64)
+	assert.Equal(t, hashed, AnonymizeIp("81.2.69.142", config.IpAnonymizationHash, "salt"))
+	assert.NotEqual(t, hashed, AnonymizeIp("81.2.69.142", config.IpAnonymizationHash, "other salt"))
+	assert.Empty(t, AnonymizeIp("", config.IpAnonymizationHash, "salt"))
+	// values which are no IP must not be stored in clear text
+	assert.Len(t, AnonymizeIp("unknown", config.IpAnonymizationTruncate, "salt"), 64)
+}
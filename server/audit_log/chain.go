@@ -0,0 +1,390 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+	hankoJwk "github.com/teamhanko/passkey-server/crypto/jwk"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"time"
+)
+
+const verifyBatchSize = 500
+
+// checkpointPayload is signed with a JWK of the tenant. As every hash covers all entries before it, a checkpoint
+// proves the state of the whole chain up to its sequence.
+type checkpointPayload struct {
+	TenantId uuid.UUID `json:"tenant_id"`
+	Sequence int       `json:"sequence"`
+	Hash     string    `json:"hash"`
+}
+
+// ChainVerification is the result of the verification of an audit log chain
+type ChainVerification struct {
+	Valid              bool       `json:"valid"`
+	TenantId           *uuid.UUID `json:"tenant_id,omitempty"`
+	Start              *time.Time `json:"start_time,omitempty"`
+	End                *time.Time `json:"end_time,omitempty"`
+	CheckedEntries     int        `json:"checked_entries"`
+	CheckedCheckpoints int        `json:"checked_checkpoints"`
+	// PseudonymizedEntries are verified, except for their personal data which was removed
+	PseudonymizedEntries int         `json:"pseudonymized_entries"`
+	FirstBrokenLink      *BrokenLink `json:"first_broken_link,omitempty"`
+}
+
+// BrokenLink describes the first entry of a chain which could not be verified
+type BrokenLink struct {
+	AuditLogId *uuid.UUID `json:"audit_log_id,omitempty"`
+	Sequence   int        `json:"sequence"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	Reason     string     `json:"reason"`
+}
+
+// CreateCheckpoint signs the hash of the audit log with the current JWK of the tenant
+func CreateCheckpoint(persister persistence.Persister, tx *pop.Connection, tenant *models.Tenant, auditLog *models.AuditLog) error {
+	if !auditLog.IsChained() {
+		return fmt.Errorf("unable to create a checkpoint for an audit log which is not chained")
+	}
+
+	jwkManager, err := newJwkManager(persister, tx, tenant)
+	if err != nil {
+		return err
+	}
+
+	signingKey, err := jwkManager.GetSigningKey(tenant.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get signing key: %w", err)
+	}
+
+	signature, err := signCheckpoint(signingKey, tenant.ID, auditLog)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return fmt.Errorf("failed to create id: %w", err)
+	}
+
+	now := time.Now().UTC()
+	return persister.GetAuditLogCheckpointPersister(tx).Create(&models.AuditLogCheckpoint{
+		ID:        id,
+		TenantID:  tenant.ID,
+		Sequence:  *auditLog.Sequence,
+		Hash:      *auditLog.Hash,
+		Signature: signature,
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+}
+
+func signCheckpoint(signingKey jwk.Key, tenantId uuid.UUID, auditLog *models.AuditLog) (string, error) {
+	payload, err := json.Marshal(checkpointPayload{
+		TenantId: tenantId,
+		Sequence: *auditLog.Sequence,
+		Hash:     *auditLog.Hash,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize checkpoint: %w", err)
+	}
+
+	signature, err := jws.Sign(payload, jws.WithKey(jwa.RS256, signingKey))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign checkpoint: %w", err)
+	}
+
+	return string(signature), nil
+}
+
+// VerifyChain checks all chained entries of the tenant in the time range. Without a tenant, the chain of entries
+// which are not attached to a tenant is verified. Verification stops at the first broken link.
+func VerifyChain(persister persistence.Persister, tenant *models.Tenant, start *time.Time, end *time.Time) (*ChainVerification, error) {
+	verifier := chainVerifier{
+		persister:         persister,
+		auditLogPersister: persister.GetAuditLogPersister(nil),
+		tenant:            tenant,
+		result: ChainVerification{
+			Valid: true,
+			Start: start,
+			End:   end,
+		},
+	}
+
+	if tenant != nil {
+		verifier.result.TenantId = &tenant.ID
+	}
+
+	err := verifier.verify(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return &verifier.result, nil
+}
+
+type chainVerifier struct {
+	persister         persistence.Persister
+	auditLogPersister persisters.AuditLogPersister
+	tenant            *models.Tenant
+	publicKeys        jwk.Set
+	result            ChainVerification
+}
+
+func (v *chainVerifier) verify(start *time.Time, end *time.Time) error {
+	head, err := v.auditLogPersister.GetChainHead(v.result.TenantId)
+	if err != nil {
+		return err
+	}
+
+	// the head of a chain is created with its tenant, so a chain without entries has a head with sequence 0
+	if head == nil || head.Sequence == 0 {
+		return nil
+	}
+
+	var previous *models.AuditLog
+	afterSequence := 0
+	for {
+		entries, err := v.auditLogPersister.ListChain(persisters.AuditLogChainOptions{
+			TenantId:      v.result.TenantId,
+			Start:         start,
+			End:           end,
+			AfterSequence: afterSequence,
+			Limit:         verifyBatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(entries) == 0 {
+			break
+		}
+
+		if previous == nil && *entries[0].Sequence > 1 {
+			previous, err = v.auditLogPersister.GetBySequence(v.result.TenantId, *entries[0].Sequence-1)
+			if err != nil {
+				return err
+			}
+
+			if previous == nil {
+				previous = purgedAnchor(head, *entries[0].Sequence-1)
+			}
+
+			if previous == nil {
+				v.breakAt(&entries[0], "the previous entry is missing")
+				return nil
+			}
+		}
+
+		checkpoints, err := v.getCheckpoints(*entries[0].Sequence, *entries[len(entries)-1].Sequence)
+		if err != nil {
+			return err
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			// entries created after the verification started are not checked
+			if *entry.Sequence > head.Sequence {
+				return nil
+			}
+
+			reason, err := verifyEntry(entry, previous)
+			if err != nil {
+				return err
+			}
+
+			if reason == "" {
+				if checkpoint, ok := checkpoints[*entry.Sequence]; ok {
+					reason, err = v.verifyCheckpoint(checkpoint, entry)
+					if err != nil {
+						return err
+					}
+				}
+			}
+
+			if reason != "" {
+				v.breakAt(entry, reason)
+				return nil
+			}
+
+			v.result.CheckedEntries++
+			if entry.PseudonymizedAt != nil {
+				v.result.PseudonymizedEntries++
+			}
+			previous = entry
+		}
+
+		afterSequence = *previous.Sequence
+	}
+
+	if end != nil || (start != nil && previous == nil) {
+		return nil
+	}
+
+	if previous == nil {
+		previous = purgedAnchor(head, head.Sequence)
+	}
+
+	// the head of the chain is updated with every entry, so deleting the latest entries can be detected as well
+	if previous == nil || *previous.Sequence != head.Sequence || *previous.Hash != head.Hash {
+		v.result.Valid = false
+		v.result.FirstBrokenLink = &BrokenLink{
+			Sequence: head.Sequence,
+			Reason:   "the latest entries of the chain are missing",
+		}
+	}
+
+	return nil
+}
+
+// purgedAnchor returns the latest entry purged by the retention as predecessor of the entry after it. The anchor
+// only contains the sequence and the hash, which is all that is needed to verify the next entry.
+func purgedAnchor(head *models.AuditLogChain, sequence int) *models.AuditLog {
+	if head.PurgedHash == nil || head.PurgedSequence != sequence {
+		return nil
+	}
+
+	hash := *head.PurgedHash
+	return &models.AuditLog{Sequence: &sequence, Hash: &hash}
+}
+
+func (v *chainVerifier) breakAt(entry *models.AuditLog, reason string) {
+	createdAt := entry.CreatedAt
+	v.result.Valid = false
+	v.result.FirstBrokenLink = &BrokenLink{
+		AuditLogId: &entry.ID,
+		Sequence:   *entry.Sequence,
+		CreatedAt:  &createdAt,
+		Reason:     reason,
+	}
+}
+
+func (v *chainVerifier) getCheckpoints(fromSequence int, toSequence int) (map[int]models.AuditLogCheckpoint, error) {
+	checkpoints := make(map[int]models.AuditLogCheckpoint)
+	if v.tenant == nil {
+		return checkpoints, nil
+	}
+
+	list, err := v.persister.GetAuditLogCheckpointPersister(nil).ListBetween(v.tenant.ID, fromSequence, toSequence)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, checkpoint := range list {
+		checkpoints[checkpoint.Sequence] = checkpoint
+	}
+
+	return checkpoints, nil
+}
+
+func (v *chainVerifier) verifyCheckpoint(checkpoint models.AuditLogCheckpoint, entry *models.AuditLog) (string, error) {
+	if v.publicKeys == nil {
+		jwkManager, err := newJwkManager(v.persister, nil, v.tenant)
+		if err != nil {
+			return "", err
+		}
+
+		keys, err := jwkManager.GetPublicKeys(v.tenant.ID)
+		if err != nil {
+			return "", fmt.Errorf("failed to get public keys: %w", err)
+		}
+
+		v.publicKeys = keys
+	}
+
+	v.result.CheckedCheckpoints++
+
+	payload, err := jws.Verify([]byte(checkpoint.Signature), jws.WithKeySet(v.publicKeys))
+	if err != nil {
+		return "the signature of the checkpoint is invalid", nil
+	}
+
+	var signed checkpointPayload
+	err = json.Unmarshal(payload, &signed)
+	if err != nil {
+		return "the signature of the checkpoint is invalid", nil
+	}
+
+	if signed.TenantId != v.tenant.ID || signed.Sequence != *entry.Sequence || signed.Hash != *entry.Hash || checkpoint.Hash != *entry.Hash {
+		return "the entry does not match the signed checkpoint", nil
+	}
+
+	return "", nil
+}
+
+// verifyEntry returns why the entry breaks the chain or an empty string if it is valid
+func verifyEntry(entry *models.AuditLog, previous *models.AuditLog) (string, error) {
+	if !entry.IsChained() {
+		return "the entry is not chained", nil
+	}
+
+	// the salt of the personal data is removed by the pseudonymization, all other fields can always be verified
+	if entry.PersonalSalt != nil {
+		personalHash, err := entry.CalculatePersonalHash()
+		if err != nil {
+			return "", err
+		}
+
+		if personalHash != *entry.PersonalHash {
+			return "the personal data of the entry was modified", nil
+		}
+	} else if entry.PseudonymizedAt == nil {
+		return "the personal data of the entry was modified", nil
+	}
+
+	contentHash, err := entry.CalculateContentHash()
+	if err != nil {
+		return "", err
+	}
+
+	if contentHash != *entry.ContentHash {
+		return "the content of the entry was modified", nil
+	}
+
+	if previous == nil {
+		if entry.PreviousHash != nil {
+			return "the first entry of the chain references a previous entry", nil
+		}
+	} else {
+		if *entry.Sequence != *previous.Sequence+1 {
+			return fmt.Sprintf("%d entries before this entry are missing", *entry.Sequence-*previous.Sequence-1), nil
+		}
+
+		if entry.PreviousHash == nil || *entry.PreviousHash != *previous.Hash {
+			return "the entry does not reference the hash of the previous entry", nil
+		}
+	}
+
+	if entry.CalculateHash() != *entry.Hash {
+		return "the hash of the entry was modified", nil
+	}
+
+	return "", nil
+}
+
+func newJwkManager(persister persistence.Persister, tx *pop.Connection, tenant *models.Tenant) (hankoJwk.Manager, error) {
+	var keys []string
+	for _, secret := range tenant.Config.Secrets {
+		if !secret.IsAPISecret {
+			keys = append(keys, secret.Key)
+		}
+	}
+
+	ctx := context.Background()
+	if tx != nil {
+		ctx = tx.Context()
+	}
+
+	jwkManager, err := hankoJwk.NewDefaultManager(ctx, keys, tenant.ID, persister.GetJwkPersister(tx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create jwk manager: %w", err)
+	}
+
+	return jwkManager, nil
+}
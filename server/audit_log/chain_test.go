@@ -0,0 +1,158 @@
+package auditlog
+
+import (
+	"github.com/gofrs/uuid"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	hankoJwk "github.com/teamhanko/passkey-server/crypto/jwk"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"testing"
+	"time"
+)
+
+func createChain(t *testing.T, tenantId uuid.UUID, length int) []models.AuditLog {
+	chain := make([]models.AuditLog, 0, length)
+	for i := 1; i <= length; i++ {
+		id, _ := uuid.NewV4()
+		sequence := i
+		entry := models.AuditLog{
+			ID:           id,
+			TenantID:     &tenantId,
+			Type:         models.AuditLogWebAuthnAuthenticationFinalSucceeded,
+			MetaSourceIp: "127.0.0.1",
+			CreatedAt:    time.Now().UTC().Truncate(time.Second),
+			Sequence:     &sequence,
+		}
+
+		if i > 1 {
+			entry.PreviousHash = chain[i-2].Hash
+		}
+
+		require.NoError(t, entry.SealPersonalData())
+		contentHash, err := entry.CalculateContentHash()
+		require.NoError(t, err)
+		entry.ContentHash = &contentHash
+
+		hash := entry.CalculateHash()
+		entry.Hash = &hash
+
+		chain = append(chain, entry)
+	}
+
+	return chain
+}
+
+func verifyEntries(t *testing.T, chain []models.AuditLog) string {
+	var previous *models.AuditLog
+	for i := range chain {
+		reason, err := verifyEntry(&chain[i], previous)
+		require.NoError(t, err)
+
+		if reason != "" {
+			return reason
+		}
+
+		previous = &chain[i]
+	}
+
+	return ""
+}
+
+func TestVerifyEntry(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+
+	assert.Empty(t, verifyEntries(t, createChain(t, tenantId, 3)))
+}
+
+func TestVerifyEntryWithModifiedContent(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	chain := createChain(t, tenantId, 3)
+	chain[1].MetaSourceIp = "10.0.0.1"
+
+	assert.Equal(t, "the personal data of the entry was modified", verifyEntries(t, chain))
+
+	chain = createChain(t, tenantId, 3)
+	chain[1].Type = models.AuditLogWebAuthnAuthenticationFinalFailed
+
+	assert.Equal(t, "the content of the entry was modified", verifyEntries(t, chain))
+}
+
+func TestVerifyEntryWithDeletedEntry(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	chain := createChain(t, tenantId, 3)
+
+	assert.Equal(t, "1 entries before this entry are missing", verifyEntries(t, []models.AuditLog{chain[0], chain[2]}))
+}
+
+func TestVerifyEntryWithRecalculatedContentHash(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	chain := createChain(t, tenantId, 3)
+	chain[1].Type = models.AuditLogWebAuthnAuthenticationFinalFailed
+	contentHash, err := chain[1].CalculateContentHash()
+	require.NoError(t, err)
+	chain[1].ContentHash = &contentHash
+
+	assert.Equal(t, "the hash of the entry was modified", verifyEntries(t, chain))
+}
+
+func TestVerifyCheckpoint(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	chain := createChain(t, tenantId, 2)
+
+	generator := hankoJwk.RSAKeyGenerator{}
+	signingKey, err := generator.Generate("key")
+	require.NoError(t, err)
+
+	publicKeys, err := jwk.PublicSetOf(newKeySet(t, signingKey))
+	require.NoError(t, err)
+
+	signature, err := signCheckpoint(signingKey, tenantId, &chain[1])
+	require.NoError(t, err)
+
+	verifier := chainVerifier{
+		tenant:     &models.Tenant{ID: tenantId},
+		publicKeys: publicKeys,
+	}
+
+	checkpoint := models.AuditLogCheckpoint{TenantID: tenantId, Sequence: 2, Hash: *chain[1].Hash, Signature: signature}
+	reason, err := verifier.verifyCheckpoint(checkpoint, &chain[1])
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+
+	reason, err = verifier.verifyCheckpoint(checkpoint, &chain[0])
+	require.NoError(t, err)
+	assert.Equal(t, "the entry does not match the signed checkpoint", reason)
+
+	checkpoint.Signature = signature[:len(signature)-4] + "AAAA"
+	reason, err = verifier.verifyCheckpoint(checkpoint, &chain[1])
+	require.NoError(t, err)
+	assert.Equal(t, "the signature of the checkpoint is invalid", reason)
+}
+
+func newKeySet(t *testing.T, key jwk.Key) jwk.Set {
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(key))
+
+	return set
+}
+
+func TestVerifyEntryAfterPurge(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	chain := createChain(t, tenantId, 4)
+	head := &models.AuditLogChain{Sequence: 4, Hash: *chain[3].Hash, PurgedSequence: 2, PurgedHash: chain[1].Hash}
+
+	assert.Nil(t, purgedAnchor(head, 1))
+
+	anchor := purgedAnchor(head, 2)
+	require.NotNil(t, anchor)
+	reason, err := verifyEntry(&chain[2], anchor)
+	require.NoError(t, err)
+	assert.Empty(t, reason)
+
+	otherHash := *chain[0].Hash
+	head.PurgedHash = &otherHash
+	reason, err = verifyEntry(&chain[2], purgedAnchor(head, 2))
+	require.NoError(t, err)
+	assert.Equal(t, "the entry does not reference the hash of the previous entry", reason)
+}
@@ -0,0 +1,113 @@
+package auditlog
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/utils"
+)
+
+// Enricher adds a summary of the user agent and the location of the source IP to audit logs. The location is looked
+// up in local MaxMind DB files, so no request leaves the server. A nil Enricher only parses the user agent.
+type Enricher struct {
+	databases []*maxminddb.Reader
+}
+
+// geoIpRecord contains the fields of the city and the ASN databases. Fields missing in a database are left empty.
+type geoIpRecord struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	AutonomousSystemNumber       uint   `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+func NewEnricher(cfg config.GeoIp) (*Enricher, error) {
+	enricher := &Enricher{}
+	for _, file := range cfg.DatabaseFiles {
+		database, err := maxminddb.Open(file)
+		if err != nil {
+			_ = enricher.Close()
+			return nil, fmt.Errorf("failed to open geo ip database '%s': %w", file, err)
+		}
+
+		enricher.databases = append(enricher.databases, database)
+	}
+
+	return enricher, nil
+}
+
+// Enrich sets the meta fields of the audit log derived from its user agent and source IP
+func (e *Enricher) Enrich(auditLog *models.AuditLog) {
+	enrichUserAgent(auditLog)
+
+	if e == nil || len(e.databases) == 0 {
+		return
+	}
+
+	ip := net.ParseIP(auditLog.MetaSourceIp)
+	if ip == nil {
+		return
+	}
+
+	var record geoIpRecord
+	for _, database := range e.databases {
+		// a failed lookup only leaves the location empty, it must not fail the ceremony
+		_ = database.Lookup(ip, &record)
+	}
+
+	auditLog.MetaCountry = nonEmpty(record.Country.IsoCode)
+	auditLog.MetaCity = nonEmpty(record.City.Names["en"])
+	auditLog.MetaAsOrganization = nonEmpty(record.AutonomousSystemOrganization)
+	if record.AutonomousSystemNumber > 0 {
+		asn := int(record.AutonomousSystemNumber)
+		auditLog.MetaAsn = &asn
+	}
+}
+
+func (e *Enricher) Close() error {
+	if e == nil {
+		return nil
+	}
+
+	var errs []string
+	for _, database := range e.databases {
+		err := database.Close()
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to close geo ip databases: %s", strings.Join(errs, ", "))
+	}
+
+	return nil
+}
+
+// enrichUserAgent uses the same parser as the creation metadata of credentials, so both describe a client alike
+func enrichUserAgent(auditLog *models.AuditLog) {
+	if strings.TrimSpace(auditLog.MetaUserAgent) == "" {
+		return
+	}
+
+	ua := utils.ParseUserAgent(auditLog.MetaUserAgent)
+	auditLog.MetaBrowser = nonEmpty(ua.BrowserWithVersion())
+	auditLog.MetaOs = nonEmpty(ua.OSWithVersion())
+	auditLog.MetaDevice = nonEmpty(ua.Device)
+}
+
+func nonEmpty(value string) *string {
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
@@ -0,0 +1,75 @@
+package auditlog
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/utils"
+)
+
+func TestEnricherParsesUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		browser   string
+		os        string
+		device    string
+	}{
+		{
+			name:      "desktop",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			browser:   "Chrome 120.0.0.0",
+			os:        "macOS 10.15.7",
+			device:    utils.DeviceDesktop,
+		},
+		{
+			name:      "mobile",
+			userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			browser:   "Safari 17.1",
+			os:        "iOS 17.1",
+			device:    utils.DeviceMobile,
+		},
+	}
+
+	var enricher *Enricher
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			auditLog := models.AuditLog{MetaUserAgent: test.userAgent, MetaSourceIp: "81.2.69.142"}
+			enricher.Enrich(&auditLog)
+
+			require.NotNil(t, auditLog.MetaBrowser)
+			assert.Equal(t, test.browser, *auditLog.MetaBrowser)
+			require.NotNil(t, auditLog.MetaOs)
+			assert.Equal(t, test.os, *auditLog.MetaOs)
+			require.NotNil(t, auditLog.MetaDevice)
+			assert.Equal(t, test.device, *auditLog.MetaDevice)
+
+			// without a database the location stays empty
+			assert.Nil(t, auditLog.MetaCountry)
+			assert.Nil(t, auditLog.MetaAsn)
+		})
+	}
+}
+
+func TestEnricherKeepsEmptyUserAgent(t *testing.T) {
+	enricher, err := NewEnricher(config.GeoIp{})
+	require.NoError(t, err)
+	defer enricher.Close()
+
+	auditLog := models.AuditLog{MetaSourceIp: "not an ip"}
+	enricher.Enrich(&auditLog)
+
+	assert.Nil(t, auditLog.MetaBrowser)
+	assert.Nil(t, auditLog.MetaOs)
+	assert.Nil(t, auditLog.MetaDevice)
+	assert.Nil(t, auditLog.MetaCountry)
+}
+
+func TestNewEnricherFailsOnMissingDatabase(t *testing.T) {
+	_, err := NewEnricher(config.GeoIp{DatabaseFiles: []string{filepath.Join(t.TempDir(), "missing.mmdb")}})
+	assert.ErrorContains(t, err, "failed to open geo ip database")
+}
@@ -8,6 +8,7 @@ import (
 	zeroLog "github.com/rs/zerolog"
 	zeroLogger "github.com/rs/zerolog/log"
 	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"os"
@@ -25,6 +26,12 @@ type logger struct {
 	storageEnabled        bool
 	logger                zeroLog.Logger
 	consoleLoggingEnabled bool
+	checkpointInterval    int
+	ipAnonymization       string
+	ipHashSalt            string
+	storeUserAgent        bool
+	sinks                 []Sink
+	enricher              *Enricher
 	tenant                *models.Tenant
 	ctx                   echo.Context
 }
@@ -33,7 +40,7 @@ const (
 	CreationFailureFormat = "failed to create audit log: %w"
 )
 
-func NewLogger(persister persistence.Persister, cfg models.AuditLogConfig, ctx echo.Context, tenant *models.Tenant) Logger {
+func NewLogger(persister persistence.Persister, cfg models.AuditLogConfig, ctx echo.Context, tenant *models.Tenant, sinks []Sink, enricher *Enricher) Logger {
 	var loggerOutput *os.File = nil
 	switch cfg.OutputStream {
 	case config.OutputStreamStdOut:
@@ -49,6 +56,12 @@ func NewLogger(persister persistence.Persister, cfg models.AuditLogConfig, ctx e
 		storageEnabled:        cfg.StorageEnabled,
 		logger:                zeroLog.New(loggerOutput),
 		consoleLoggingEnabled: cfg.ConsoleEnabled,
+		checkpointInterval:    cfg.CheckpointInterval,
+		ipAnonymization:       cfg.IpAnonymization,
+		ipHashSalt:            cfg.IpHashSalt,
+		storeUserAgent:        cfg.StoreUserAgent,
+		sinks:                 sinks,
+		enricher:              enricher,
 		ctx:                   ctx,
 		tenant:                tenant,
 	}
@@ -59,29 +72,43 @@ func (l *logger) Create(auditLogType models.AuditLogType, user *string, transact
 }
 
 func (l *logger) CreateWithConnection(tx *pop.Connection, auditLogType models.AuditLogType, user *string, transaction *models.Transaction, logError error) error {
+	// the ceremony is observed first, so it is counted even if its entry cannot be stored
+	metrics.ObserveCeremony(l.tenant.ID, auditLogType, metrics.ErrorClass(logError))
+
+	al, err := l.newAuditLog(auditLogType, user, transaction, logError)
+	if err != nil {
+		return err
+	}
+
 	if l.storageEnabled {
-		err := l.store(tx, auditLogType, user, transaction, logError)
+		err = l.store(tx, al)
 		if err != nil {
 			return err
 		}
 	}
 
 	if l.consoleLoggingEnabled {
-		l.logToConsole(auditLogType, user, transaction, logError)
+		l.logToConsole(al, logError)
+	}
+
+	// sinks receive the entry before the transaction is committed, as they must not block the ceremony. They also
+	// receive entries which are rolled back, see config.AuditSink.
+	for _, sink := range l.sinks {
+		sink.Send(*al)
 	}
 
 	return nil
 }
 
-func (l *logger) store(tx *pop.Connection, auditLogType models.AuditLogType, user *string, transaction *models.Transaction, logError error) error {
+func (l *logger) newAuditLog(auditLogType models.AuditLogType, user *string, transaction *models.Transaction, logError error) (*models.AuditLog, error) {
 	id, err := uuid.NewV4()
 	if err != nil {
-		return fmt.Errorf("failed to create id: %w", err)
+		return nil, fmt.Errorf("failed to create id: %w", err)
 	}
 
 	al := models.AuditLog{
 		ID:                id,
-		Tenant:            l.tenant,
+		TenantID:          &l.tenant.ID,
 		Type:              auditLogType,
 		Error:             nil,
 		MetaHttpRequestId: l.ctx.Response().Header().Get(echo.HeaderXRequestID),
@@ -89,6 +116,7 @@ func (l *logger) store(tx *pop.Connection, auditLogType models.AuditLogType, use
 		MetaSourceIp:      l.ctx.RealIP(),
 		ActorUserId:       nil,
 		TransactionId:     nil,
+		CreatedAt:         time.Now().UTC(),
 	}
 
 	if user != nil {
@@ -105,28 +133,61 @@ func (l *logger) store(tx *pop.Connection, auditLogType models.AuditLogType, use
 		al.Error = &tmp
 	}
 
-	return l.persister.GetAuditLogPersister(tx).Create(al)
+	// the user agent is summarized before it is dropped. If the IP is anonymized, the location is looked up from its
+	// truncated network, so it is not more precise than the anonymized IP.
+	sourceIp := al.MetaSourceIp
+	if l.ipAnonymization == config.IpAnonymizationTruncate || l.ipAnonymization == config.IpAnonymizationHash {
+		al.MetaSourceIp = AnonymizeIp(sourceIp, config.IpAnonymizationTruncate, l.ipHashSalt)
+	}
+	l.enricher.Enrich(&al)
+
+	al.MetaSourceIp = AnonymizeIp(sourceIp, l.ipAnonymization, l.ipHashSalt)
+	if !l.storeUserAgent {
+		al.MetaUserAgent = ""
+	}
+
+	return &al, nil
+}
+
+func (l *logger) store(tx *pop.Connection, al *models.AuditLog) error {
+	err := l.persister.GetAuditLogPersister(tx).Create(al)
+	if err != nil {
+		return err
+	}
+
+	if l.checkpointInterval > 0 && *al.Sequence%l.checkpointInterval == 0 {
+		err = CreateCheckpoint(l.persister, tx, l.tenant, al)
+		if err != nil {
+			return fmt.Errorf("failed to create audit log checkpoint: %w", err)
+		}
+	}
+
+	return nil
 }
 
-func (l *logger) logToConsole(auditLogType models.AuditLogType, user *string, transaction *models.Transaction, logError error) {
+// logToConsole logs the entry with the same anonymized source IP and user agent which is stored
+func (l *logger) logToConsole(al *models.AuditLog, logError error) {
 	now := time.Now()
 	loggerEvent := zeroLogger.Log().
 		Str("audience", "audit").
-		Str("type", string(auditLogType)).
+		Str("type", string(al.Type)).
 		AnErr("error", logError).
 		Str("tenant", l.tenant.ID.String()).
-		Str("http_request_id", l.ctx.Response().Header().Get(echo.HeaderXRequestID)).
-		Str("source_ip", l.ctx.RealIP()).
-		Str("user_agent", l.ctx.Request().UserAgent()).
+		Str("http_request_id", al.MetaHttpRequestId).
+		Str("source_ip", al.MetaSourceIp).
 		Str("time", now.Format(time.RFC3339Nano)).
 		Str("time_unix", strconv.FormatInt(now.Unix(), 10))
 
-	if user != nil {
-		loggerEvent.Str("user_id", *user)
+	if al.MetaUserAgent != "" {
+		loggerEvent.Str("user_agent", al.MetaUserAgent)
 	}
 
-	if transaction != nil {
-		loggerEvent.Str("transaction_id", transaction.Identifier)
+	if al.ActorUserId != nil {
+		loggerEvent.Str("user_id", *al.ActorUserId)
+	}
+
+	if al.TransactionId != nil {
+		loggerEvent.Str("transaction_id", *al.TransactionId)
 	}
 
 	loggerEvent.Send()
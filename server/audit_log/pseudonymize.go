@@ -0,0 +1,54 @@
+package auditlog
+
+import (
+	"time"
+
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+// Pseudonymize replaces the personal data of an entry of an erased user. The user id and the ids of the user as
+// target are replaced by the pseudonym, so the entries of the user can still be correlated. The source IP, the
+// user agent, the precise location and all changed values are removed. The hashes are kept and the salt of the
+// personal data is removed, so the chain and all other fields can still be verified, but the personal hash cannot be
+// used to guess the removed data.
+func Pseudonymize(auditLog *models.AuditLog, pseudonym string, userIds []string, now time.Time) {
+	if auditLog.ActorUserId != nil && containsString(userIds, *auditLog.ActorUserId) {
+		auditLog.ActorUserId = &pseudonym
+	}
+
+	if auditLog.TargetsUser() && auditLog.TargetId != nil && containsString(userIds, *auditLog.TargetId) {
+		auditLog.TargetId = &pseudonym
+	}
+
+	auditLog.MetaSourceIp = ""
+	auditLog.MetaUserAgent = ""
+	auditLog.MetaCity = nil
+	auditLog.MetaAsn = nil
+	auditLog.MetaAsOrganization = nil
+
+	if auditLog.Details != nil {
+		changes := make([]models.AuditLogChange, 0, len(auditLog.Details.Changes))
+		for _, change := range auditLog.Details.Changes {
+			changes = append(changes, models.AuditLogChange{
+				Path: change.Path,
+				From: redactPresentValue(change.From),
+				To:   redactPresentValue(change.To),
+			})
+		}
+		auditLog.Details = &models.AuditLogDetails{Changes: changes}
+	}
+
+	auditLog.PersonalSalt = nil
+	auditLog.PseudonymizedAt = &now
+	auditLog.UpdatedAt = now
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package auditlog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+func TestPseudonymizeKeepsChainIntact(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	chain := createChain(t, tenantId, 3)
+
+	userId := "user-1"
+	chain[1].ActorUserId = &userId
+	chain[1].MetaUserAgent = "Mozilla/5.0"
+	require.NoError(t, chain[1].SealPersonalData())
+	contentHash, err := chain[1].CalculateContentHash()
+	require.NoError(t, err)
+	chain[1].ContentHash = &contentHash
+	for i := 1; i < len(chain); i++ {
+		chain[i].PreviousHash = chain[i-1].Hash
+		hash := chain[i].CalculateHash()
+		chain[i].Hash = &hash
+	}
+	require.Empty(t, verifyEntries(t, chain))
+
+	Pseudonymize(&chain[1], "pseudonym", []string{userId}, time.Now().UTC())
+
+	assert.Equal(t, "pseudonym", *chain[1].ActorUserId)
+	assert.Empty(t, chain[1].MetaSourceIp)
+	assert.Empty(t, chain[1].MetaUserAgent)
+	assert.NotNil(t, chain[1].PseudonymizedAt)
+	assert.Nil(t, chain[1].PersonalSalt)
+	assert.Empty(t, verifyEntries(t, chain))
+
+	chain[1].Type = models.AuditLogWebAuthnAuthenticationFinalFailed
+	assert.Equal(t, "the content of the entry was modified", verifyEntries(t, chain))
+
+	chain[1].Type = models.AuditLogWebAuthnAuthenticationFinalSucceeded
+	chain[1].PseudonymizedAt = nil
+	assert.Equal(t, "the personal data of the entry was modified", verifyEntries(t, chain))
+}
+
+func TestPseudonymizeRedactsAdminEntries(t *testing.T) {
+	targetType := string(models.AuditLogTargetUser)
+	targetId, _ := uuid.NewV4()
+	target := targetId.String()
+	auditLog := models.AuditLog{
+		TargetType: &targetType,
+		TargetId:   &target,
+		Details: &models.AuditLogDetails{Changes: []models.AuditLogChange{
+			{Path: "/name", From: "John", To: "Johnny"},
+			{Path: "/icon", To: "https://example.com/john.png"},
+		}},
+	}
+
+	Pseudonymize(&auditLog, "pseudonym", []string{"user-1", target}, time.Now().UTC())
+
+	assert.Equal(t, "pseudonym", *auditLog.TargetId)
+	assert.Equal(t, []models.AuditLogChange{
+		{Path: "/name", From: RedactedValue, To: RedactedValue},
+		{Path: "/icon", To: RedactedValue},
+	}, auditLog.Details.Changes)
+}
@@ -0,0 +1,53 @@
+package auditlog
+
+import (
+	"log"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+)
+
+// WatchRetention purges expired audit logs in the configured interval
+func WatchRetention(cfg config.AuditRetention, persister persistence.Persister) {
+	ticker := time.NewTicker(cfg.GetPurgeInterval())
+	defer ticker.Stop()
+
+	for {
+		count, err := PurgeExpired(persister, time.Now())
+		if err != nil {
+			log.Printf("failed to purge audit logs: %v", err)
+		} else if count > 0 {
+			log.Printf("purged %d expired audit logs", count)
+		}
+
+		<-ticker.C
+	}
+}
+
+// PurgeExpired deletes the audit logs of all tenants which are older than the retention of the tenant. Each tenant is
+// purged in its own transaction, so a failure does not block the purge of other tenants.
+func PurgeExpired(persister persistence.Persister, now time.Time) (int, error) {
+	retentions, err := persister.GetAuditLogConfigPersister(nil).ListRetentions()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var lastErr error
+	for _, retention := range retentions {
+		before := now.UTC().AddDate(0, 0, -retention.RetentionDays)
+		err = persister.Transaction(func(tx *pop.Connection) error {
+			purged, err := persister.GetAuditLogPersister(tx).Purge(retention.TenantID, before)
+			count += purged
+			return err
+		})
+		if err != nil {
+			log.Printf("failed to purge audit logs of tenant %s: %v", retention.TenantID, err)
+			lastErr = err
+		}
+	}
+
+	return count, lastErr
+}
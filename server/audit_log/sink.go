@@ -0,0 +1,282 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	zeroLogger "github.com/rs/zerolog/log"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink receives the audit logs of all tenants which selected it in their audit log config. Send never blocks, so a
+// slow sink cannot delay a ceremony.
+type Sink interface {
+	Name() string
+	Send(auditLog models.AuditLog)
+	Close() error
+}
+
+// sinkWriter delivers a batch of audit logs to the destination of a sink. It is only called by the worker of the
+// sink, so implementations do not need to be safe for concurrent use.
+type sinkWriter interface {
+	Write(auditLogs []models.AuditLog) error
+	Close() error
+}
+
+// Sinks contains the sinks of the global config by their name
+type Sinks struct {
+	sinks map[string]Sink
+}
+
+func NewSinks(cfg config.AuditSinks) (*Sinks, error) {
+	sinks := &Sinks{
+		sinks: make(map[string]Sink, len(cfg)),
+	}
+
+	for _, sinkConfig := range cfg {
+		sink, err := newSink(sinkConfig)
+		if err != nil {
+			_ = sinks.Close()
+			return nil, fmt.Errorf("failed to create audit sink '%s': %w", sinkConfig.Name, err)
+		}
+
+		sinks.sinks[sinkConfig.Name] = sink
+	}
+
+	return sinks, nil
+}
+
+func newSink(cfg config.AuditSink) (Sink, error) {
+	switch cfg.Type {
+	case config.AuditSinkTypeFile:
+		return newBufferedSink(cfg, newFileWriter(cfg.File), 1, time.Second), nil
+	case config.AuditSinkTypeSyslog:
+		return newBufferedSink(cfg, newSyslogWriter(cfg.Syslog), 1, time.Second), nil
+	case config.AuditSinkTypeHttp:
+		return newBufferedSink(cfg, newHttpWriter(cfg.Http), cfg.Http.GetBatchSize(), cfg.Http.GetFlushInterval()), nil
+	default:
+		return nil, fmt.Errorf("unknown sink type: %s", cfg.Type)
+	}
+}
+
+// Select returns the sinks with the given names. Unknown names are logged and skipped, as the tenant config is
+// managed independently of the server config.
+func (s *Sinks) Select(names []string) []Sink {
+	if s == nil || len(names) == 0 {
+		return nil
+	}
+
+	selected := make([]Sink, 0, len(names))
+	for _, name := range names {
+		sink, ok := s.sinks[name]
+		if !ok {
+			zeroLogger.Warn().Str("sink", name).Msg("audit sink is not configured")
+			continue
+		}
+
+		selected = append(selected, sink)
+	}
+
+	return selected
+}
+
+// Close flushes all buffered audit logs and closes the sinks
+func (s *Sinks) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, sink := range s.sinks {
+		errs = append(errs, sink.Close())
+	}
+
+	return errors.Join(errs...)
+}
+
+// bufferedSink decouples the delivery of audit logs from the request. Audit logs which do not fit into the buffer or
+// could not be delivered are written to the dead letter file.
+type bufferedSink struct {
+	name          string
+	writer        sinkWriter
+	deadLetter    *deadLetterFile
+	batchSize     int
+	flushInterval time.Duration
+	entries       chan models.AuditLog
+	done          chan struct{}
+	mutex         sync.RWMutex
+	closed        bool
+}
+
+func newBufferedSink(cfg config.AuditSink, writer sinkWriter, batchSize int, flushInterval time.Duration) *bufferedSink {
+	sink := &bufferedSink{
+		name:          cfg.Name,
+		writer:        writer,
+		deadLetter:    newDeadLetterFile(cfg.DeadLetterFile),
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+		entries:       make(chan models.AuditLog, cfg.GetBufferSize()),
+		done:          make(chan struct{}),
+	}
+
+	go sink.run()
+
+	return sink
+}
+
+func (s *bufferedSink) Name() string {
+	return s.name
+}
+
+func (s *bufferedSink) Send(auditLog models.AuditLog) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.closed {
+		s.drop([]models.AuditLog{auditLog}, errors.New("sink is closed"))
+		return
+	}
+
+	select {
+	case s.entries <- auditLog:
+	default:
+		s.drop([]models.AuditLog{auditLog}, errors.New("buffer is full"))
+	}
+}
+
+func (s *bufferedSink) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.entries)
+	s.mutex.Unlock()
+
+	<-s.done
+
+	return errors.Join(s.writer.Close(), s.deadLetter.Close())
+}
+
+func (s *bufferedSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.AuditLog, 0, s.batchSize)
+	for {
+		select {
+		case auditLog, ok := <-s.entries:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+
+			batch = append(batch, auditLog)
+			if len(batch) >= s.batchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (s *bufferedSink) flush(batch []models.AuditLog) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := s.writer.Write(batch)
+	if err != nil {
+		s.drop(batch, err)
+	}
+}
+
+func (s *bufferedSink) drop(auditLogs []models.AuditLog, reason error) {
+	err := s.deadLetter.Write(s.name, reason, auditLogs)
+	if err != nil {
+		zeroLogger.Error().Err(err).Str("sink", s.name).Int("count", len(auditLogs)).AnErr("reason", reason).
+			Msg("failed to deliver audit logs")
+	}
+}
+
+type deadLetterEntry struct {
+	Sink     string          `json:"sink"`
+	Reason   string          `json:"reason"`
+	FailedAt time.Time       `json:"failed_at"`
+	AuditLog models.AuditLog `json:"audit_log"`
+}
+
+// deadLetterFile stores undeliverable audit logs as JSON lines, so they can be replayed manually
+type deadLetterFile struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+func newDeadLetterFile(path string) *deadLetterFile {
+	return &deadLetterFile{
+		path: path,
+	}
+}
+
+func (d *deadLetterFile) Write(sink string, reason error, auditLogs []models.AuditLog) error {
+	if d.path == "" {
+		return errors.New("no dead letter file configured")
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.file == nil {
+		file, err := os.OpenFile(d.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open dead letter file: %w", err)
+		}
+
+		d.file = file
+	}
+
+	now := time.Now().UTC()
+	for _, auditLog := range auditLogs {
+		line, err := json.Marshal(deadLetterEntry{
+			Sink:     sink,
+			Reason:   reason.Error(),
+			FailedAt: now,
+			AuditLog: auditLog,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to serialize dead letter: %w", err)
+		}
+
+		_, err = d.file.Write(append(line, '\n'))
+		if err != nil {
+			return fmt.Errorf("failed to write dead letter: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *deadLetterFile) Close() error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+
+	err := d.file.Close()
+	d.file = nil
+
+	return err
+}
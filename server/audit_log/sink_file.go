@@ -0,0 +1,114 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"os"
+)
+
+// fileWriter appends one JSON object per line. When the file would exceed the maximum size, it is renamed to
+// `<path>.1` and older backups are shifted, e.g. `<path>.1` to `<path>.2`.
+type fileWriter struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+func newFileWriter(cfg config.AuditFileSink) *fileWriter {
+	return &fileWriter{
+		path:       cfg.Path,
+		maxSize:    cfg.GetMaxSize(),
+		maxBackups: cfg.GetMaxBackups(),
+	}
+}
+
+func (w *fileWriter) Write(auditLogs []models.AuditLog) error {
+	if w.file == nil {
+		err := w.open()
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, auditLog := range auditLogs {
+		line, err := json.Marshal(auditLog)
+		if err != nil {
+			return fmt.Errorf("failed to serialize audit log: %w", err)
+		}
+		line = append(line, '\n')
+
+		if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+			err = w.rotate()
+			if err != nil {
+				return err
+			}
+		}
+
+		written, err := w.file.Write(line)
+		w.size += int64(written)
+		if err != nil {
+			return fmt.Errorf("failed to write audit log file: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (w *fileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to read audit log file: %w", err)
+	}
+
+	w.file = file
+	w.size = info.Size()
+
+	return nil
+}
+
+func (w *fileWriter) rotate() error {
+	err := w.Close()
+	if err != nil {
+		return err
+	}
+
+	for i := w.maxBackups - 1; i > 0; i-- {
+		err = os.Rename(w.backupPath(i), w.backupPath(i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to rotate audit log file: %w", err)
+		}
+	}
+
+	err = os.Rename(w.path, w.backupPath(1))
+	if err != nil {
+		return fmt.Errorf("failed to rotate audit log file: %w", err)
+	}
+
+	return w.open()
+}
+
+func (w *fileWriter) backupPath(index int) string {
+	return fmt.Sprintf("%s.%d", w.path, index)
+}
+
+func (w *fileWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+
+	err := w.file.Close()
+	w.file = nil
+
+	return err
+}
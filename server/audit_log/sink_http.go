@@ -0,0 +1,175 @@
+package auditlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const (
+	otlpSeverityInfo = 9
+	otlpSeverityWarn = 13
+)
+
+// httpWriter posts each batch either as JSON array of audit logs or as OTLP/HTTP JSON logs request
+type httpWriter struct {
+	url     string
+	format  string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newHttpWriter(cfg config.AuditHttpSink) *httpWriter {
+	return &httpWriter{
+		url:     cfg.Url,
+		format:  cfg.GetFormat(),
+		headers: cfg.Headers,
+		client: &http.Client{
+			Timeout: cfg.GetTimeout(),
+		},
+	}
+}
+
+func (w *httpWriter) Write(auditLogs []models.AuditLog) error {
+	var payload interface{} = auditLogs
+	if w.format == config.AuditSinkHttpFormatOtlp {
+		payload = toOtlpLogs(auditLogs)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit logs: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create audit log request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range w.headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send audit logs: %w", err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("failed to send audit logs: unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (w *httpWriter) Close() error {
+	w.client.CloseIdleConnections()
+	return nil
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber"`
+	SeverityText   string          `json:"severityText"`
+	Body           otlpValue       `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// toOtlpLogs maps the audit logs to the JSON encoding of an OTLP ExportLogsServiceRequest
+func toOtlpLogs(auditLogs []models.AuditLog) otlpLogsRequest {
+	records := make([]otlpLogRecord, 0, len(auditLogs))
+	for _, auditLog := range auditLogs {
+		record := otlpLogRecord{
+			TimeUnixNano:   strconv.FormatInt(auditLog.CreatedAt.UnixNano(), 10),
+			SeverityNumber: otlpSeverityInfo,
+			SeverityText:   "INFO",
+			Body:           otlpValue{StringValue: string(auditLog.Type)},
+		}
+
+		if auditLog.Error != nil {
+			record.SeverityNumber = otlpSeverityWarn
+			record.SeverityText = "WARN"
+		}
+
+		record.Attributes = appendOtlpAttribute(record.Attributes, "audit_log.id", auditLog.ID.String())
+		record.Attributes = appendOtlpAttribute(record.Attributes, "audit_log.type", string(auditLog.Type))
+		record.Attributes = appendOtlpAttribute(record.Attributes, "http.request_id", auditLog.MetaHttpRequestId)
+		record.Attributes = appendOtlpAttribute(record.Attributes, "client.address", auditLog.MetaSourceIp)
+		record.Attributes = appendOtlpAttribute(record.Attributes, "user_agent.original", auditLog.MetaUserAgent)
+		if auditLog.TenantID != nil {
+			record.Attributes = appendOtlpAttribute(record.Attributes, "tenant.id", auditLog.TenantID.String())
+		}
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "error.message", auditLog.Error)
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "user.id", auditLog.ActorUserId)
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "transaction.id", auditLog.TransactionId)
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "actor", auditLog.Actor)
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "target.type", auditLog.TargetType)
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "target.id", auditLog.TargetId)
+		record.Attributes = appendOptionalOtlpAttribute(record.Attributes, "audit_log.hash", auditLog.Hash)
+
+		records = append(records, record)
+	}
+
+	return otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{{Key: "service.name", Value: otlpValue{StringValue: "passkey-server"}}},
+			},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: "passkey-server/audit"},
+				LogRecords: records,
+			}},
+		}},
+	}
+}
+
+func appendOtlpAttribute(attributes []otlpAttribute, key string, value string) []otlpAttribute {
+	return append(attributes, otlpAttribute{Key: key, Value: otlpValue{StringValue: value}})
+}
+
+func appendOptionalOtlpAttribute(attributes []otlpAttribute, key string, value *string) []otlpAttribute {
+	if value == nil {
+		return attributes
+	}
+
+	return appendOtlpAttribute(attributes, key, *value)
+}
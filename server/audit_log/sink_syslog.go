@@ -0,0 +1,127 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	syslogSeverityWarning = 4
+	syslogSeverityInfo    = 6
+	// syslogEnterpriseId is used for the structured data of the messages
+	syslogEnterpriseId = 32473
+	syslogDialTimeout  = 5 * time.Second
+)
+
+// syslogWriter sends RFC 5424 messages. Stream connections use octet counting framing (RFC 6587), datagram
+// connections send one message per datagram.
+type syslogWriter struct {
+	network  string
+	address  string
+	appName  string
+	facility int
+	hostname string
+	conn     net.Conn
+}
+
+func newSyslogWriter(cfg config.AuditSyslogSink) *syslogWriter {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	return &syslogWriter{
+		network:  cfg.Network,
+		address:  cfg.Address,
+		appName:  cfg.GetAppName(),
+		facility: cfg.GetFacility(),
+		hostname: hostname,
+	}
+}
+
+func (w *syslogWriter) Write(auditLogs []models.AuditLog) error {
+	if w.conn == nil {
+		conn, err := net.DialTimeout(w.network, w.address, syslogDialTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to connect to syslog: %w", err)
+		}
+
+		w.conn = conn
+	}
+
+	for _, auditLog := range auditLogs {
+		message, err := w.format(auditLog)
+		if err != nil {
+			return err
+		}
+
+		if w.isStream() {
+			message = fmt.Sprintf("%d %s", len(message), message)
+		}
+
+		_, err = w.conn.Write([]byte(message))
+		if err != nil {
+			// reconnect with the next batch
+			_ = w.Close()
+			return fmt.Errorf("failed to write to syslog: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (w *syslogWriter) isStream() bool {
+	return w.network == "tcp" || w.network == "unix"
+}
+
+// format creates a message like
+// `<110>1 2026-10-19T10:00:00.000000Z host passkey-server 42 audit [audit@32473 type="..." tenant_id="..."] {...}`
+func (w *syslogWriter) format(auditLog models.AuditLog) (string, error) {
+	severity := syslogSeverityInfo
+	if auditLog.Error != nil {
+		severity = syslogSeverityWarning
+	}
+
+	body, err := json.Marshal(auditLog)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize audit log: %w", err)
+	}
+
+	structuredData := fmt.Sprintf(`[audit@%d id="%s" type="%s"`, syslogEnterpriseId, auditLog.ID, escapeSyslogParam(string(auditLog.Type)))
+	if auditLog.TenantID != nil {
+		structuredData += fmt.Sprintf(` tenant_id="%s"`, auditLog.TenantID)
+	}
+	structuredData += "]"
+
+	return fmt.Sprintf(
+		"<%d>1 %s %s %s %d audit %s %s",
+		w.facility*8+severity,
+		auditLog.CreatedAt.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname,
+		w.appName,
+		os.Getpid(),
+		structuredData,
+		body,
+	), nil
+}
+
+func escapeSyslogParam(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
+
+func (w *syslogWriter) Close() error {
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+
+	return err
+}
@@ -0,0 +1,210 @@
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newSinkAuditLog(t *testing.T) models.AuditLog {
+	id, err := uuid.NewV4()
+	require.NoError(t, err)
+	tenantId, err := uuid.NewV4()
+	require.NoError(t, err)
+
+	return models.AuditLog{
+		ID:           id,
+		TenantID:     &tenantId,
+		Type:         models.AuditLogWebAuthnAuthenticationFinalSucceeded,
+		MetaSourceIp: "127.0.0.1",
+		CreatedAt:    time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func readLines(t *testing.T, path string) []string {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	require.NoError(t, scanner.Err())
+
+	return lines
+}
+
+type blockingWriter struct {
+	release chan struct{}
+}
+
+func (w *blockingWriter) Write(_ []models.AuditLog) error {
+	<-w.release
+	return nil
+}
+
+func (w *blockingWriter) Close() error {
+	return nil
+}
+
+type failingWriter struct{}
+
+func (w *failingWriter) Write(_ []models.AuditLog) error {
+	return errors.New("destination unavailable")
+}
+
+func (w *failingWriter) Close() error {
+	return nil
+}
+
+func TestFileWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writer := &fileWriter{path: path, maxSize: 700, maxBackups: 2}
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, writer.Write([]models.AuditLog{newSinkAuditLog(t)}))
+	}
+	require.NoError(t, writer.Close())
+
+	assert.Len(t, readLines(t, path), 1)
+	assert.Len(t, readLines(t, path+".1"), 2)
+	assert.Len(t, readLines(t, path+".2"), 2)
+	assert.NoFileExists(t, path+".3")
+
+	var auditLog models.AuditLog
+	require.NoError(t, json.Unmarshal([]byte(readLines(t, path)[0]), &auditLog))
+	assert.Equal(t, models.AuditLogWebAuthnAuthenticationFinalSucceeded, auditLog.Type)
+}
+
+func TestSyslogWriterFormat(t *testing.T) {
+	writer := &syslogWriter{appName: "passkey-server", facility: 13, hostname: "host"}
+	auditLog := newSinkAuditLog(t)
+	logError := "user not found"
+	auditLog.Error = &logError
+
+	message, err := writer.format(auditLog)
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(message, "<108>1 2026-10-19T10:00:00.000000Z host passkey-server "))
+	assert.Contains(t, message, ` audit [audit@32473 id="`+auditLog.ID.String()+`" type="webauthn_authentication_final_succeeded" tenant_id="`+auditLog.TenantID.String()+`"] {`)
+	assert.Contains(t, message, `"error":"user not found"`)
+}
+
+func TestHttpWriterSendsOtlpLogs(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	writer := newHttpWriter(config.AuditHttpSink{
+		Url:     server.URL,
+		Format:  config.AuditSinkHttpFormatOtlp,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	})
+
+	require.NoError(t, writer.Write([]models.AuditLog{newSinkAuditLog(t), newSinkAuditLog(t)}))
+
+	resourceLogs := body["resourceLogs"].([]interface{})
+	require.Len(t, resourceLogs, 1)
+	scopeLogs := resourceLogs[0].(map[string]interface{})["scopeLogs"].([]interface{})
+	records := scopeLogs[0].(map[string]interface{})["logRecords"].([]interface{})
+	require.Len(t, records, 2)
+
+	record := records[0].(map[string]interface{})
+	assert.Equal(t, "1792404000000000000", record["timeUnixNano"])
+	assert.Equal(t, "INFO", record["severityText"])
+	assert.Equal(t, "webauthn_authentication_final_succeeded", record["body"].(map[string]interface{})["stringValue"])
+}
+
+func TestHttpWriterFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	writer := newHttpWriter(config.AuditHttpSink{Url: server.URL})
+
+	assert.EqualError(t, writer.Write([]models.AuditLog{newSinkAuditLog(t)}), "failed to send audit logs: unexpected status code 503")
+}
+
+func TestBufferedSinkDoesNotBlockWhenFull(t *testing.T) {
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	writer := &blockingWriter{release: make(chan struct{})}
+	sink := newBufferedSink(config.AuditSink{Name: "slow", BufferSize: 1, DeadLetterFile: deadLetterPath}, writer, 1, time.Second)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			sink.Send(newSinkAuditLog(t))
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("sending to a full sink must not block")
+	}
+
+	close(writer.release)
+	require.NoError(t, sink.Close())
+
+	lines := readLines(t, deadLetterPath)
+	// at most one entry is held by the worker and one is buffered, all others are dropped
+	assert.GreaterOrEqual(t, len(lines), 3)
+
+	var entry deadLetterEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "slow", entry.Sink)
+	assert.Equal(t, "buffer is full", entry.Reason)
+}
+
+func TestBufferedSinkWritesFailedBatchesToDeadLetterFile(t *testing.T) {
+	deadLetterPath := filepath.Join(t.TempDir(), "dead-letter.jsonl")
+	sink := newBufferedSink(config.AuditSink{Name: "failing", DeadLetterFile: deadLetterPath}, &failingWriter{}, 10, time.Hour)
+
+	auditLog := newSinkAuditLog(t)
+	sink.Send(auditLog)
+	sink.Send(newSinkAuditLog(t))
+	require.NoError(t, sink.Close())
+
+	lines := readLines(t, deadLetterPath)
+	require.Len(t, lines, 2)
+
+	var entry deadLetterEntry
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "destination unavailable", entry.Reason)
+	assert.Equal(t, auditLog.ID, entry.AuditLog.ID)
+}
+
+func TestSinksSelectSkipsUnknownNames(t *testing.T) {
+	sinks, err := NewSinks(config.AuditSinks{
+		{Name: "archive", Type: config.AuditSinkTypeFile, File: config.AuditFileSink{Path: filepath.Join(t.TempDir(), "audit.jsonl")}},
+	})
+	require.NoError(t, err)
+	defer sinks.Close()
+
+	selected := sinks.Select([]string{"archive", "unknown"})
+	require.Len(t, selected, 1)
+	assert.Equal(t, "archive", selected[0].Name())
+
+	var noSinks *Sinks
+	assert.Nil(t, noSinks.Select([]string{"archive"}))
+}
@@ -0,0 +1,18 @@
+package audit
+
+import "github.com/spf13/cobra"
+
+func NewAuditCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "audit",
+		Short: "Audit log management",
+		Long:  "Verifying the integrity of the audit logs",
+	}
+}
+
+func RegisterCommands(parent *cobra.Command) {
+	cmd := NewAuditCommand()
+	cmd.AddCommand(NewVerifyCommand())
+
+	parent.AddCommand(cmd)
+}
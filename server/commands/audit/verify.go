@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"log"
+	"os"
+	"time"
+)
+
+func NewVerifyCommand() *cobra.Command {
+	var (
+		configFile string
+		tenantId   string
+		startTime  string
+		endTime    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "verify the hash chain of the audit logs",
+		Long:  "Verifying the hash chain and the signed checkpoints of the audit logs of a tenant. Without a tenant, the audit logs of admin operations which are not attached to a tenant are verified. Exits with status 1 if the chain is broken.",
+		Run: func(cmd *cobra.Command, args []string) {
+			start, err := parseTime(startTime)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			end, err := parseTime(endTime)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			cfg, err := config.Load(&configFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			persister, err := persistence.NewDatabase(cfg.Database)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var tenant *models.Tenant
+			if tenantId != "" {
+				id, err := uuid.FromString(tenantId)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				tenant, err = persister.GetTenantPersister(nil).Get(id)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				if tenant == nil {
+					log.Fatalf("tenant '%s' not found", tenantId)
+				}
+			}
+
+			verification, err := auditlog.VerifyChain(persister, tenant, start, end)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			content, err := json.MarshalIndent(verification, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			_, err = cmd.OutOrStdout().Write(append(content, '\n'))
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if !verification.Valid {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", config.DefaultConfigFilePath, "config file")
+	cmd.Flags().StringVar(&tenantId, "tenant-id", "", "id of the tenant to verify (default: audit logs without tenant)")
+	cmd.Flags().StringVar(&startTime, "start-time", "", "only verify entries created at or after this time (RFC 3339)")
+	cmd.Flags().StringVar(&endTime, "end-time", "", "only verify entries created at or before this time (RFC 3339)")
+
+	return cmd
+}
+
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time '%s': %w", value, err)
+	}
+
+	return &parsed, nil
+}
@@ -4,7 +4,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/teamhanko/passkey-server/api"
 	"github.com/teamhanko/passkey-server/config"
-	"github.com/teamhanko/passkey-server/persistence"
 	"log"
 	"sync"
 )
@@ -22,15 +21,24 @@ func NewServeAdminApiCommand() *cobra.Command {
 				log.Fatal(err)
 			}
 
-			persister, err := persistence.NewDatabase(globalConfig.Database)
+			stopTracing := startTracing(globalConfig)
+			defer stopTracing()
+
+			persister, err := newDatabase(globalConfig)
 			if err != nil {
 				log.Fatal(err)
 			}
 
+			auditSinks := newAuditSinks(globalConfig)
+			defer auditSinks.Close()
+
+			ctx, stop := newShutdownContext()
+			defer stop()
+
 			var wg sync.WaitGroup
 			wg.Add(1)
 
-			go api.StartAdmin(globalConfig, &wg, persister, nil)
+			go api.StartAdmin(ctx, globalConfig, &wg, persister, nil, auditSinks)
 
 			wg.Wait()
 		},
@@ -6,7 +6,6 @@ import (
 	"github.com/teamhanko/passkey-server/api"
 	"github.com/teamhanko/passkey-server/config"
 	"github.com/teamhanko/passkey-server/mapper"
-	"github.com/teamhanko/passkey-server/persistence"
 	"log"
 	"sync"
 )
@@ -29,17 +28,27 @@ func NewServeAllCommand() *cobra.Command {
 
 			authenticatorMetadata := mapper.LoadAuthenticatorMetadata(&authenticatorMetadataFile)
 
-			persister, err := persistence.NewDatabase(cfg.Database)
+			stopTracing := startTracing(cfg)
+			defer stopTracing()
+
+			persister, err := newDatabase(cfg)
 			if err != nil {
 				log.Fatal(err)
 			}
+
+			auditSinks := newAuditSinks(cfg)
+			defer auditSinks.Close()
+
+			ctx, stop := newShutdownContext()
+			defer stop()
+
 			var wg sync.WaitGroup
 			wg.Add(2)
 
 			prometheus := echoprometheus.NewMiddleware("hanko")
 
-			go api.StartPublic(cfg, &wg, persister, authenticatorMetadata)
-			go api.StartAdmin(cfg, &wg, persister, prometheus)
+			go api.StartPublic(ctx, cfg, &wg, persister, authenticatorMetadata, auditSinks)
+			go api.StartAdmin(ctx, cfg, &wg, persister, prometheus, auditSinks)
 
 			wg.Wait()
 		},
@@ -5,7 +5,6 @@ import (
 	"github.com/teamhanko/passkey-server/api"
 	"github.com/teamhanko/passkey-server/config"
 	"github.com/teamhanko/passkey-server/mapper"
-	"github.com/teamhanko/passkey-server/persistence"
 	"log"
 	"sync"
 )
@@ -28,15 +27,24 @@ func NewServePublicCommand() *cobra.Command {
 
 			authenticatorMetadata := mapper.LoadAuthenticatorMetadata(&authenticatorMetadataFile)
 
-			persister, err := persistence.NewDatabase(globalConfig.Database)
+			stopTracing := startTracing(globalConfig)
+			defer stopTracing()
+
+			persister, err := newDatabase(globalConfig)
 			if err != nil {
 				log.Fatal(err)
 			}
 
+			auditSinks := newAuditSinks(globalConfig)
+			defer auditSinks.Close()
+
+			ctx, stop := newShutdownContext()
+			defer stop()
+
 			var wg sync.WaitGroup
 			wg.Add(1)
 
-			go api.StartPublic(globalConfig, &wg, persister, authenticatorMetadata)
+			go api.StartPublic(ctx, globalConfig, &wg, persister, authenticatorMetadata, auditSinks)
 
 			wg.Wait()
 		},
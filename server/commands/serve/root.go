@@ -1,8 +1,17 @@
 package serve
 
 import (
+	"context"
 	"github.com/spf13/cobra"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
 	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/tracing"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 func NewServeCommand() *cobra.Command {
@@ -27,3 +36,47 @@ func RegisterCommands(parent *cobra.Command) {
 
 	parent.AddCommand(cmd)
 }
+
+// newShutdownContext returns a context which is canceled on SIGINT or SIGTERM, so the servers can finish their running
+// requests and the audit sinks and the tracer are flushed before the process exits
+func newShutdownContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// startTracing registers the tracer provider of the config. The returned function flushes the pending spans.
+func startTracing(cfg *config.Config) func() {
+	shutdown, err := tracing.Init(cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		err := shutdown(ctx)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// newAuditSinks creates the audit sinks of the config. They are shared by the public and the admin API, so every sink
+// is only opened once per process.
+func newAuditSinks(cfg *config.Config) *auditlog.Sinks {
+	auditSinks, err := auditlog.NewSinks(cfg.AuditSinks)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return auditSinks
+}
+
+// newDatabase returns a database whose queries are traced if tracing is enabled
+func newDatabase(cfg *config.Config) (persistence.Database, error) {
+	if cfg.Tracing.Enabled {
+		return persistence.NewTracedDatabase(cfg.Database)
+	}
+
+	return persistence.NewDatabase(cfg.Database)
+}
@@ -0,0 +1,63 @@
+package tenants
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/provisioning"
+	"log"
+)
+
+func NewApplyCommand() *cobra.Command {
+	var (
+		configFile string
+		tenantFile string
+		dryRun     bool
+		prune      bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "apply",
+		Short: "reconcile tenants with tenant files",
+		Long:  "Creating, updating and (with --prune) deleting tenants until the database matches the tenant file or all tenant files of a directory",
+		Run: func(cmd *cobra.Command, args []string) {
+			definitions, err := provisioning.LoadTenants(tenantFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			cfg, err := config.Load(&configFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			persister, err := persistence.NewDatabase(cfg.Database)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			reconciler := provisioning.NewReconciler(persister, prune)
+
+			var plan *provisioning.Plan
+			if dryRun {
+				plan, err = reconciler.Plan(definitions)
+			} else {
+				plan, err = reconciler.Apply(definitions)
+			}
+
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			plan.Print(cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", config.DefaultConfigFilePath, "config file")
+	cmd.Flags().StringVarP(&tenantFile, "file", "f", "", "tenant file or directory containing tenant files")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only show the changes without applying them")
+	cmd.Flags().BoolVar(&prune, "prune", false, "delete tenants and api keys which are not defined")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
@@ -0,0 +1,90 @@
+package tenants
+
+import (
+	"encoding/json"
+	"github.com/gofrs/uuid"
+	"github.com/spf13/cobra"
+	"github.com/teamhanko/passkey-server/archive"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"log"
+	"os"
+)
+
+// PassphraseEnv is used as archive passphrase when the --passphrase flag is not set, so it does not end up in the
+// shell history
+const PassphraseEnv = "PASSKEY_ARCHIVE_PASSPHRASE"
+
+func NewExportCommand() *cobra.Command {
+	var (
+		configFile       string
+		tenantId         string
+		passphrase       string
+		outputFile       string
+		includeJwks      bool
+		includeAuditLogs bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "export a tenant into an encrypted archive",
+		Long:  "Exporting a tenant with its users, credentials and transactions into a signed archive. Secrets and JWKs are encrypted with the passphrase.",
+		Run: func(cmd *cobra.Command, args []string) {
+			id, err := uuid.FromString(tenantId)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			cfg, err := config.Load(&configFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			persister, err := persistence.NewDatabase(cfg.Database)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			tenantArchive, err := archive.Export(persister, id, getPassphrase(passphrase), archive.ExportOptions{
+				IncludeJwks:      includeJwks,
+				IncludeAuditLogs: includeAuditLogs,
+			})
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			content, err := json.MarshalIndent(tenantArchive, "", "  ")
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if outputFile == "" {
+				_, err = cmd.OutOrStdout().Write(append(content, '\n'))
+			} else {
+				err = os.WriteFile(outputFile, content, 0600)
+			}
+
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", config.DefaultConfigFilePath, "config file")
+	cmd.Flags().StringVar(&tenantId, "tenant-id", "", "id of the tenant to export")
+	cmd.Flags().StringVar(&passphrase, "passphrase", "", "passphrase to encrypt the archive with (default: $"+PassphraseEnv+")")
+	cmd.Flags().StringVarP(&outputFile, "output", "o", "", "archive file (default: stdout)")
+	cmd.Flags().BoolVar(&includeJwks, "include-jwks", false, "include the signing keys of the tenant")
+	cmd.Flags().BoolVar(&includeAuditLogs, "include-audit-logs", false, "include the audit logs of the tenant")
+	_ = cmd.MarkFlagRequired("tenant-id")
+
+	return cmd
+}
+
+func getPassphrase(passphrase string) string {
+	if passphrase != "" {
+		return passphrase
+	}
+
+	return os.Getenv(PassphraseEnv)
+}
@@ -0,0 +1,77 @@
+package tenants
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/cobra"
+	"github.com/teamhanko/passkey-server/archive"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"log"
+	"os"
+)
+
+func NewImportCommand() *cobra.Command {
+	var (
+		configFile  string
+		archiveFile string
+		passphrase  string
+		tenantId    string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "import a tenant from an archive",
+		Long:  "Importing a tenant from an archive created by the export command. The archive is verified completely before anything is written.",
+		Run: func(cmd *cobra.Command, args []string) {
+			content, err := os.ReadFile(archiveFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			var tenantArchive archive.Archive
+			err = json.Unmarshal(content, &tenantArchive)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			options := archive.ImportOptions{}
+			if tenantId != "" {
+				id, err := uuid.FromString(tenantId)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				options.TenantId = &id
+			}
+
+			cfg, err := config.Load(&configFile)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			persister, err := persistence.NewDatabase(cfg.Database)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			result, err := archive.Import(echo.New().NewContext(nil, nil), persister, &tenantArchive, getPassphrase(passphrase), options)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "imported tenant %s: %d users, %d credentials, %d transactions, %d jwks, %d audit logs\n",
+				result.TenantId, result.Users, result.Credentials, result.Transactions, result.Jwks, result.AuditLogs)
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", config.DefaultConfigFilePath, "config file")
+	cmd.Flags().StringVarP(&archiveFile, "file", "f", "", "archive file")
+	cmd.Flags().StringVar(&passphrase, "passphrase", "", "passphrase of the archive (default: $"+PassphraseEnv+")")
+	cmd.Flags().StringVar(&tenantId, "tenant-id", "", "import the tenant under a new id")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
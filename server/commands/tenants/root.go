@@ -0,0 +1,20 @@
+package tenants
+
+import "github.com/spf13/cobra"
+
+func NewTenantsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "tenants",
+		Short: "Tenant management",
+		Long:  "Managing tenants from declarative tenant files and moving tenants between environments",
+	}
+}
+
+func RegisterCommands(parent *cobra.Command) {
+	cmd := NewTenantsCommand()
+	cmd.AddCommand(NewApplyCommand())
+	cmd.AddCommand(NewExportCommand())
+	cmd.AddCommand(NewImportCommand())
+
+	parent.AddCommand(cmd)
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	IpAnonymizationNone     = "none"
+	IpAnonymizationTruncate = "truncate"
+	IpAnonymizationHash     = "hash"
+)
+
+// AuditRetention configures the background job of the admin API which deletes the audit logs of tenants with a
+// retention in their audit log config
+type AuditRetention struct {
+	PurgeInterval string `yaml:"purge_interval" json:"purge_interval,omitempty" koanf:"purge_interval" jsonschema:"default=1h"`
+}
+
+func (r *AuditRetention) Validate() error {
+	interval, err := time.ParseDuration(r.PurgeInterval)
+	if err != nil {
+		return fmt.Errorf("purge_interval must be a duration: %w", err)
+	}
+
+	if interval <= 0 {
+		return errors.New("purge_interval must be greater than zero")
+	}
+
+	return nil
+}
+
+func (r *AuditRetention) GetPurgeInterval() time.Duration {
+	interval, _ := time.ParseDuration(r.PurgeInterval)
+	return interval
+}
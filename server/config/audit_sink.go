@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	AuditSinkTypeFile   = "file"
+	AuditSinkTypeSyslog = "syslog"
+	AuditSinkTypeHttp   = "http"
+
+	AuditSinkHttpFormatJson = "json"
+	AuditSinkHttpFormatOtlp = "otlp"
+)
+
+// AuditSink configures a destination the audit logs of a tenant can be sent to. Tenants select sinks by their name in
+// the audit log config. Entries are buffered, so a slow sink cannot block a ceremony. If the buffer is full or the
+// sink fails, entries are written to the dead letter file instead or logged, if no dead letter file is configured.
+// Entries are sent when they are created, before the transaction of the request is committed. A sink therefore also
+// receives entries which are rolled back, e.g. the entries of failed ceremonies.
+type AuditSink struct {
+	Name           string          `yaml:"name" json:"name" koanf:"name"`
+	Type           string          `yaml:"type" json:"type" koanf:"type" jsonschema:"enum=file,enum=syslog,enum=http"`
+	BufferSize     int             `yaml:"buffer_size" json:"buffer_size,omitempty" koanf:"buffer_size" jsonschema:"default=1000"`
+	DeadLetterFile string          `yaml:"dead_letter_file" json:"dead_letter_file,omitempty" koanf:"dead_letter_file"`
+	File           AuditFileSink   `yaml:"file" json:"file,omitempty" koanf:"file"`
+	Syslog         AuditSyslogSink `yaml:"syslog" json:"syslog,omitempty" koanf:"syslog"`
+	Http           AuditHttpSink   `yaml:"http" json:"http,omitempty" koanf:"http"`
+}
+
+// AuditFileSink writes one JSON object per line and rotates the file when it exceeds the maximum size
+type AuditFileSink struct {
+	Path       string `yaml:"path" json:"path" koanf:"path"`
+	MaxSizeMB  int    `yaml:"max_size_mb" json:"max_size_mb,omitempty" koanf:"max_size_mb" jsonschema:"default=100"`
+	MaxBackups int    `yaml:"max_backups" json:"max_backups,omitempty" koanf:"max_backups" jsonschema:"default=5"`
+}
+
+// AuditSyslogSink sends RFC 5424 messages to a syslog server
+type AuditSyslogSink struct {
+	Network  string `yaml:"network" json:"network" koanf:"network" jsonschema:"enum=udp,enum=tcp,enum=unix,enum=unixgram"`
+	Address  string `yaml:"address" json:"address" koanf:"address"`
+	AppName  string `yaml:"app_name" json:"app_name,omitempty" koanf:"app_name" jsonschema:"default=passkey-server"`
+	Facility int    `yaml:"facility" json:"facility,omitempty" koanf:"facility" jsonschema:"default=13"`
+}
+
+// AuditHttpSink posts batches of entries either as JSON array or as OTLP logs
+type AuditHttpSink struct {
+	Url           string            `yaml:"url" json:"url" koanf:"url"`
+	Format        string            `yaml:"format" json:"format,omitempty" koanf:"format" jsonschema:"enum=json,enum=otlp,default=json"`
+	Headers       map[string]string `yaml:"headers" json:"headers,omitempty" koanf:"headers"`
+	BatchSize     int               `yaml:"batch_size" json:"batch_size,omitempty" koanf:"batch_size" jsonschema:"default=100"`
+	FlushInterval string            `yaml:"flush_interval" json:"flush_interval,omitempty" koanf:"flush_interval" jsonschema:"default=5s"`
+	Timeout       string            `yaml:"timeout" json:"timeout,omitempty" koanf:"timeout" jsonschema:"default=10s"`
+}
+
+type AuditSinks []AuditSink
+
+func (s AuditSinks) Validate() error {
+	names := make(map[string]bool, len(s))
+	for _, sink := range s {
+		if names[sink.Name] {
+			return fmt.Errorf("sink names must be unique: %s", sink.Name)
+		}
+		names[sink.Name] = true
+
+		err := sink.Validate()
+		if err != nil {
+			return fmt.Errorf("failed to validate sink '%s': %w", sink.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Contains reports whether a sink with the name is configured
+func (s AuditSinks) Contains(name string) bool {
+	for _, sink := range s {
+		if sink.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *AuditSink) Validate() error {
+	if len(strings.TrimSpace(s.Name)) == 0 {
+		return errors.New("name must not be empty")
+	}
+
+	if s.BufferSize < 0 {
+		return errors.New("buffer_size must not be negative")
+	}
+
+	switch s.Type {
+	case AuditSinkTypeFile:
+		return s.File.Validate()
+	case AuditSinkTypeSyslog:
+		return s.Syslog.Validate()
+	case AuditSinkTypeHttp:
+		return s.Http.Validate()
+	default:
+		return fmt.Errorf("unknown type '%s', must be one of: file, syslog, http", s.Type)
+	}
+}
+
+// GetBufferSize returns the number of entries which are buffered before they are written to the dead letter file
+func (s *AuditSink) GetBufferSize() int {
+	if s.BufferSize == 0 {
+		return 1000
+	}
+
+	return s.BufferSize
+}
+
+func (f *AuditFileSink) Validate() error {
+	if len(strings.TrimSpace(f.Path)) == 0 {
+		return errors.New("file.path must not be empty")
+	}
+
+	if f.MaxSizeMB < 0 || f.MaxBackups < 0 {
+		return errors.New("file.max_size_mb and file.max_backups must not be negative")
+	}
+
+	return nil
+}
+
+func (f *AuditFileSink) GetMaxSize() int64 {
+	if f.MaxSizeMB == 0 {
+		return 100 * 1024 * 1024
+	}
+
+	return int64(f.MaxSizeMB) * 1024 * 1024
+}
+
+func (f *AuditFileSink) GetMaxBackups() int {
+	if f.MaxBackups == 0 {
+		return 5
+	}
+
+	return f.MaxBackups
+}
+
+func (s *AuditSyslogSink) Validate() error {
+	switch s.Network {
+	case "udp", "tcp", "unix", "unixgram":
+	default:
+		return fmt.Errorf("unknown syslog.network '%s', must be one of: udp, tcp, unix, unixgram", s.Network)
+	}
+
+	if len(strings.TrimSpace(s.Address)) == 0 {
+		return errors.New("syslog.address must not be empty")
+	}
+
+	if s.Facility < 0 || s.Facility > 23 {
+		return errors.New("syslog.facility must be between 0 and 23")
+	}
+
+	return nil
+}
+
+func (s *AuditSyslogSink) GetAppName() string {
+	if len(strings.TrimSpace(s.AppName)) == 0 {
+		return "passkey-server"
+	}
+
+	return s.AppName
+}
+
+// GetFacility defaults to the facility for log audit (13)
+func (s *AuditSyslogSink) GetFacility() int {
+	if s.Facility == 0 {
+		return 13
+	}
+
+	return s.Facility
+}
+
+func (h *AuditHttpSink) Validate() error {
+	target, err := url.Parse(h.Url)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return errors.New("http.url must be an absolute http or https url")
+	}
+
+	if h.Format != "" && h.Format != AuditSinkHttpFormatJson && h.Format != AuditSinkHttpFormatOtlp {
+		return fmt.Errorf("unknown http.format '%s', must be one of: json, otlp", h.Format)
+	}
+
+	if h.BatchSize < 0 {
+		return errors.New("http.batch_size must not be negative")
+	}
+
+	for name, value := range map[string]string{"flush_interval": h.FlushInterval, "timeout": h.Timeout} {
+		if value == "" {
+			continue
+		}
+
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("http.%s must be a duration: %w", name, err)
+		}
+
+		if duration <= 0 {
+			return fmt.Errorf("http.%s must be greater than zero", name)
+		}
+	}
+
+	return nil
+}
+
+func (h *AuditHttpSink) GetFormat() string {
+	if h.Format == "" {
+		return AuditSinkHttpFormatJson
+	}
+
+	return h.Format
+}
+
+func (h *AuditHttpSink) GetBatchSize() int {
+	if h.BatchSize == 0 {
+		return 100
+	}
+
+	return h.BatchSize
+}
+
+func (h *AuditHttpSink) GetFlushInterval() time.Duration {
+	interval, err := time.ParseDuration(h.FlushInterval)
+	if err != nil {
+		return 5 * time.Second
+	}
+
+	return interval
+}
+
+func (h *AuditHttpSink) GetTimeout() time.Duration {
+	timeout, err := time.ParseDuration(h.Timeout)
+	if err != nil {
+		return 10 * time.Second
+	}
+
+	return timeout
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseValidAuditSinksConfig(t *testing.T) {
+	// given
+	configPath := "./testdata/audit-sinks-config.yaml"
+
+	// when
+	cfg, err := loadTestConfig[Config](&configPath)
+
+	// then
+	require.NoError(t, err)
+	sinks := cfg.AuditSinks
+	require.Len(t, sinks, 3)
+	assert.NoError(t, sinks.Validate())
+
+	assert.Equal(t, AuditSinkTypeFile, sinks[0].Type)
+	assert.Equal(t, int64(50*1024*1024), sinks[0].File.GetMaxSize())
+	assert.Equal(t, 5, sinks[0].File.GetMaxBackups())
+	assert.Equal(t, 1000, sinks[0].GetBufferSize())
+
+	assert.Equal(t, "passkey-server", sinks[1].Syslog.GetAppName())
+	assert.Equal(t, 13, sinks[1].Syslog.GetFacility())
+
+	assert.Equal(t, 5000, sinks[2].GetBufferSize())
+	assert.Equal(t, AuditSinkHttpFormatOtlp, sinks[2].Http.GetFormat())
+	assert.Equal(t, 2*time.Second, sinks[2].Http.GetFlushInterval())
+	assert.Equal(t, 10*time.Second, sinks[2].Http.GetTimeout())
+	assert.Equal(t, "Bearer token", sinks[2].Http.Headers["Authorization"])
+
+	assert.Equal(t, []string{"siem"}, cfg.AdminAuditSinks)
+	assert.True(t, sinks.Contains("siem"))
+	assert.False(t, sinks.Contains("unknown"))
+}
+
+func TestAuditSinksValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		sinks         AuditSinks
+		expectedError string
+	}{
+		{
+			name:          "error on missing name",
+			sinks:         AuditSinks{{Type: AuditSinkTypeFile, File: AuditFileSink{Path: "audit.jsonl"}}},
+			expectedError: "failed to validate sink '': name must not be empty",
+		},
+		{
+			name: "error on duplicate name",
+			sinks: AuditSinks{
+				{Name: "file", Type: AuditSinkTypeFile, File: AuditFileSink{Path: "audit.jsonl"}},
+				{Name: "file", Type: AuditSinkTypeFile, File: AuditFileSink{Path: "other.jsonl"}},
+			},
+			expectedError: "sink names must be unique: file",
+		},
+		{
+			name:          "error on unknown type",
+			sinks:         AuditSinks{{Name: "kafka", Type: "kafka"}},
+			expectedError: "failed to validate sink 'kafka': unknown type 'kafka', must be one of: file, syslog, http",
+		},
+		{
+			name:          "error on missing file path",
+			sinks:         AuditSinks{{Name: "file", Type: AuditSinkTypeFile}},
+			expectedError: "failed to validate sink 'file': file.path must not be empty",
+		},
+		{
+			name:          "error on unknown syslog network",
+			sinks:         AuditSinks{{Name: "syslog", Type: AuditSinkTypeSyslog, Syslog: AuditSyslogSink{Network: "quic", Address: "localhost:514"}}},
+			expectedError: "failed to validate sink 'syslog': unknown syslog.network 'quic', must be one of: udp, tcp, unix, unixgram",
+		},
+		{
+			name:          "error on relative http url",
+			sinks:         AuditSinks{{Name: "http", Type: AuditSinkTypeHttp, Http: AuditHttpSink{Url: "/v1/logs"}}},
+			expectedError: "failed to validate sink 'http': http.url must be an absolute http or https url",
+		},
+		{
+			name:          "error on invalid flush interval",
+			sinks:         AuditSinks{{Name: "http", Type: AuditSinkTypeHttp, Http: AuditHttpSink{Url: "https://example.com", FlushInterval: "-1s"}}},
+			expectedError: "failed to validate sink 'http': http.flush_interval must be greater than zero",
+		},
+	}
+
+	for _, testData := range tests {
+		t.Run(testData.name, func(t *testing.T) {
+			err := testData.sinks.Validate()
+
+			assert.EqualError(t, err, testData.expectedError)
+		})
+	}
+}
@@ -16,10 +16,20 @@ var (
 )
 
 type Config struct {
-	Address      string   `yaml:"address" json:"address,omitempty" koanf:"address"`
-	AdminAddress string   `yaml:"admin_address" json:"admin_address,omitempty" koanf:"admin_address"`
-	Database     Database `yaml:"database" json:"database,omitempty" koanf:"database"`
-	Log          Logger   `yaml:"log" json:"log,omitempty" koanf:"log"`
+	Address      string       `yaml:"address" json:"address,omitempty" koanf:"address"`
+	AdminAddress string       `yaml:"admin_address" json:"admin_address,omitempty" koanf:"admin_address"`
+	Database     Database     `yaml:"database" json:"database,omitempty" koanf:"database"`
+	Log          Logger       `yaml:"log" json:"log,omitempty" koanf:"log"`
+	Provisioning Provisioning `yaml:"provisioning" json:"provisioning,omitempty" koanf:"provisioning"`
+	AuditSinks   AuditSinks   `yaml:"audit_sinks" json:"audit_sinks,omitempty" koanf:"audit_sinks"`
+	Stats        Stats        `yaml:"stats" json:"stats,omitempty" koanf:"stats"`
+	GeoIp        GeoIp        `yaml:"geo_ip" json:"geo_ip,omitempty" koanf:"geo_ip"`
+	// AuditRetention configures the purge of audit logs older than the retention of their tenant
+	AuditRetention AuditRetention `yaml:"audit_retention" json:"audit_retention,omitempty" koanf:"audit_retention"`
+	Tracing        Tracing        `yaml:"tracing" json:"tracing,omitempty" koanf:"tracing"`
+	Metrics        Metrics        `yaml:"metrics" json:"metrics,omitempty" koanf:"metrics"`
+	// AdminAuditSinks selects the audit sinks which receive the audit logs of the admin API
+	AdminAuditSinks []string `yaml:"admin_audit_sinks" json:"admin_audit_sinks,omitempty" koanf:"admin_audit_sinks"`
 }
 
 func (c *Config) Validate() error {
@@ -44,6 +54,47 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("failed to validate database config: %w", err)
 	}
 
+	err = c.Provisioning.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate provisioning config: %w", err)
+	}
+
+	err = c.AuditSinks.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate audit sinks config: %w", err)
+	}
+
+	for _, name := range c.AdminAuditSinks {
+		if !c.AuditSinks.Contains(name) {
+			return fmt.Errorf("admin audit sink '%s' is not configured", name)
+		}
+	}
+
+	err = c.Stats.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate stats config: %w", err)
+	}
+
+	err = c.GeoIp.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate geo ip config: %w", err)
+	}
+
+	err = c.AuditRetention.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate audit retention config: %w", err)
+	}
+
+	err = c.Tracing.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate tracing config: %w", err)
+	}
+
+	err = c.Metrics.Validate()
+	if err != nil {
+		return fmt.Errorf("failed to validate metrics config: %w", err)
+	}
+
 	return nil
 }
 
@@ -86,6 +137,26 @@ func NewConfig() *Config {
 		Database: Database{
 			Database: "passkey",
 		},
+		Provisioning: Provisioning{
+			Interval: "30s",
+		},
+		Stats: Stats{
+			RollupInterval: "15m",
+		},
+		AuditRetention: AuditRetention{
+			PurgeInterval: "1h",
+		},
+		Tracing: Tracing{
+			Exporter:    TracingExporterOtlp,
+			Endpoint:    "localhost:4318",
+			ServiceName: "passkey-server",
+			SampleRatio: 1,
+		},
+		Metrics: Metrics{
+			Address:     ":8002",
+			TenantLabel: MetricsTenantLabelId,
+			MaxTenants:  100,
+		},
 	}
 }
 
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GeoIp configures the offline lookup of the location of the source IP of audit logs. The files must be in the
+// MaxMind DB format, e.g. GeoLite2-City and GeoLite2-ASN. Every file is queried, so city and ASN databases can be
+// combined. Without files, audit logs are stored without location.
+type GeoIp struct {
+	DatabaseFiles []string `yaml:"database_files" json:"database_files,omitempty" koanf:"database_files"`
+}
+
+func (g *GeoIp) Validate() error {
+	for _, file := range g.DatabaseFiles {
+		if len(strings.TrimSpace(file)) == 0 {
+			return fmt.Errorf("database_files must not contain empty paths")
+		}
+
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("database file '%s' is not readable: %w", file, err)
+		}
+	}
+
+	return nil
+}
+
+func (g *GeoIp) Enabled() bool {
+	return len(g.DatabaseFiles) > 0
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	MetricsTenantLabelId   = "id"
+	MetricsTenantLabelNone = "none"
+)
+
+// Metrics configures the Prometheus metrics of the ceremonies on the public server. They are served on their own
+// address, so they are not reachable through the public API, and by the admin API of 'serve all'.
+type Metrics struct {
+	Enabled bool   `yaml:"enabled" json:"enabled,omitempty" koanf:"enabled" jsonschema:"default=false"`
+	Address string `yaml:"address" json:"address,omitempty" koanf:"address" jsonschema:"default=:8002"`
+	// TenantLabel sets whether the metrics are labeled with the tenant ID ('id') or aggregated over all tenants ('none')
+	TenantLabel string `yaml:"tenant_label" json:"tenant_label,omitempty" koanf:"tenant_label" jsonschema:"enum=id,enum=none,default=id"`
+	// MaxTenants limits the number of tenant IDs used as label. Tenants seen after the limit is reached are labeled
+	// as 'other'. 0 disables the limit.
+	MaxTenants int `yaml:"max_tenants" json:"max_tenants,omitempty" koanf:"max_tenants" jsonschema:"default=100"`
+}
+
+func (m *Metrics) Validate() error {
+	if !m.Enabled {
+		return nil
+	}
+
+	if _, _, err := net.SplitHostPort(m.Address); err != nil {
+		return errors.New("address must be formatted as 'host%zone:port', '[host]:port' or '[host%zone]:port'")
+	}
+
+	switch strings.TrimSpace(m.TenantLabel) {
+	case MetricsTenantLabelId, MetricsTenantLabelNone:
+	default:
+		return fmt.Errorf("tenant_label must be one of '%s' or '%s'", MetricsTenantLabelId, MetricsTenantLabelNone)
+	}
+
+	if m.MaxTenants < 0 {
+		return errors.New("max_tenants must not be negative")
+	}
+
+	return nil
+}
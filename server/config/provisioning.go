@@ -0,0 +1,42 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provisioning configures the declarative tenant provisioning. All tenant files in the directory are reconciled with
+// the database on startup of the admin API and whenever a file changes.
+type Provisioning struct {
+	Directory string `yaml:"directory" json:"directory,omitempty" koanf:"directory"`
+	Interval  string `yaml:"interval" json:"interval,omitempty" koanf:"interval" jsonschema:"default=30s"`
+	Prune     bool   `yaml:"prune" json:"prune,omitempty" koanf:"prune" jsonschema:"default=false"`
+}
+
+func (p *Provisioning) Enabled() bool {
+	return len(strings.TrimSpace(p.Directory)) > 0
+}
+
+func (p *Provisioning) Validate() error {
+	if !p.Enabled() {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(p.Interval)
+	if err != nil {
+		return fmt.Errorf("interval must be a duration: %w", err)
+	}
+
+	if interval <= 0 {
+		return errors.New("interval must be greater than zero")
+	}
+
+	return nil
+}
+
+func (p *Provisioning) GetInterval() time.Duration {
+	interval, _ := time.ParseDuration(p.Interval)
+	return interval
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Stats configures the rollup of audit logs for the stats API. When enabled, the admin API aggregates the audit logs
+// of all tenants hourly into rollup tables, so the stats do not have to count all audit logs on every request.
+type Stats struct {
+	Rollup         bool   `yaml:"rollup" json:"rollup,omitempty" koanf:"rollup" jsonschema:"default=false"`
+	RollupInterval string `yaml:"rollup_interval" json:"rollup_interval,omitempty" koanf:"rollup_interval" jsonschema:"default=15m"`
+}
+
+func (s *Stats) Validate() error {
+	if !s.Rollup {
+		return nil
+	}
+
+	interval, err := time.ParseDuration(s.RollupInterval)
+	if err != nil {
+		return fmt.Errorf("rollup_interval must be a duration: %w", err)
+	}
+
+	if interval <= 0 {
+		return errors.New("rollup_interval must be greater than zero")
+	}
+
+	return nil
+}
+
+func (s *Stats) GetRollupInterval() time.Duration {
+	interval, _ := time.ParseDuration(s.RollupInterval)
+	return interval
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	TracingExporterOtlp   = "otlp"
+	TracingExporterStdout = "stdout"
+)
+
+// Tracing configures the OpenTelemetry spans of the HTTP routers, the webauthn services, the JWK decryption and the
+// database queries. Incoming W3C traceparent headers are continued. Spans are exported via OTLP over HTTP or, for
+// local testing, printed to stdout.
+type Tracing struct {
+	Enabled  bool   `yaml:"enabled" json:"enabled,omitempty" koanf:"enabled" jsonschema:"default=false"`
+	Exporter string `yaml:"exporter" json:"exporter,omitempty" koanf:"exporter" jsonschema:"enum=otlp,enum=stdout,default=otlp"`
+	// Endpoint is the host and port of the OTLP collector, e.g. 'localhost:4318'
+	Endpoint    string            `yaml:"endpoint" json:"endpoint,omitempty" koanf:"endpoint" jsonschema:"default=localhost:4318"`
+	Insecure    bool              `yaml:"insecure" json:"insecure,omitempty" koanf:"insecure" jsonschema:"default=false"`
+	Headers     map[string]string `yaml:"headers" json:"headers,omitempty" koanf:"headers"`
+	ServiceName string            `yaml:"service_name" json:"service_name,omitempty" koanf:"service_name" jsonschema:"default=passkey-server"`
+	// SampleRatio is the share of traces without sampled parent which are recorded
+	SampleRatio float64 `yaml:"sample_ratio" json:"sample_ratio,omitempty" koanf:"sample_ratio" jsonschema:"default=1"`
+}
+
+func (t *Tracing) Validate() error {
+	if !t.Enabled {
+		return nil
+	}
+
+	switch t.Exporter {
+	case TracingExporterOtlp:
+		if len(strings.TrimSpace(t.Endpoint)) == 0 {
+			return errors.New("endpoint must not be empty")
+		}
+	case TracingExporterStdout:
+	default:
+		return fmt.Errorf("exporter must be one of '%s' or '%s'", TracingExporterOtlp, TracingExporterStdout)
+	}
+
+	if len(strings.TrimSpace(t.ServiceName)) == 0 {
+		return errors.New("service_name must not be empty")
+	}
+
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		return errors.New("sample_ratio must be between 0 and 1")
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package cose
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"math/big"
+)
+
+// MinRSAKeySize is the minimum size of an RSA modulus in bits
+const MinRSAKeySize = 2048
+
+// COSE key parameters, see https://www.iana.org/assignments/cose/cose.xhtml#key-common-parameters
+const (
+	keyTypeLabel   = 1
+	algorithmLabel = 3
+	curveLabel     = -1
+	xLabel         = -2
+	yLabel         = -3
+	modulusLabel   = -1
+	exponentLabel  = -2
+)
+
+// COSE elliptic curves, see https://www.iana.org/assignments/cose/cose.xhtml#elliptic-curves
+const (
+	curveP256    = 1
+	curveP384    = 2
+	curveP521    = 3
+	curveEd25519 = 6
+)
+
+// ValidatePublicKey checks that the bytes are a COSE_Key which can be used to verify WebAuthn assertions. In contrast to
+// webauthncose.ParsePublicKey, malformed keys, unsupported algorithms and invalid curve points are rejected.
+func ValidatePublicKey(keyBytes []byte) error {
+	if len(keyBytes) == 0 {
+		return errors.New("public key is empty")
+	}
+
+	var key map[int64]interface{}
+	err := webauthncbor.Unmarshal(keyBytes, &key)
+	if err != nil {
+		return fmt.Errorf("public key is not a valid COSE key: %w", err)
+	}
+
+	keyType, ok := getInt(key, keyTypeLabel)
+	if !ok {
+		return errors.New("public key has no key type")
+	}
+
+	algorithm, ok := getInt(key, algorithmLabel)
+	if !ok {
+		return errors.New("public key has no algorithm")
+	}
+
+	switch webauthncose.COSEKeyType(keyType) {
+	case webauthncose.EllipticKey:
+		return validateEC2Key(key, webauthncose.COSEAlgorithmIdentifier(algorithm))
+	case webauthncose.OctetKey:
+		return validateOKPKey(key, webauthncose.COSEAlgorithmIdentifier(algorithm))
+	case webauthncose.RSAKey:
+		return validateRSAKey(key, webauthncose.COSEAlgorithmIdentifier(algorithm))
+	default:
+		return fmt.Errorf("unsupported key type %d", keyType)
+	}
+}
+
+func validateEC2Key(key map[int64]interface{}, algorithm webauthncose.COSEAlgorithmIdentifier) error {
+	curve, _ := getInt(key, curveLabel)
+
+	var ecdhCurve ecdh.Curve
+	var expectedCurve int64
+	switch algorithm {
+	case webauthncose.AlgES256:
+		ecdhCurve, expectedCurve = ecdh.P256(), curveP256
+	case webauthncose.AlgES384:
+		ecdhCurve, expectedCurve = ecdh.P384(), curveP384
+	case webauthncose.AlgES512:
+		ecdhCurve, expectedCurve = ecdh.P521(), curveP521
+	default:
+		return fmt.Errorf("unsupported algorithm %d for an EC2 key", algorithm)
+	}
+
+	if curve != expectedCurve {
+		return fmt.Errorf("curve %d does not match algorithm %d", curve, algorithm)
+	}
+
+	x, xOk := getBytes(key, xLabel)
+	y, yOk := getBytes(key, yLabel)
+	if !xOk || !yOk {
+		return errors.New("EC2 key is missing its coordinates")
+	}
+
+	// an uncompressed point is only accepted by ecdh if it lies on the curve
+	point := append([]byte{0x04}, x...)
+	point = append(point, y...)
+	_, err := ecdhCurve.NewPublicKey(point)
+	if err != nil {
+		return errors.New("EC2 key is not a valid point on the curve")
+	}
+
+	return nil
+}
+
+func validateOKPKey(key map[int64]interface{}, algorithm webauthncose.COSEAlgorithmIdentifier) error {
+	if algorithm != webauthncose.AlgEdDSA {
+		return fmt.Errorf("unsupported algorithm %d for an OKP key", algorithm)
+	}
+
+	curve, _ := getInt(key, curveLabel)
+	if curve != curveEd25519 {
+		return fmt.Errorf("unsupported curve %d for an OKP key", curve)
+	}
+
+	x, ok := getBytes(key, xLabel)
+	if !ok || len(x) != ed25519.PublicKeySize {
+		return errors.New("OKP key has an invalid length")
+	}
+
+	return nil
+}
+
+func validateRSAKey(key map[int64]interface{}, algorithm webauthncose.COSEAlgorithmIdentifier) error {
+	switch algorithm {
+	case webauthncose.AlgRS256, webauthncose.AlgRS384, webauthncose.AlgRS512,
+		webauthncose.AlgPS256, webauthncose.AlgPS384, webauthncose.AlgPS512:
+	default:
+		return fmt.Errorf("unsupported algorithm %d for an RSA key", algorithm)
+	}
+
+	modulus, modulusOk := getBytes(key, modulusLabel)
+	exponent, exponentOk := getBytes(key, exponentLabel)
+	if !modulusOk || !exponentOk {
+		return errors.New("RSA key is missing its modulus or exponent")
+	}
+
+	if new(big.Int).SetBytes(modulus).BitLen() < MinRSAKeySize {
+		return fmt.Errorf("RSA key must be at least %d bits long", MinRSAKeySize)
+	}
+
+	// the exponent is decoded as 3 bytes by go-webauthn
+	e := new(big.Int).SetBytes(exponent)
+	if len(exponent) != 3 || e.Bit(0) == 0 || e.Cmp(big.NewInt(1)) <= 0 {
+		return errors.New("RSA key has an invalid exponent")
+	}
+
+	return nil
+}
+
+func getInt(key map[int64]interface{}, label int64) (int64, bool) {
+	switch value := key[label].(type) {
+	case int64:
+		return value, true
+	case uint64:
+		if value > 1<<62 {
+			return 0, false
+		}
+		return int64(value), true
+	default:
+		return 0, false
+	}
+}
+
+func getBytes(key map[int64]interface{}, label int64) ([]byte, bool) {
+	value, ok := key[label].([]byte)
+	return value, ok && len(value) > 0
+}
@@ -0,0 +1,96 @@
+package cose
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"testing"
+)
+
+func createEC2Key(t *testing.T) webauthncose.EC2PublicKeyData {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	return webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  curveP256,
+		XCoord: privateKey.X.FillBytes(make([]byte, 32)),
+		YCoord: privateKey.Y.FillBytes(make([]byte, 32)),
+	}
+}
+
+func marshal(t *testing.T, key interface{}) []byte {
+	keyBytes, err := webauthncbor.Marshal(key)
+	require.NoError(t, err)
+
+	return keyBytes
+}
+
+func TestValidatePublicKey_EC2(t *testing.T) {
+	assert.NoError(t, ValidatePublicKey(marshal(t, createEC2Key(t))))
+}
+
+func TestValidatePublicKey_EC2NotOnCurve(t *testing.T) {
+	key := createEC2Key(t)
+	key.YCoord[31] ^= 0x01
+
+	assert.Error(t, ValidatePublicKey(marshal(t, key)))
+}
+
+func TestValidatePublicKey_EC2CurveMismatch(t *testing.T) {
+	key := createEC2Key(t)
+	key.Algorithm = int64(webauthncose.AlgES384)
+
+	assert.Error(t, ValidatePublicKey(marshal(t, key)))
+}
+
+func TestValidatePublicKey_OKP(t *testing.T) {
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	key := map[int64]interface{}{
+		keyTypeLabel:   int64(webauthncose.OctetKey),
+		algorithmLabel: int64(webauthncose.AlgEdDSA),
+		curveLabel:     curveEd25519,
+		xLabel:         []byte(publicKey),
+	}
+
+	assert.NoError(t, ValidatePublicKey(marshal(t, key)))
+}
+
+func createRSAKey(t *testing.T, bits int) webauthncose.RSAPublicKeyData {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	require.NoError(t, err)
+
+	return webauthncose.RSAPublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.RSAKey),
+			Algorithm: int64(webauthncose.AlgRS256),
+		},
+		Modulus:  privateKey.N.Bytes(),
+		Exponent: big.NewInt(int64(privateKey.E)).Bytes(),
+	}
+}
+
+func TestValidatePublicKey_RSA(t *testing.T) {
+	assert.NoError(t, ValidatePublicKey(marshal(t, createRSAKey(t, 2048))))
+}
+
+func TestValidatePublicKey_RSATooShort(t *testing.T) {
+	assert.Error(t, ValidatePublicKey(marshal(t, createRSAKey(t, 1024))))
+}
+
+func TestValidatePublicKey_Malformed(t *testing.T) {
+	assert.Error(t, ValidatePublicKey(nil))
+	assert.Error(t, ValidatePublicKey([]byte("not a cose key")))
+}
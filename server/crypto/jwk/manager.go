@@ -1,12 +1,15 @@
 package jwk
 
 import (
+	"context"
 	"encoding/json"
 	"github.com/gofrs/uuid"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/teamhanko/passkey-server/crypto/aes_gcm"
+	"github.com/teamhanko/passkey-server/metrics"
 	"github.com/teamhanko/passkey-server/persistence/models"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/tracing"
 	"time"
 )
 
@@ -20,17 +23,20 @@ type Manager interface {
 }
 
 type DefaultManager struct {
+	ctx       context.Context
 	encrypter *aes_gcm.AESGCM
 	persister persisters.JwkPersister
 }
 
 // NewDefaultManager returns a DefaultManager that reads and persists the jwks to database and generates jwks if a new secret gets added to the config.
-func NewDefaultManager(keys []string, tenantId uuid.UUID, persister persisters.JwkPersister) (Manager, error) {
+// The decryption of keys is traced as part of the span in the context.
+func NewDefaultManager(ctx context.Context, keys []string, tenantId uuid.UUID, persister persisters.JwkPersister) (Manager, error) {
 	encrypter, err := aes_gcm.NewAESGCM(keys)
 	if err != nil {
 		return nil, err
 	}
 	manager := &DefaultManager{
+		ctx:       ctx,
 		encrypter: encrypter,
 		persister: persister,
 	}
@@ -89,16 +95,8 @@ func (m *DefaultManager) GetSigningKey(tenantId uuid.UUID) (jwk.Key, error) {
 	if err != nil {
 		return nil, err
 	}
-	k, err := m.encrypter.Decrypt(sigModel.KeyData)
-	if err != nil {
-		return nil, err
-	}
 
-	key, err := jwk.ParseKey(k)
-	if err != nil {
-		return nil, err
-	}
-	return key, nil
+	return m.decrypt(tenantId, sigModel.KeyData)
 }
 
 func (m *DefaultManager) GetPublicKeys(tenantId uuid.UUID) (jwk.Set, error) {
@@ -109,13 +107,7 @@ func (m *DefaultManager) GetPublicKeys(tenantId uuid.UUID) (jwk.Set, error) {
 
 	publicKeys := jwk.NewSet()
 	for _, model := range modelList {
-		k, err := m.encrypter.Decrypt(model.KeyData)
-		if err != nil {
-			return nil, err
-		}
-
-		key, err := jwk.ParseKey(k)
-
+		key, err := m.decrypt(tenantId, model.KeyData)
 		if err != nil {
 			return nil, err
 		}
@@ -132,3 +124,16 @@ func (m *DefaultManager) GetPublicKeys(tenantId uuid.UUID) (jwk.Set, error) {
 
 	return publicKeys, nil
 }
+
+func (m *DefaultManager) decrypt(tenantId uuid.UUID, keyData string) (key jwk.Key, err error) {
+	_, span := tracing.Start(m.ctx, "jwk.Decrypt", tracing.TenantId(tenantId))
+	defer func() { tracing.End(span, err) }()
+	defer metrics.ObserveJwkDecryption(tenantId, time.Now())
+
+	k, err := m.encrypter.Decrypt(keyData)
+	if err != nil {
+		return nil, err
+	}
+
+	return jwk.ParseKey(k)
+}
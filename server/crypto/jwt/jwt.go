@@ -14,23 +14,82 @@ import (
 type Generator interface {
 	Sign(jwt.Token) ([]byte, error)
 	Verify([]byte) (jwt.Token, error)
-	Generate(userId string, credentialId string) (string, error)
-	GenerateForTransaction(userId string, credentialId string, transactionIdentifier string) (string, error)
+	Generate(userId string, credentialId string, authentication Authentication) (string, error)
+	GenerateForTransaction(userId string, credentialId string, transactionIdentifier string, authentication Authentication) (string, error)
+	GenerateMfaRequired(userId string, credentialId string, tokenId string, authentication Authentication) (string, error)
+	VerifyMfaRequired(token string, userId string) (*Authentication, string, error)
 }
 
 const (
 	JwtExpirationDuration = 300 // 5 Min from Creation to Expire
 )
 
+const (
+	// AmrPasskey is set when the user authenticated with a passkey
+	AmrPasskey = "passkey"
+	// AmrMfa is set when the user authenticated with a MFA credential
+	AmrMfa = "mfa"
+	// AmrUserVerification is set when the authenticator verified the user (e.g. by PIN or biometrics)
+	AmrUserVerification = "uv"
+
+	// AcrUserVerified is the assurance level of an authentication with user verification
+	AcrUserVerified = "uv"
+	// AcrUserPresent is the assurance level of an authentication with user presence only
+	AcrUserPresent = "up"
+	// AcrMultiFactor is the assurance level of an authentication with a passkey and a MFA credential
+	AcrMultiFactor = "mfa"
+
+	// MfaRequiredState marks an intermediate token which has to be completed with a MFA login
+	MfaRequiredState = "mfa_required"
+)
+
+// Authentication describes how the user authenticated and is added to the token as 'amr', 'acr' and 'auth_time' claims
+type Authentication struct {
+	UserVerified bool
+	IsMFA        bool
+	AuthTime     time.Time
+	// PrimaryFactor is set when the authentication completes a login which required a second factor
+	PrimaryFactor *Authentication
+}
+
+func (a Authentication) methods() []string {
+	var methods []string
+	if a.PrimaryFactor != nil || !a.IsMFA {
+		methods = append(methods, AmrPasskey)
+	}
+
+	if a.IsMFA {
+		methods = append(methods, AmrMfa)
+	}
+
+	if a.UserVerified || (a.PrimaryFactor != nil && a.PrimaryFactor.UserVerified) {
+		methods = append(methods, AmrUserVerification)
+	}
+
+	return methods
+}
+
+func (a Authentication) level() string {
+	if a.PrimaryFactor != nil {
+		return AcrMultiFactor
+	}
+
+	if a.UserVerified {
+		return AcrUserVerified
+	}
+
+	return AcrUserPresent
+}
+
 // Generator is used to sign and verify JWTs
 type generator struct {
 	signatureKey jwk.Key
 	verKeys      jwk.Set
-	config       *models.WebauthnConfig
+	relyingParty *models.RelyingParty
 }
 
 // NewGenerator returns a new jwt generator which signs JWTs with the given signing key and verifies JWTs with the given verificationKeys
-func NewGenerator(cfg *models.WebauthnConfig, jwkManager hankoJwk.Manager, tenantId uuid.UUID) (Generator, error) {
+func NewGenerator(relyingParty *models.RelyingParty, jwkManager hankoJwk.Manager, tenantId uuid.UUID) (Generator, error) {
 	signatureKey, err := jwkManager.GetSigningKey(tenantId)
 	const jwkGenFailure = "failed to create jwk jwtGenerator: %w"
 	if err != nil {
@@ -49,7 +108,7 @@ func NewGenerator(cfg *models.WebauthnConfig, jwkManager hankoJwk.Manager, tenan
 	return &generator{
 		signatureKey: signatureKey,
 		verKeys:      pubKeySet,
-		config:       cfg,
+		relyingParty: relyingParty,
 	}, nil
 }
 
@@ -71,7 +130,7 @@ func (g *generator) Verify(signed []byte) (jwt.Token, error) {
 	return token, nil
 }
 
-func (g *generator) generateDefaultToken(userId string, credentialId string) jwt.Token {
+func (g *generator) generateDefaultToken(userId string, credentialId string, authentication Authentication) jwt.Token {
 	issuedAt := time.Now()
 	expiresAt := issuedAt.Add(time.Second * JwtExpirationDuration)
 
@@ -79,8 +138,11 @@ func (g *generator) generateDefaultToken(userId string, credentialId string) jwt
 	_ = token.Set(jwt.SubjectKey, userId)
 	_ = token.Set(jwt.IssuedAtKey, issuedAt)
 	_ = token.Set(jwt.ExpirationKey, expiresAt)
-	_ = token.Set(jwt.AudienceKey, []string{g.config.RelyingParty.RPId})
+	_ = token.Set(jwt.AudienceKey, []string{g.relyingParty.RPId})
 	_ = token.Set("cred", credentialId)
+	_ = token.Set("amr", authentication.methods())
+	_ = token.Set("acr", authentication.level())
+	_ = token.Set("auth_time", authentication.AuthTime.Unix())
 
 	return token
 }
@@ -94,13 +156,62 @@ func (g *generator) signToken(token jwt.Token) (string, error) {
 	return string(signed), nil
 }
 
-func (g *generator) Generate(userId string, credentialId string) (string, error) {
-	token := g.generateDefaultToken(userId, credentialId)
+func (g *generator) Generate(userId string, credentialId string, authentication Authentication) (string, error) {
+	token := g.generateDefaultToken(userId, credentialId, authentication)
 	return g.signToken(token)
 }
 
-func (g *generator) GenerateForTransaction(userId string, credentialId string, transactionIdentifier string) (string, error) {
-	token := g.generateDefaultToken(userId, credentialId)
+// GenerateMfaRequired creates an intermediate token for a successful passkey login of a user which also has to
+// authenticate with a MFA credential. The token is not valid for the audience of the relying party. The token id is
+// stored by the caller, so the token can only be used once.
+func (g *generator) GenerateMfaRequired(userId string, credentialId string, tokenId string, authentication Authentication) (string, error) {
+	token := g.generateDefaultToken(userId, credentialId, authentication)
+	_ = token.Set(jwt.JwtIDKey, tokenId)
+	_ = token.Set(jwt.AudienceKey, []string{MfaRequiredState})
+	_ = token.Set("state", MfaRequiredState)
+
+	return g.signToken(token)
+}
+
+// VerifyMfaRequired verifies an intermediate token created by GenerateMfaRequired for the given user and returns
+// the authentication of the first factor and the id of the token
+func (g *generator) VerifyMfaRequired(signed string, userId string) (*Authentication, string, error) {
+	token, err := g.Verify([]byte(signed))
+	if err != nil {
+		return nil, "", err
+	}
+
+	err = jwt.Validate(token, jwt.WithAudience(MfaRequiredState), jwt.WithSubject(userId), jwt.WithClaimValue("state", MfaRequiredState))
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid mfa token: %w", err)
+	}
+
+	if token.JwtID() == "" {
+		return nil, "", fmt.Errorf("invalid mfa token: the token id is missing")
+	}
+
+	authentication := &Authentication{}
+	if authTime, ok := token.Get("auth_time"); ok {
+		if unixTime, ok := authTime.(float64); ok {
+			authentication.AuthTime = time.Unix(int64(unixTime), 0)
+		}
+	}
+
+	if methods, ok := token.Get("amr"); ok {
+		if methodList, ok := methods.([]interface{}); ok {
+			for _, method := range methodList {
+				if method == AmrUserVerification {
+					authentication.UserVerified = true
+				}
+			}
+		}
+	}
+
+	return authentication, token.JwtID(), nil
+}
+
+func (g *generator) GenerateForTransaction(userId string, credentialId string, transactionIdentifier string, authentication Authentication) (string, error) {
+	token := g.generateDefaultToken(userId, credentialId, authentication)
 	_ = token.Set("trans", transactionIdentifier)
 
 	return g.signToken(token)
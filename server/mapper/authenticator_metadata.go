@@ -22,9 +22,17 @@ type Authenticator struct {
 type AuthenticatorMetadata map[string]Authenticator
 
 func (w AuthenticatorMetadata) GetNameForAaguid(aaguid uuid.UUID) *string {
+	if authenticator := w.GetForAaguid(aaguid); authenticator != nil {
+		return &authenticator.Name
+	}
+
+	return nil
+}
+
+func (w AuthenticatorMetadata) GetForAaguid(aaguid uuid.UUID) *Authenticator {
 	if w != nil {
-		if webauthnAaguid, ok := w[aaguid.String()]; ok {
-			return &webauthnAaguid.Name
+		if authenticator, ok := w[aaguid.String()]; ok {
+			return &authenticator
 		}
 	}
 
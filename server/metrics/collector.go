@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+)
+
+// storeCacheTTL is how long the counts of the database are reused, so frequent scrapes or several Prometheus servers
+// do not query the whole tables every time
+const storeCacheTTL = time.Minute
+
+var (
+	sessionDataDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "webauthn", "session_data"),
+		"Number of stored session data of ceremonies which were initialized but not finalized.",
+		[]string{"tenant", "operation"}, nil,
+	)
+
+	credentialsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "webauthn", "credentials"),
+		"Number of registered credentials by authenticator.",
+		[]string{"tenant", "authenticator"}, nil,
+	)
+)
+
+// storeCollector counts the session data and the credentials in the database. The counts are cached for the
+// storeCacheTTL, failed queries are not cached.
+type storeCollector struct {
+	statsPersister        persisters.StatsPersister
+	authenticatorMetadata mapper.AuthenticatorMetadata
+	labeler               *tenantLabeler
+
+	mutex                sync.Mutex
+	now                  func() time.Time
+	sessionData          []models.SessionDataCount
+	sessionDataFetchedAt time.Time
+	credentials          []models.CredentialAaguidCount
+	credentialsFetchedAt time.Time
+}
+
+func newStoreCollector(statsPersister persisters.StatsPersister, authenticatorMetadata mapper.AuthenticatorMetadata, labeler *tenantLabeler) *storeCollector {
+	return &storeCollector{
+		statsPersister:        statsPersister,
+		authenticatorMetadata: authenticatorMetadata,
+		labeler:               labeler,
+		now:                   time.Now,
+	}
+}
+
+func (c *storeCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- sessionDataDesc
+	descs <- credentialsDesc
+}
+
+func (c *storeCollector) Collect(metrics chan<- prometheus.Metric) {
+	c.collectSessionData(metrics)
+	c.collectCredentials(metrics)
+}
+
+func (c *storeCollector) countSessionData() ([]models.SessionDataCount, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.sessionData != nil && c.now().Sub(c.sessionDataFetchedAt) < storeCacheTTL {
+		return c.sessionData, nil
+	}
+
+	counts, err := c.statsPersister.CountSessionData()
+	if err != nil {
+		return nil, err
+	}
+
+	c.sessionData = counts
+	c.sessionDataFetchedAt = c.now()
+	return counts, nil
+}
+
+func (c *storeCollector) countCredentials() ([]models.CredentialAaguidCount, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.credentials != nil && c.now().Sub(c.credentialsFetchedAt) < storeCacheTTL {
+		return c.credentials, nil
+	}
+
+	counts, err := c.statsPersister.CountAllCredentialsByAaguid()
+	if err != nil {
+		return nil, err
+	}
+
+	c.credentials = counts
+	c.credentialsFetchedAt = c.now()
+	return counts, nil
+}
+
+func (c *storeCollector) collectSessionData(metrics chan<- prometheus.Metric) {
+	counts, err := c.countSessionData()
+	if err != nil {
+		metrics <- prometheus.NewInvalidMetric(sessionDataDesc, err)
+		return
+	}
+
+	// several tenants share a label if the tenant label is limited, so their counts are summed up
+	sums := make(map[[2]string]int)
+	for _, count := range counts {
+		sums[[2]string{c.labeler.label(count.TenantID), string(count.Operation)}] += count.Count
+	}
+
+	for labels, sum := range sums {
+		metrics <- prometheus.MustNewConstMetric(sessionDataDesc, prometheus.GaugeValue, float64(sum), labels[0], labels[1])
+	}
+}
+
+func (c *storeCollector) collectCredentials(metrics chan<- prometheus.Metric) {
+	counts, err := c.countCredentials()
+	if err != nil {
+		metrics <- prometheus.NewInvalidMetric(credentialsDesc, err)
+		return
+	}
+
+	sums := make(map[[2]string]int)
+	for _, count := range counts {
+		authenticator := count.AAGUID.String()
+		if name := c.authenticatorMetadata.GetNameForAaguid(count.AAGUID); name != nil {
+			authenticator = *name
+		}
+
+		sums[[2]string{c.labeler.label(count.TenantID), authenticator}] += count.Count
+	}
+
+	for labels, sum := range sums {
+		metrics <- prometheus.MustNewConstMetric(credentialsDesc, prometheus.GaugeValue, float64(sum), labels[0], labels[1])
+	}
+}
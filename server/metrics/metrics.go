@@ -0,0 +1,228 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+)
+
+const namespace = "hanko"
+
+const (
+	CeremonyRegistration      = "registration"
+	CeremonyAuthentication    = "authentication"
+	CeremonyTransaction       = "transaction"
+	CeremonyMfaRegistration   = "mfa_registration"
+	CeremonyMfaAuthentication = "mfa_authentication"
+
+	StepInit     = "init"
+	StepFinalize = "finalize"
+
+	ResultSucceeded   = "succeeded"
+	ResultFailed      = "failed"
+	ResultMfaRequired = "mfa_required"
+
+	ErrorClassBadRequest   = "bad_request"
+	ErrorClassUnauthorized = "unauthorized"
+	ErrorClassForbidden    = "forbidden"
+	ErrorClassNotFound     = "not_found"
+	ErrorClassConflict     = "conflict"
+	ErrorClassClient       = "client"
+	ErrorClassInternal     = "internal"
+	ErrorClassUserDisabled = "user_disabled"
+
+	// TenantAll is the tenant label of all metrics if the tenant label is disabled
+	TenantAll = "all"
+	// TenantOther is the tenant label of tenants seen after the maximum number of tenant labels was reached
+	TenantOther = "other"
+)
+
+type ceremonyStep struct {
+	ceremony string
+	step     string
+	result   string
+}
+
+var ceremonySteps = map[models.AuditLogType]ceremonyStep{
+	models.AuditLogWebAuthnRegistrationInitSucceeded:    {CeremonyRegistration, StepInit, ResultSucceeded},
+	models.AuditLogWebAuthnRegistrationInitFailed:       {CeremonyRegistration, StepInit, ResultFailed},
+	models.AuditLogWebAuthnRegistrationFinalSucceeded:   {CeremonyRegistration, StepFinalize, ResultSucceeded},
+	models.AuditLogWebAuthnRegistrationFinalFailed:      {CeremonyRegistration, StepFinalize, ResultFailed},
+	models.AuditLogWebAuthnAuthenticationInitSucceeded:  {CeremonyAuthentication, StepInit, ResultSucceeded},
+	models.AuditLogWebAuthnAuthenticationInitFailed:     {CeremonyAuthentication, StepInit, ResultFailed},
+	models.AuditLogWebAuthnAuthenticationFinalSucceeded: {CeremonyAuthentication, StepFinalize, ResultSucceeded},
+	models.AuditLogWebAuthnAuthenticationFinalFailed:    {CeremonyAuthentication, StepFinalize, ResultFailed},
+	models.AuditLogWebAuthnAuthenticationMfaRequired:    {CeremonyAuthentication, StepFinalize, ResultMfaRequired},
+	models.AuditLogWebAuthnTransactionInitSucceeded:     {CeremonyTransaction, StepInit, ResultSucceeded},
+	models.AuditLogWebAuthnTransactionInitFailed:        {CeremonyTransaction, StepInit, ResultFailed},
+	models.AuditLogWebAuthnTransactionFinalSucceeded:    {CeremonyTransaction, StepFinalize, ResultSucceeded},
+	models.AuditLogWebAuthnTransactionFinalFailed:       {CeremonyTransaction, StepFinalize, ResultFailed},
+	models.AuditLogMfaRegistrationInitSucceeded:         {CeremonyMfaRegistration, StepInit, ResultSucceeded},
+	models.AuditLogMfaRegistrationInitFailed:            {CeremonyMfaRegistration, StepInit, ResultFailed},
+	models.AuditLogMfaRegistrationFinalSucceeded:        {CeremonyMfaRegistration, StepFinalize, ResultSucceeded},
+	models.AuditLogMfaRegistrationFinalFailed:           {CeremonyMfaRegistration, StepFinalize, ResultFailed},
+	models.AuditLogMfaAuthenticationInitSucceeded:       {CeremonyMfaAuthentication, StepInit, ResultSucceeded},
+	models.AuditLogMfaAuthenticationInitFailed:          {CeremonyMfaAuthentication, StepInit, ResultFailed},
+	models.AuditLogMfaAuthenticationFinalSucceeded:      {CeremonyMfaAuthentication, StepFinalize, ResultSucceeded},
+	models.AuditLogMfaAuthenticationFinalFailed:         {CeremonyMfaAuthentication, StepFinalize, ResultFailed},
+}
+
+var (
+	ceremonies = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "webauthn",
+		Name:      "ceremonies_total",
+		Help:      "Number of ceremony steps by result and class of the error.",
+	}, []string{"tenant", "ceremony", "step", "result", "error_class"})
+
+	validationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "webauthn",
+		Name:      "validation_duration_seconds",
+		Help:      "Duration of the validation of attestations and assertions.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"tenant", "ceremony"})
+
+	jwkDecryptionDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "jwk",
+		Name:      "decryption_duration_seconds",
+		Help:      "Duration of the decryption of a JWK.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 8),
+	}, []string{"tenant"})
+)
+
+// labels is nil until the metrics are registered, so nothing is recorded if the metrics are disabled
+var labels atomic.Pointer[tenantLabeler]
+
+// Register registers the metrics at the default Prometheus registerer and starts recording them. The stored session
+// data and the credentials are counted on every scrape.
+func Register(cfg config.Metrics, statsPersister persisters.StatsPersister, authenticatorMetadata mapper.AuthenticatorMetadata) error {
+	labeler := newTenantLabeler(cfg)
+	collectors := []prometheus.Collector{
+		ceremonies,
+		validationDuration,
+		jwkDecryptionDuration,
+		newStoreCollector(statsPersister, authenticatorMetadata, labeler),
+	}
+
+	for _, collector := range collectors {
+		err := prometheus.Register(collector)
+		if err != nil {
+			return fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+
+	labels.Store(labeler)
+
+	return nil
+}
+
+// ObserveCeremony counts the ceremony step of the audit log type. Other audit log types are ignored.
+func ObserveCeremony(tenantId uuid.UUID, auditLogType models.AuditLogType, errorClass string) {
+	labeler := labels.Load()
+	step, ok := ceremonySteps[auditLogType]
+	if labeler == nil || !ok {
+		return
+	}
+
+	ceremonies.WithLabelValues(labeler.label(tenantId), step.ceremony, step.step, step.result, errorClass).Inc()
+}
+
+// ObserveValidation records the duration of a validation which was started at the given time
+func ObserveValidation(tenantId uuid.UUID, ceremony string, start time.Time) {
+	labeler := labels.Load()
+	if labeler == nil {
+		return
+	}
+
+	validationDuration.WithLabelValues(labeler.label(tenantId), ceremony).Observe(time.Since(start).Seconds())
+}
+
+// ObserveJwkDecryption records the duration of a decryption which was started at the given time
+func ObserveJwkDecryption(tenantId uuid.UUID, start time.Time) {
+	labeler := labels.Load()
+	if labeler == nil {
+		return
+	}
+
+	jwkDecryptionDuration.WithLabelValues(labeler.label(tenantId)).Observe(time.Since(start).Seconds())
+}
+
+// ErrorClass returns the class of the error by its HTTP status. Errors without status are internal errors.
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var httpError *echo.HTTPError
+	if !errors.As(err, &httpError) {
+		return ErrorClassInternal
+	}
+
+	switch httpError.Code {
+	case http.StatusBadRequest:
+		return ErrorClassBadRequest
+	case http.StatusUnauthorized:
+		return ErrorClassUnauthorized
+	case http.StatusForbidden:
+		return ErrorClassForbidden
+	case http.StatusNotFound:
+		return ErrorClassNotFound
+	case http.StatusConflict:
+		return ErrorClassConflict
+	}
+
+	if httpError.Code < http.StatusInternalServerError {
+		return ErrorClassClient
+	}
+
+	return ErrorClassInternal
+}
+
+// tenantLabeler limits the cardinality of the tenant label
+type tenantLabeler struct {
+	disabled   bool
+	maxTenants int
+
+	mutex   sync.Mutex
+	tenants map[uuid.UUID]struct{}
+}
+
+func newTenantLabeler(cfg config.Metrics) *tenantLabeler {
+	return &tenantLabeler{
+		disabled:   cfg.TenantLabel == config.MetricsTenantLabelNone,
+		maxTenants: cfg.MaxTenants,
+		tenants:    make(map[uuid.UUID]struct{}),
+	}
+}
+
+func (l *tenantLabeler) label(tenantId uuid.UUID) string {
+	if l.disabled {
+		return TenantAll
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if _, ok := l.tenants[tenantId]; !ok {
+		if l.maxTenants > 0 && len(l.tenants) >= l.maxTenants {
+			return TenantOther
+		}
+
+		l.tenants[tenantId] = struct{}{}
+	}
+
+	return tenantId.String()
+}
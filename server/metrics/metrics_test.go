@@ -0,0 +1,159 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/mapper"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/persistence/persisters"
+)
+
+func TestErrorClass(t *testing.T) {
+	assert.Equal(t, "", ErrorClass(nil))
+	assert.Equal(t, ErrorClassInternal, ErrorClass(errors.New("db failed")))
+	assert.Equal(t, ErrorClassUnauthorized, ErrorClass(echo.NewHTTPError(http.StatusUnauthorized, "failed to validate assertion")))
+	assert.Equal(t, ErrorClassBadRequest, ErrorClass(fmt.Errorf("wrapped: %w", echo.NewHTTPError(http.StatusBadRequest))))
+	assert.Equal(t, ErrorClassClient, ErrorClass(echo.NewHTTPError(http.StatusTooManyRequests)))
+	assert.Equal(t, ErrorClassInternal, ErrorClass(echo.NewHTTPError(http.StatusServiceUnavailable)))
+}
+
+func TestTenantLabelLimit(t *testing.T) {
+	labeler := newTenantLabeler(config.Metrics{TenantLabel: config.MetricsTenantLabelId, MaxTenants: 2})
+	first, _ := uuid.NewV4()
+	second, _ := uuid.NewV4()
+	third, _ := uuid.NewV4()
+
+	assert.Equal(t, first.String(), labeler.label(first))
+	assert.Equal(t, second.String(), labeler.label(second))
+	assert.Equal(t, TenantOther, labeler.label(third))
+	assert.Equal(t, first.String(), labeler.label(first))
+}
+
+func TestTenantLabelNone(t *testing.T) {
+	labeler := newTenantLabeler(config.Metrics{TenantLabel: config.MetricsTenantLabelNone})
+	tenantId, _ := uuid.NewV4()
+
+	assert.Equal(t, TenantAll, labeler.label(tenantId))
+}
+
+func TestObserveCeremony(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	counter := ceremonies.WithLabelValues(tenantId.String(), CeremonyAuthentication, StepFinalize, ResultFailed, ErrorClassUnauthorized)
+
+	ObserveCeremony(tenantId, models.AuditLogWebAuthnAuthenticationFinalFailed, ErrorClassUnauthorized)
+	assert.Equal(t, 0.0, counterValue(t, counter), "nothing is recorded before the metrics are registered")
+
+	labels.Store(newTenantLabeler(config.Metrics{TenantLabel: config.MetricsTenantLabelId}))
+	t.Cleanup(func() {
+		labels.Store(nil)
+	})
+
+	ObserveCeremony(tenantId, models.AuditLogWebAuthnAuthenticationFinalFailed, ErrorClassUnauthorized)
+	ObserveCeremony(tenantId, models.AuditLogAdminUserCreated, "")
+	assert.Equal(t, 1.0, counterValue(t, counter))
+}
+
+type fakeStatsPersister struct {
+	persisters.StatsPersister
+	sessionData []models.SessionDataCount
+	credentials []models.CredentialAaguidCount
+	queries     int
+}
+
+func (p *fakeStatsPersister) CountSessionData() ([]models.SessionDataCount, error) {
+	p.queries++
+	return p.sessionData, nil
+}
+
+func (p *fakeStatsPersister) CountAllCredentialsByAaguid() ([]models.CredentialAaguidCount, error) {
+	p.queries++
+	return p.credentials, nil
+}
+
+func TestStoreCollectorSumsTenantsSharingALabel(t *testing.T) {
+	first, _ := uuid.NewV4()
+	second, _ := uuid.NewV4()
+	aaguid, _ := uuid.NewV4()
+	unknownAaguid, _ := uuid.NewV4()
+
+	statsPersister := &fakeStatsPersister{
+		sessionData: []models.SessionDataCount{
+			{TenantID: first, Operation: models.WebauthnOperationAuthentication, Count: 3},
+			{TenantID: second, Operation: models.WebauthnOperationAuthentication, Count: 2},
+		},
+		credentials: []models.CredentialAaguidCount{
+			{TenantID: first, AAGUID: aaguid, Count: 4},
+			{TenantID: second, AAGUID: aaguid, Count: 1},
+			{TenantID: second, AAGUID: unknownAaguid, Count: 7},
+		},
+	}
+	metadata := mapper.AuthenticatorMetadata{aaguid.String(): mapper.Authenticator{Name: "Security Key"}}
+	labeler := newTenantLabeler(config.Metrics{TenantLabel: config.MetricsTenantLabelNone})
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(newStoreCollector(statsPersister, metadata, labeler)))
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			key := family.GetName()
+			for _, label := range metric.GetLabel() {
+				key += "," + label.GetValue()
+			}
+			values[key] = metric.GetGauge().GetValue()
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"hanko_webauthn_session_data,authentication,all":                5,
+		"hanko_webauthn_credentials,Security Key,all":                   5,
+		"hanko_webauthn_credentials," + unknownAaguid.String() + ",all": 7,
+	}, values)
+}
+
+func TestStoreCollectorCachesCounts(t *testing.T) {
+	tenantId, _ := uuid.NewV4()
+	statsPersister := &fakeStatsPersister{
+		sessionData: []models.SessionDataCount{{TenantID: tenantId, Operation: models.WebauthnOperationRegistration, Count: 1}},
+		credentials: []models.CredentialAaguidCount{},
+	}
+	labeler := newTenantLabeler(config.Metrics{TenantLabel: config.MetricsTenantLabelNone})
+	collector := newStoreCollector(statsPersister, mapper.AuthenticatorMetadata{}, labeler)
+	now := time.Now()
+	collector.now = func() time.Time { return now }
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(collector))
+
+	_, err := registry.Gather()
+	require.NoError(t, err)
+	_, err = registry.Gather()
+	require.NoError(t, err)
+	assert.Equal(t, 2, statsPersister.queries)
+
+	now = now.Add(storeCacheTTL)
+	_, err = registry.Gather()
+	require.NoError(t, err)
+	assert.Equal(t, 4, statsPersister.queries)
+}
+
+func counterValue(t *testing.T, counter prometheus.Counter) float64 {
+	var metric dto.Metric
+	require.NoError(t, counter.Write(&metric))
+
+	return metric.GetCounter().GetValue()
+}
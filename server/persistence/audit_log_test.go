@@ -0,0 +1,104 @@
+package persistence_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	auditlog "github.com/teamhanko/passkey-server/audit_log"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+// newTestDatabase connects to the database in PASSKEY_TEST_DATABASE_URL, e.g.
+// 'mysql://passkey:passkey@(localhost:3306)/passkey?parseTime=true', and migrates it
+func newTestDatabase(t *testing.T) persistence.Database {
+	url := os.Getenv("PASSKEY_TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("PASSKEY_TEST_DATABASE_URL is not set")
+	}
+
+	database, err := persistence.NewDatabase(config.Database{Url: url})
+	require.NoError(t, err)
+	require.NoError(t, database.MigrateUp())
+
+	return database
+}
+
+func TestAuditLogChainRoundTrip(t *testing.T) {
+	database := newTestDatabase(t)
+
+	tenantId, _ := uuid.NewV4()
+	now := time.Now().UTC()
+	tenant := &models.Tenant{ID: tenantId, DisplayName: "Round Trip", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, database.GetTenantPersister(nil).Create(tenant))
+	t.Cleanup(func() {
+		_ = database.GetTenantPersister(nil).Delete(tenant)
+	})
+
+	auditLogPersister := database.GetAuditLogPersister(nil)
+	for _, fraction := range []time.Duration{100 * time.Millisecond, 700 * time.Millisecond} {
+		id, _ := uuid.NewV4()
+		auditLog := &models.AuditLog{
+			ID:           id,
+			TenantID:     &tenantId,
+			Type:         models.AuditLogWebAuthnAuthenticationFinalSucceeded,
+			MetaSourceIp: "127.0.0.1",
+			// databases which do not store fractions of seconds may round them up
+			CreatedAt: now.Truncate(time.Second).Add(fraction),
+		}
+		require.NoError(t, auditLogPersister.Create(auditLog))
+
+		stored, err := auditLogPersister.GetBySequence(&tenantId, *auditLog.Sequence)
+		require.NoError(t, err)
+		require.NotNil(t, stored)
+		assert.True(t, auditLog.CreatedAt.Equal(stored.CreatedAt))
+
+		contentHash, err := stored.CalculateContentHash()
+		require.NoError(t, err)
+		assert.Equal(t, *auditLog.ContentHash, contentHash)
+	}
+
+	verification, err := auditlog.VerifyChain(database, tenant, nil, nil)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid)
+	assert.Equal(t, 2, verification.CheckedEntries)
+}
+
+func TestAuditLogListForUserWithDeletedCredential(t *testing.T) {
+	database := newTestDatabase(t)
+
+	tenantId, _ := uuid.NewV4()
+	now := time.Now().UTC()
+	tenant := &models.Tenant{ID: tenantId, DisplayName: "List For User", CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, database.GetTenantPersister(nil).Create(tenant))
+	t.Cleanup(func() {
+		_ = database.GetTenantPersister(nil).Delete(tenant)
+	})
+
+	userId := "user-1"
+	credentialId := "deleted-credential"
+	targetType := string(models.AuditLogTargetCredential)
+	auditLogPersister := database.GetAuditLogPersister(nil)
+	for _, actorUserId := range []*string{nil, &userId} {
+		id, _ := uuid.NewV4()
+		require.NoError(t, auditLogPersister.Create(&models.AuditLog{
+			ID:           id,
+			TenantID:     &tenantId,
+			Type:         models.AuditLogAdminCredentialDeleted,
+			MetaSourceIp: "127.0.0.1",
+			ActorUserId:  actorUserId,
+			TargetType:   &targetType,
+			TargetId:     &credentialId,
+			CreatedAt:    now,
+		}))
+	}
+
+	auditLogs, err := auditLogPersister.ListForUser(tenantId, userId, nil)
+	require.NoError(t, err)
+	assert.Len(t, auditLogs, 2)
+}
@@ -0,0 +1,3 @@
+DROP INDEX webauthn_users_tenant_id_display_name_pattern_idx;
+DROP INDEX webauthn_users_tenant_id_name_pattern_idx;
+DROP INDEX webauthn_users_tenant_id_user_id_pattern_idx;
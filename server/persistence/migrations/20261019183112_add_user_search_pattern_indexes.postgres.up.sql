@@ -0,0 +1,4 @@
+-- LIKE prefix queries can only use btree indexes with the pattern operator classes on non-C collations
+CREATE INDEX webauthn_users_tenant_id_user_id_pattern_idx ON webauthn_users (tenant_id, user_id text_pattern_ops);
+CREATE INDEX webauthn_users_tenant_id_name_pattern_idx ON webauthn_users (tenant_id, name text_pattern_ops);
+CREATE INDEX webauthn_users_tenant_id_display_name_pattern_idx ON webauthn_users (tenant_id, display_name text_pattern_ops);
@@ -1,6 +1,14 @@
 package models
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/gofrs/uuid"
@@ -16,9 +24,216 @@ type AuditLog struct {
 	ActorUserId       *string      `db:"actor_user_id" json:"actor_user_id,omitempty"`
 	CreatedAt         time.Time    `db:"created_at" json:"created_at"`
 	UpdatedAt         time.Time    `db:"updated_at" json:"updated_at"`
-	Tenant            *Tenant      `json:"-" belongs_to:"tenants"`
-	TenantID          uuid.UUID    `json:"tenant_id" db:"tenant_id"`
-	TransactionId     *string      `json:"transaction_id" db:"transaction_id"`
+	// TenantID is empty for admin operations which outlive the tenant, e.g. the deletion of a tenant
+	TenantID      *uuid.UUID       `json:"tenant_id,omitempty" db:"tenant_id"`
+	TransactionId *string          `json:"transaction_id" db:"transaction_id"`
+	Actor         *string          `json:"actor,omitempty" db:"actor"`
+	TargetType    *string          `json:"target_type,omitempty" db:"target_type"`
+	TargetId      *string          `json:"target_id,omitempty" db:"target_id"`
+	Details       *AuditLogDetails `json:"details,omitempty" db:"details"`
+	// Sequence, ContentHash, PreviousHash and Hash chain the entries of a tenant. They are empty for entries created
+	// before the chain was introduced.
+	Sequence     *int    `json:"sequence,omitempty" db:"sequence"`
+	ContentHash  *string `json:"content_hash,omitempty" db:"content_hash"`
+	PreviousHash *string `json:"previous_hash,omitempty" db:"previous_hash"`
+	Hash         *string `json:"hash,omitempty" db:"hash"`
+	// MetaBrowser, MetaOs and MetaDevice are parsed from the user agent. The location of the source IP is only
+	// looked up if a GeoIP database is configured.
+	MetaBrowser        *string `json:"meta_browser,omitempty" db:"meta_browser"`
+	MetaOs             *string `json:"meta_os,omitempty" db:"meta_os"`
+	MetaDevice         *string `json:"meta_device,omitempty" db:"meta_device"`
+	MetaCountry        *string `json:"meta_country,omitempty" db:"meta_country"`
+	MetaCity           *string `json:"meta_city,omitempty" db:"meta_city"`
+	MetaAsn            *int    `json:"meta_asn,omitempty" db:"meta_asn"`
+	MetaAsOrganization *string `json:"meta_as_organization,omitempty" db:"meta_as_organization"`
+	// PersonalHash covers the personal data of the entry, salted with the PersonalSalt. The content hash only covers
+	// the personal hash, so the erasure of a user can remove the personal data and the salt, while all other fields of
+	// the entry can still be verified.
+	PersonalHash *string `json:"personal_hash,omitempty" db:"personal_hash"`
+	PersonalSalt *string `json:"-" db:"personal_salt"`
+	// PseudonymizedAt is set when the personal data of the entry was removed by the erasure of its user. The personal
+	// data of such entries can no longer be verified.
+	PseudonymizedAt *time.Time `json:"pseudonymized_at,omitempty" db:"pseudonymized_at"`
+}
+
+// auditLogContent contains all fields of an entry which are covered by the content hash. The personal data is only
+// covered by its hash. New fields must be omitted when empty, so the hashes of existing entries stay valid.
+type auditLogContent struct {
+	ID                uuid.UUID    `json:"id"`
+	TenantID          *uuid.UUID   `json:"tenant_id"`
+	Type              AuditLogType `json:"type"`
+	Error             *string      `json:"error"`
+	MetaHttpRequestId string       `json:"meta_http_request_id"`
+	TransactionId     *string      `json:"transaction_id"`
+	Actor             *string      `json:"actor"`
+	TargetType        *string      `json:"target_type"`
+	TargetId          *string      `json:"target_id"`
+	CreatedAt         int64        `json:"created_at"`
+	MetaBrowser       *string      `json:"meta_browser,omitempty"`
+	MetaOs            *string      `json:"meta_os,omitempty"`
+	MetaDevice        *string      `json:"meta_device,omitempty"`
+	MetaCountry       *string      `json:"meta_country,omitempty"`
+	PersonalHash      *string      `json:"personal_hash"`
+}
+
+// auditLogPersonalContent contains the personal data of an entry, which is removed by the erasure of its user
+type auditLogPersonalContent struct {
+	ActorUserId        *string          `json:"actor_user_id"`
+	TargetId           *string          `json:"target_id"`
+	MetaSourceIp       string           `json:"meta_source_ip"`
+	MetaUserAgent      string           `json:"meta_user_agent"`
+	MetaCity           *string          `json:"meta_city,omitempty"`
+	MetaAsn            *int             `json:"meta_asn,omitempty"`
+	MetaAsOrganization *string          `json:"meta_as_organization,omitempty"`
+	Details            *AuditLogDetails `json:"details"`
+}
+
+// SealPersonalData salts and hashes the personal data of a new entry. Must be called before the content hash is
+// calculated.
+func (auditLog *AuditLog) SealPersonalData() error {
+	salt := make([]byte, 32)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return fmt.Errorf("failed to create personal data salt: %w", err)
+	}
+
+	encodedSalt := hex.EncodeToString(salt)
+	auditLog.PersonalSalt = &encodedSalt
+
+	personalHash, err := auditLog.CalculatePersonalHash()
+	if err != nil {
+		return err
+	}
+	auditLog.PersonalHash = &personalHash
+
+	return nil
+}
+
+// CalculatePersonalHash hashes the personal data of the entry with its salt. The salt prevents guessing the personal
+// data from the hash after the entry was pseudonymized.
+func (auditLog *AuditLog) CalculatePersonalHash() (string, error) {
+	if auditLog.PersonalSalt == nil {
+		return "", errors.New("the personal data salt of the audit log is missing")
+	}
+
+	content := auditLogPersonalContent{
+		ActorUserId:        auditLog.ActorUserId,
+		MetaSourceIp:       auditLog.MetaSourceIp,
+		MetaUserAgent:      auditLog.MetaUserAgent,
+		MetaCity:           auditLog.MetaCity,
+		MetaAsn:            auditLog.MetaAsn,
+		MetaAsOrganization: auditLog.MetaAsOrganization,
+		Details:            auditLog.Details,
+	}
+	if auditLog.TargetsUser() {
+		content.TargetId = auditLog.TargetId
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize audit log personal data: %w", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(*auditLog.PersonalSalt))
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CalculateContentHash hashes the content of the entry. The creation time is only covered with a precision of
+// seconds, as not all databases store fractions of seconds.
+func (auditLog *AuditLog) CalculateContentHash() (string, error) {
+	content := auditLogContent{
+		ID:                auditLog.ID,
+		TenantID:          auditLog.TenantID,
+		Type:              auditLog.Type,
+		Error:             auditLog.Error,
+		MetaHttpRequestId: auditLog.MetaHttpRequestId,
+		TransactionId:     auditLog.TransactionId,
+		Actor:             auditLog.Actor,
+		TargetType:        auditLog.TargetType,
+		CreatedAt:         auditLog.CreatedAt.Unix(),
+		MetaBrowser:       auditLog.MetaBrowser,
+		MetaOs:            auditLog.MetaOs,
+		MetaDevice:        auditLog.MetaDevice,
+		MetaCountry:       auditLog.MetaCountry,
+		PersonalHash:      auditLog.PersonalHash,
+	}
+	// the id of a user as target is personal data, the ids of other targets are not
+	if !auditLog.TargetsUser() {
+		content.TargetId = auditLog.TargetId
+	}
+
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize audit log content: %w", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// TargetsUser reports whether the entry describes an admin operation on a user
+func (auditLog *AuditLog) TargetsUser() bool {
+	return auditLog.TargetType != nil && *auditLog.TargetType == string(AuditLogTargetUser)
+}
+
+// CalculateHash links the content hash of the entry to its predecessor in the chain
+func (auditLog *AuditLog) CalculateHash() string {
+	sequence, contentHash, previousHash := 0, "", ""
+	if auditLog.Sequence != nil {
+		sequence = *auditLog.Sequence
+	}
+	if auditLog.ContentHash != nil {
+		contentHash = *auditLog.ContentHash
+	}
+	if auditLog.PreviousHash != nil {
+		previousHash = *auditLog.PreviousHash
+	}
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%s:%s", sequence, previousHash, contentHash)))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsChained returns false for entries created before the chain was introduced
+func (auditLog *AuditLog) IsChained() bool {
+	return auditLog.Sequence != nil && auditLog.ContentHash != nil && auditLog.Hash != nil && auditLog.PersonalHash != nil
+}
+
+// AuditLogChange describes a single value changed by an admin operation
+type AuditLogChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// AuditLogDetails summarizes the changes of an admin operation. It is stored as JSON.
+type AuditLogDetails struct {
+	Changes []AuditLogChange `json:"changes"`
+}
+
+func (details AuditLogDetails) Value() (driver.Value, error) {
+	value, err := json.Marshal(details)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize audit log details: %w", err)
+	}
+
+	return string(value), nil
+}
+
+func (details *AuditLogDetails) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported type for audit log details: %T", value)
+	}
+
+	return json.Unmarshal(data, details)
 }
 
 type AuditLogs []AuditLog
@@ -35,9 +250,13 @@ var (
 	AuditLogWebAuthnAuthenticationInitFailed     AuditLogType = "webauthn_authentication_init_failed"
 	AuditLogWebAuthnAuthenticationFinalSucceeded AuditLogType = "webauthn_authentication_final_succeeded"
 	AuditLogWebAuthnAuthenticationFinalFailed    AuditLogType = "webauthn_authentication_final_failed"
+	AuditLogWebAuthnAuthenticationMfaRequired    AuditLogType = "webauthn_authentication_mfa_required"
 
-	AuditLogWebAuthnCredentialUpdated AuditLogType = "webauthn_credential_updated"
-	AuditLogWebAuthnCredentialDeleted AuditLogType = "webauthn_credential_deleted"
+	AuditLogWebAuthnCredentialUpdated  AuditLogType = "webauthn_credential_updated"
+	AuditLogWebAuthnCredentialDeleted  AuditLogType = "webauthn_credential_deleted"
+	AuditLogWebAuthnCredentialEnabled  AuditLogType = "webauthn_credential_enabled"
+	AuditLogWebAuthnCredentialDisabled AuditLogType = "webauthn_credential_disabled"
+	AuditLogWebAuthnCredentialRevoked  AuditLogType = "webauthn_credential_revoked"
 
 	AuditLogWebAuthnTransactionInitFailed    AuditLogType = "webauthn_transaction_init_failed"
 	AuditLogWebAuthnTransactionInitSucceeded AuditLogType = "webauthn_transaction_init_succeeded"
@@ -54,4 +273,76 @@ var (
 	AuditLogMfaAuthenticationInitFailed     AuditLogType = "mfa_authentication_init_failed"
 	AuditLogMfaAuthenticationFinalSucceeded AuditLogType = "mfa_authentication_final_succeeded"
 	AuditLogMfaAuthenticationFinalFailed    AuditLogType = "mfa_authentication_final_failed"
+
+	// AuditLogUserDisabledRejected replaces the failed type of the rejected operation when a disabled user tries to
+	// register or authenticate
+	AuditLogUserDisabledRejected AuditLogType = "user_disabled_rejected"
+
+	AuditLogAdminTenantCreated  AuditLogType = "admin_tenant_created"
+	AuditLogAdminTenantUpdated  AuditLogType = "admin_tenant_updated"
+	AuditLogAdminTenantDeleted  AuditLogType = "admin_tenant_deleted"
+	AuditLogAdminTenantImported AuditLogType = "admin_tenant_imported"
+	AuditLogAdminTenantExported AuditLogType = "admin_tenant_exported"
+
+	AuditLogAdminConfigUpdated    AuditLogType = "admin_config_updated"
+	AuditLogAdminConfigRolledBack AuditLogType = "admin_config_rolled_back"
+
+	AuditLogAdminApiKeyCreated AuditLogType = "admin_api_key_created"
+	AuditLogAdminApiKeyDeleted AuditLogType = "admin_api_key_deleted"
+	AuditLogAdminJwkCreated    AuditLogType = "admin_jwk_created"
+	AuditLogAdminJwkDeleted    AuditLogType = "admin_jwk_deleted"
+
+	AuditLogAdminUserCreated  AuditLogType = "admin_user_created"
+	AuditLogAdminUserUpdated  AuditLogType = "admin_user_updated"
+	AuditLogAdminUserDeleted  AuditLogType = "admin_user_deleted"
+	AuditLogAdminUserDisabled AuditLogType = "admin_user_disabled"
+	AuditLogAdminUserEnabled  AuditLogType = "admin_user_enabled"
+	AuditLogAdminUserExported AuditLogType = "admin_user_exported"
+	AuditLogAdminUserErased   AuditLogType = "admin_user_erased"
+
+	AuditLogAdminCredentialUpdated   AuditLogType = "admin_credential_updated"
+	AuditLogAdminCredentialDeleted   AuditLogType = "admin_credential_deleted"
+	AuditLogAdminCredentialEnabled   AuditLogType = "admin_credential_enabled"
+	AuditLogAdminCredentialDisabled  AuditLogType = "admin_credential_disabled"
+	AuditLogAdminCredentialRevoked   AuditLogType = "admin_credential_revoked"
+	AuditLogAdminCredentialsImported AuditLogType = "admin_credentials_imported"
+)
+
+// AdminAuditLogTypes contains all types written by the admin API
+var AdminAuditLogTypes = []AuditLogType{
+	AuditLogAdminTenantCreated,
+	AuditLogAdminTenantUpdated,
+	AuditLogAdminTenantDeleted,
+	AuditLogAdminTenantImported,
+	AuditLogAdminTenantExported,
+	AuditLogAdminConfigUpdated,
+	AuditLogAdminConfigRolledBack,
+	AuditLogAdminApiKeyCreated,
+	AuditLogAdminApiKeyDeleted,
+	AuditLogAdminJwkCreated,
+	AuditLogAdminJwkDeleted,
+	AuditLogAdminUserCreated,
+	AuditLogAdminUserUpdated,
+	AuditLogAdminUserDeleted,
+	AuditLogAdminUserDisabled,
+	AuditLogAdminUserEnabled,
+	AuditLogAdminUserExported,
+	AuditLogAdminUserErased,
+	AuditLogAdminCredentialUpdated,
+	AuditLogAdminCredentialDeleted,
+	AuditLogAdminCredentialEnabled,
+	AuditLogAdminCredentialDisabled,
+	AuditLogAdminCredentialRevoked,
+	AuditLogAdminCredentialsImported,
+}
+
+type AuditLogTargetType string
+
+const (
+	AuditLogTargetTenant     AuditLogTargetType = "tenant"
+	AuditLogTargetConfig     AuditLogTargetType = "config"
+	AuditLogTargetApiKey     AuditLogTargetType = "api_key"
+	AuditLogTargetJwk        AuditLogTargetType = "jwk"
+	AuditLogTargetUser       AuditLogTargetType = "user"
+	AuditLogTargetCredential AuditLogTargetType = "credential"
 )
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// GlobalAuditLogChainId identifies the chain of audit logs which are not attached to a tenant
+const GlobalAuditLogChainId = "global"
+
+// AuditLogChain is used by pop to map your audit_log_chains database table to your go code.
+// It stores the head of the audit log chain of a tenant, so concurrent entries can be serialized by locking it.
+type AuditLogChain struct {
+	ID       string `json:"id" db:"id"`
+	Sequence int    `json:"sequence" db:"sequence"`
+	Hash     string `json:"hash" db:"hash"`
+	// PurgedSequence and PurgedHash anchor the chain after the oldest entries were purged by the retention
+	PurgedSequence int       `json:"purged_sequence" db:"purged_sequence"`
+	PurgedHash     *string   `json:"purged_hash,omitempty" db:"purged_hash"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AuditLogChainId returns the id of the chain of the tenant
+func AuditLogChainId(tenantId *uuid.UUID) string {
+	if tenantId == nil {
+		return GlobalAuditLogChainId
+	}
+
+	return tenantId.String()
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// AuditLogCheckpoint is used by pop to map your audit_log_checkpoints database table to your go code.
+// The signature is a JWS over the hash of the chain at the sequence, signed with a JWK of the tenant.
+type AuditLogCheckpoint struct {
+	ID        uuid.UUID `json:"id" db:"id"`
+	TenantID  uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	Sequence  int       `json:"sequence" db:"sequence"`
+	Hash      string    `json:"hash" db:"hash"`
+	Signature string    `json:"signature" db:"signature"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type AuditLogCheckpoints []AuditLogCheckpoint
+
+func (checkpoint *AuditLogCheckpoint) Validate(_ *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Name: "ID", Field: checkpoint.ID},
+		&validators.UUIDIsPresent{Name: "TenantID", Field: checkpoint.TenantID},
+		&validators.IntIsGreaterThan{Name: "Sequence", Field: checkpoint.Sequence, Compared: 0},
+		&validators.StringIsPresent{Name: "Hash", Field: checkpoint.Hash},
+		&validators.StringIsPresent{Name: "Signature", Field: checkpoint.Signature},
+	), nil
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"github.com/gobuffalo/validate/v3/validators"
 	"time"
 
@@ -17,8 +20,58 @@ type AuditLogConfig struct {
 	OutputStream   string    `json:"output_stream" db:"output_stream"`
 	ConsoleEnabled bool      `json:"enable_console" db:"enable_console"`
 	StorageEnabled bool      `json:"enable_storage" db:"enable_storage"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	// CheckpointInterval is the number of entries after which the chain is signed. 0 disables checkpoints.
+	CheckpointInterval int `json:"checkpoint_interval" db:"checkpoint_interval"`
+	// Sinks contains the names of the audit sinks of the server config the entries are sent to
+	Sinks AuditLogSinkNames `json:"sinks" db:"sinks"`
+	// IpAnonymization is applied to the source IP before the entry is stored, logged or sent to a sink
+	IpAnonymization string `json:"ip_anonymization" db:"ip_anonymization"`
+	// IpHashSalt is kept when the config is updated, so hashed IPs of a tenant stay comparable
+	IpHashSalt     string `json:"-" db:"ip_hash_salt"`
+	StoreUserAgent bool   `json:"store_user_agent" db:"store_user_agent"`
+	// RetentionDays is the number of days after which entries are purged. 0 keeps the entries forever.
+	RetentionDays int       `json:"retention_days" db:"retention_days"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// AuditLogRetention is the retention of the audit logs of a tenant
+type AuditLogRetention struct {
+	TenantID      uuid.UUID `db:"tenant_id"`
+	RetentionDays int       `db:"retention_days"`
+}
+
+// AuditLogSinkNames is stored as JSON array
+type AuditLogSinkNames []string
+
+func (names AuditLogSinkNames) Value() (driver.Value, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	value, err := json.Marshal([]string(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize audit log sinks: %w", err)
+	}
+
+	return string(value), nil
+}
+
+func (names *AuditLogSinkNames) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*names = nil
+		return nil
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("unsupported type for audit log sinks: %T", value)
+	}
+
+	return json.Unmarshal(data, (*[]string)(names))
 }
 
 // AuditLogConfigs is not required by pop and may be deleted
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gobuffalo/validate/v3/validators"
+	"github.com/gofrs/uuid"
+)
+
+// AuditLogRollup is used by pop to map your audit_log_rollups database table to your go code.
+// It contains the number of audit logs of a type which were created by a tenant in the hour starting at the bucket.
+type AuditLogRollup struct {
+	ID        uuid.UUID    `json:"id" db:"id"`
+	TenantID  uuid.UUID    `json:"tenant_id" db:"tenant_id"`
+	Bucket    time.Time    `json:"bucket" db:"bucket"`
+	Type      AuditLogType `json:"type" db:"type"`
+	Count     int          `json:"count" db:"count"`
+	CreatedAt time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+type AuditLogRollups []AuditLogRollup
+
+func (rollup *AuditLogRollup) Validate(_ *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Name: "ID", Field: rollup.ID},
+		&validators.UUIDIsPresent{Name: "TenantID", Field: rollup.TenantID},
+		&validators.TimeIsPresent{Name: "Bucket", Field: rollup.Bucket},
+		&validators.StringIsPresent{Name: "Type", Field: string(rollup.Type)},
+	), nil
+}
+
+// AuditLogTypeCount is the number of audit logs of a type in the hour starting at the bucket
+type AuditLogTypeCount struct {
+	TenantID uuid.UUID    `json:"-" db:"tenant_id"`
+	Bucket   time.Time    `json:"bucket" db:"bucket"`
+	Type     AuditLogType `json:"type" db:"type"`
+	Count    int          `json:"count" db:"count"`
+}
+
+// CredentialAaguidCount is the number of credentials registered with an authenticator model
+type CredentialAaguidCount struct {
+	TenantID uuid.UUID `db:"tenant_id"`
+	AAGUID   uuid.UUID `db:"aaguid"`
+	Count    int       `db:"count"`
+}
+
+// SessionDataCount is the number of stored session data of a ceremony, i.e. of ceremonies which were not finished
+type SessionDataCount struct {
+	TenantID  uuid.UUID `db:"tenant_id"`
+	Operation Operation `db:"operation"`
+	Count     int       `db:"count"`
+}
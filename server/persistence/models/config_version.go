@@ -0,0 +1,52 @@
+package models
+
+import (
+	"github.com/gobuffalo/validate/v3/validators"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+)
+
+type ConfigChangeType string
+
+const (
+	ConfigChangeCreate   ConfigChangeType = "create"
+	ConfigChangeUpdate   ConfigChangeType = "update"
+	ConfigChangePatch    ConfigChangeType = "patch"
+	ConfigChangeRollback ConfigChangeType = "rollback"
+	// ConfigChangeProvision marks changes applied from declarative tenant files
+	ConfigChangeProvision ConfigChangeType = "provision"
+)
+
+// ConfigVersion is used by pop to map your config_versions database table to your go code.
+// It stores a snapshot of the tenant config (in the format of the admin API) after each change.
+type ConfigVersion struct {
+	ID         uuid.UUID        `json:"id" db:"id"`
+	TenantID   uuid.UUID        `json:"tenant_id" db:"tenant_id"`
+	Tenant     *Tenant          `json:"tenant,omitempty" belongs_to:"tenants"`
+	Version    int              `json:"version" db:"version"`
+	ChangeType ConfigChangeType `json:"change_type" db:"change_type"`
+	ChangedBy  *string          `json:"changed_by" db:"changed_by"`
+	Config     string           `json:"config" db:"config"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+// ConfigVersions is not required by pop and may be deleted
+type ConfigVersions []ConfigVersion
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (version *ConfigVersion) Validate(_ *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Name: "ID", Field: version.ID},
+		&validators.UUIDIsPresent{Name: "TenantID", Field: version.TenantID},
+		&validators.IntIsGreaterThan{Name: "Version", Field: version.Version, Compared: 0},
+		&validators.StringInclusion{Name: "ChangeType", Field: string(version.ChangeType), List: []string{string(ConfigChangeCreate), string(ConfigChangeUpdate), string(ConfigChangePatch), string(ConfigChangeRollback), string(ConfigChangeProvision)}},
+		&validators.StringIsPresent{Name: "Config", Field: version.Config},
+		&validators.TimeIsPresent{Name: "UpdatedAt", Field: version.UpdatedAt},
+		&validators.TimeIsPresent{Name: "CreatedAt", Field: version.CreatedAt},
+	), nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"github.com/gobuffalo/validate/v3/validators"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+)
+
+// IdempotencyKey is used by pop to map your idempotency_keys database table to your go code.
+// It stores the response of a request so that retries with the same key replay it instead of repeating the operation.
+type IdempotencyKey struct {
+	ID             uuid.UUID `json:"id" db:"id"`
+	Key            string    `json:"idempotency_key" db:"idempotency_key"`
+	Operation      string    `json:"operation" db:"operation"`
+	RequestHash    string    `json:"request_hash" db:"request_hash"`
+	ResponseStatus int       `json:"response_status" db:"response_status"`
+	ResponseBody   string    `json:"response_body" db:"response_body"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// IsExpired reports whether the key is older than the given lifetime and can be reused for a new request
+func (key *IdempotencyKey) IsExpired(lifetime time.Duration) bool {
+	return time.Since(key.CreatedAt) > lifetime
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (key *IdempotencyKey) Validate(_ *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Name: "ID", Field: key.ID},
+		&validators.StringIsPresent{Name: "Key", Field: key.Key},
+		&validators.StringLengthInRange{Name: "Key", Field: key.Key, Min: 1, Max: 255},
+		&validators.StringIsPresent{Name: "Operation", Field: key.Operation},
+		&validators.StringIsPresent{Name: "RequestHash", Field: key.RequestHash},
+		&validators.TimeIsPresent{Name: "UpdatedAt", Field: key.UpdatedAt},
+		&validators.TimeIsPresent{Name: "CreatedAt", Field: key.CreatedAt},
+	), nil
+}
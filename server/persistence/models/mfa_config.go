@@ -22,6 +22,8 @@ type MfaConfig struct {
 	Attachment             protocol.AuthenticatorAttachment     `json:"attachment" db:"attachment"`
 	AttestationPreference  protocol.ConveyancePreference        `json:"attestation_preference" db:"attestation_preference"`
 	ResidentKeyRequirement protocol.ResidentKeyRequirement      `json:"resident_key_requirement" db:"resident_key_requirement"`
+	// RequireForLogin requires users with a MFA credential to complete a passkey login with a MFA login
+	RequireForLogin bool `json:"require_for_login" db:"require_for_login"`
 }
 
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/gobuffalo/validate/v3/validators"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+)
+
+type MobileAppPlatform string
+
+const (
+	MobileAppPlatformAndroid MobileAppPlatform = "android"
+	MobileAppPlatformIOS     MobileAppPlatform = "ios"
+)
+
+const androidOriginPrefix = "android:apk-key-hash:"
+
+// MobileApp is used by pop to map your mobile_apps database table to your go code.
+type MobileApp struct {
+	ID             uuid.UUID         `json:"id" db:"id"`
+	RelyingParty   *RelyingParty     `json:"relying_party" belongs_to:"relying_parties"`
+	RelyingPartyID uuid.UUID         `json:"relying_party_id" db:"relying_party_id"`
+	Platform       MobileAppPlatform `json:"platform" db:"platform"`
+	// AppId contains the package name for android apps and the app ID (<team id>.<bundle id>) for iOS apps
+	AppId string `json:"app_id" db:"app_id"`
+	// Sha256CertFingerprints contains the comma separated signing certificate fingerprints of an android app
+	Sha256CertFingerprints *string   `json:"sha256_cert_fingerprints" db:"sha256_cert_fingerprints"`
+	CreatedAt              time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// MobileApps is not required by pop and may be deleted
+type MobileApps []MobileApp
+
+// GetFingerprints returns the signing certificate fingerprints of an android app
+func (app *MobileApp) GetFingerprints() []string {
+	if app.Sha256CertFingerprints == nil || *app.Sha256CertFingerprints == "" {
+		return nil
+	}
+
+	return strings.Split(*app.Sha256CertFingerprints, ",")
+}
+
+// GetOrigins returns the origins the app uses in the client data of a webauthn ceremony.
+// Android apps use the hash of their signing certificate, iOS apps use the origin of the associated domain.
+func (app *MobileApp) GetOrigins(rpId string) ([]string, error) {
+	if app.Platform == MobileAppPlatformIOS {
+		return []string{fmt.Sprintf("https://%s", rpId)}, nil
+	}
+
+	var origins []string
+	for _, fingerprint := range app.GetFingerprints() {
+		hash, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate fingerprint for app '%s': %w", app.AppId, err)
+		}
+
+		origins = append(origins, androidOriginPrefix+base64.RawURLEncoding.EncodeToString(hash))
+	}
+
+	return origins, nil
+}
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (app *MobileApp) Validate(_ *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Name: "ID", Field: app.ID},
+		&validators.StringInclusion{Name: "Platform", Field: string(app.Platform), List: []string{string(MobileAppPlatformAndroid), string(MobileAppPlatformIOS)}},
+		&validators.StringIsPresent{Name: "AppId", Field: app.AppId},
+		&validators.TimeIsPresent{Name: "UpdatedAt", Field: app.UpdatedAt},
+		&validators.TimeIsPresent{Name: "CreatedAt", Field: app.CreatedAt},
+	), nil
+}
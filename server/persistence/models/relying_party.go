@@ -17,7 +17,9 @@ type RelyingParty struct {
 	RPId             string          `json:"rp_id" db:"rp_id"`
 	DisplayName      string          `json:"display_name" db:"display_name"`
 	Icon             *string         `json:"icon" db:"icon"`
+	IsDefault        bool            `json:"is_default" db:"is_default"`
 	Origins          WebauthnOrigins `json:"origins" has_many:"webauthn_origins"`
+	MobileApps       MobileApps      `json:"mobile_apps" has_many:"mobile_apps"`
 	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt        time.Time       `json:"updated_at" db:"updated_at"`
 }
@@ -25,6 +27,59 @@ type RelyingParty struct {
 // RelyingParties is not required by pop and may be deleted
 type RelyingParties []RelyingParty
 
+// HasOrigin checks if the given origin is one of the allowed origins of the relying party
+func (rp *RelyingParty) HasOrigin(origin string) bool {
+	for _, rpOrigin := range rp.GetOrigins() {
+		if rpOrigin == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetOrigins returns the configured web origins together with the origins generated for the mobile apps of the
+// relying party. Mobile apps with malformed fingerprints are skipped.
+func (rp *RelyingParty) GetOrigins() []string {
+	var origins []string
+	known := make(map[string]bool)
+	addOrigin := func(origin string) {
+		if !known[origin] {
+			known[origin] = true
+			origins = append(origins, origin)
+		}
+	}
+
+	for _, origin := range rp.Origins {
+		addOrigin(origin.Origin)
+	}
+
+	for _, app := range rp.MobileApps {
+		appOrigins, err := app.GetOrigins(rp.RPId)
+		if err != nil {
+			continue
+		}
+
+		for _, origin := range appOrigins {
+			addOrigin(origin)
+		}
+	}
+
+	return origins
+}
+
+// GetMobileApps returns all mobile apps of the relying party for the given platform
+func (rp *RelyingParty) GetMobileApps(platform MobileAppPlatform) MobileApps {
+	var apps MobileApps
+	for _, app := range rp.MobileApps {
+		if app.Platform == platform {
+			apps = append(apps, app)
+		}
+	}
+
+	return apps
+}
+
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 // This method is not required and may be deleted.
 func (rp *RelyingParty) Validate(tx *pop.Connection) (*validate.Errors, error) {
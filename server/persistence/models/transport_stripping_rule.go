@@ -0,0 +1,50 @@
+package models
+
+import (
+	"github.com/gobuffalo/validate/v3/validators"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gobuffalo/validate/v3"
+	"github.com/gofrs/uuid"
+)
+
+type TransportStrippingPolicy string
+
+const (
+	// TransportStrippingAlways removes the transports from all allowed credentials
+	TransportStrippingAlways TransportStrippingPolicy = "always"
+	// TransportStrippingNever keeps the transports of all allowed credentials
+	TransportStrippingNever TransportStrippingPolicy = "never"
+	// TransportStrippingUserAgent removes the transports only when the user agent matches one of the configured rules
+	TransportStrippingUserAgent TransportStrippingPolicy = "user_agent"
+)
+
+// DefaultTransportStrippingPatterns are used when the user agent policy is active but no rules are configured.
+// Android and Windows trigger the internal authenticator when the transports contain 'internal' although the
+// credential is not available on the device.
+var DefaultTransportStrippingPatterns = []string{"(?i)android", "(?i)windows"}
+
+// TransportStrippingRule is used by pop to map your transport_stripping_rules database table to your go code.
+type TransportStrippingRule struct {
+	ID               uuid.UUID       `json:"id" db:"id"`
+	WebauthnConfig   *WebauthnConfig `json:"webauthn_config" belongs_to:"webauthn_configs"`
+	WebauthnConfigID uuid.UUID       `json:"webauthn_config_id" db:"webauthn_config_id"`
+	UserAgentPattern string          `json:"user_agent_pattern" db:"user_agent_pattern"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// TransportStrippingRules is not required by pop and may be deleted
+type TransportStrippingRules []TransportStrippingRule
+
+// Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
+// This method is not required and may be deleted.
+func (rule *TransportStrippingRule) Validate(_ *pop.Connection) (*validate.Errors, error) {
+	return validate.Validate(
+		&validators.UUIDIsPresent{Name: "ID", Field: rule.ID},
+		&validators.StringIsPresent{Name: "UserAgentPattern", Field: rule.UserAgentPattern},
+		&validators.TimeIsPresent{Name: "UpdatedAt", Field: rule.UpdatedAt},
+		&validators.TimeIsPresent{Name: "CreatedAt", Field: rule.CreatedAt},
+	), nil
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/go-webauthn/webauthn/protocol"
@@ -13,17 +14,85 @@ import (
 
 // WebauthnConfig is used by pop to map your webauthn_configs database table to your go code.
 type WebauthnConfig struct {
-	ID                     uuid.UUID                            `json:"id" db:"id"`
-	Config                 *Config                              `json:"config" belongs_to:"configs"`
-	ConfigID               uuid.UUID                            `json:"config_id" db:"config_id"`
-	RelyingParty           RelyingParty                         `json:"relying_party" has_one:"relying_parties"`
-	Timeout                int                                  `json:"timeout" db:"timeout"`
-	CreatedAt              time.Time                            `json:"created_at" db:"created_at"`
-	UpdatedAt              time.Time                            `json:"updated_at" db:"updated_at"`
-	UserVerification       protocol.UserVerificationRequirement `json:"user_verification" db:"user_verification"`
-	Attachment             *protocol.AuthenticatorAttachment    `json:"attachment" db:"attachment"`
-	AttestationPreference  protocol.ConveyancePreference        `json:"attestation_preference" db:"attestation_preference"`
-	ResidentKeyRequirement protocol.ResidentKeyRequirement      `json:"resident_key_requirement" db:"resident_key_requirement"`
+	ID                      uuid.UUID                            `json:"id" db:"id"`
+	Config                  *Config                              `json:"config" belongs_to:"configs"`
+	ConfigID                uuid.UUID                            `json:"config_id" db:"config_id"`
+	RelyingParties          RelyingParties                       `json:"relying_parties" has_many:"relying_parties" order_by:"created_at asc"`
+	Timeout                 int                                  `json:"timeout" db:"timeout"`
+	CreatedAt               time.Time                            `json:"created_at" db:"created_at"`
+	UpdatedAt               time.Time                            `json:"updated_at" db:"updated_at"`
+	UserVerification        protocol.UserVerificationRequirement `json:"user_verification" db:"user_verification"`
+	Attachment              *protocol.AuthenticatorAttachment    `json:"attachment" db:"attachment"`
+	AttestationPreference   protocol.ConveyancePreference        `json:"attestation_preference" db:"attestation_preference"`
+	ResidentKeyRequirement  protocol.ResidentKeyRequirement      `json:"resident_key_requirement" db:"resident_key_requirement"`
+	TransportStripping      TransportStrippingPolicy             `json:"transport_stripping" db:"transport_stripping"`
+	TransportStrippingRules TransportStrippingRules              `json:"transport_stripping_rules" has_many:"transport_stripping_rules"`
+	// StoreCreationIp stores the IP address of the client which registered a credential
+	StoreCreationIp bool `json:"store_creation_ip" db:"store_creation_ip"`
+}
+
+// GetDefaultRelyingParty returns the relying party which is used when a request does not select one explicitly
+func (webauthn *WebauthnConfig) GetDefaultRelyingParty() *RelyingParty {
+	for i := range webauthn.RelyingParties {
+		if webauthn.RelyingParties[i].IsDefault {
+			return &webauthn.RelyingParties[i]
+		}
+	}
+
+	if len(webauthn.RelyingParties) > 0 {
+		return &webauthn.RelyingParties[0]
+	}
+
+	return nil
+}
+
+// FindRelyingPartyById returns the relying party with the given RP ID or nil if none matches
+func (webauthn *WebauthnConfig) FindRelyingPartyById(rpId string) *RelyingParty {
+	for i := range webauthn.RelyingParties {
+		if webauthn.RelyingParties[i].RPId == rpId {
+			return &webauthn.RelyingParties[i]
+		}
+	}
+
+	return nil
+}
+
+// FindRelyingPartyByOrigin returns the first relying party which allows the given origin or nil if none matches
+func (webauthn *WebauthnConfig) FindRelyingPartyByOrigin(origin string) *RelyingParty {
+	for i := range webauthn.RelyingParties {
+		if webauthn.RelyingParties[i].HasOrigin(origin) {
+			return &webauthn.RelyingParties[i]
+		}
+	}
+
+	return nil
+}
+
+// ShouldStripTransports checks if the transports of the allowed credentials have to be removed for the given user agent
+func (webauthn *WebauthnConfig) ShouldStripTransports(userAgent string) bool {
+	switch webauthn.TransportStripping {
+	case TransportStrippingNever:
+		return false
+	case TransportStrippingUserAgent:
+		patterns := DefaultTransportStrippingPatterns
+		if len(webauthn.TransportStrippingRules) > 0 {
+			patterns = make([]string, len(webauthn.TransportStrippingRules))
+			for i, rule := range webauthn.TransportStrippingRules {
+				patterns[i] = rule.UserAgentPattern
+			}
+		}
+
+		for _, pattern := range patterns {
+			matched, err := regexp.MatchString(pattern, userAgent)
+			if err == nil && matched {
+				return true
+			}
+		}
+
+		return false
+	default:
+		return true
+	}
 }
 
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
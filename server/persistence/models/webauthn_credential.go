@@ -1,6 +1,7 @@
 package models
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gobuffalo/pop/v6"
@@ -25,6 +26,16 @@ type WebauthnCredential struct {
 	BackupEligible  bool       `db:"backup_eligible" json:"-"`
 	BackupState     bool       `db:"backup_state" json:"-"`
 	IsMFA           bool       `db:"is_mfa" json:"-"`
+	RPId            *string    `db:"rp_id" json:"-"`
+
+	Status       CredentialStatus `db:"status" json:"-"`
+	StatusReason *string          `db:"status_reason" json:"-"`
+	DisabledAt   *time.Time       `db:"disabled_at" json:"-"`
+	RevokedAt    *time.Time       `db:"revoked_at" json:"-"`
+
+	CreatedUserAgent *string `db:"created_user_agent" json:"-"`
+	CreatedDevice    *string `db:"created_device" json:"-"`
+	CreatedIp        *string `db:"created_ip" json:"-"`
 
 	WebauthnUserID uuid.UUID     `db:"webauthn_user_id"`
 	WebauthnUser   *WebauthnUser `belongs_to:"webauthn_user"`
@@ -32,12 +43,55 @@ type WebauthnCredential struct {
 
 type WebauthnCredentials []WebauthnCredential
 
+type CredentialStatus string
+
+const (
+	CredentialStatusActive   CredentialStatus = "active"
+	CredentialStatusDisabled CredentialStatus = "disabled"
+	CredentialStatusRevoked  CredentialStatus = "revoked"
+)
+
+// ErrCredentialRevoked is returned when the status of a revoked credential should be changed. Revoking a credential
+// is final, the credential is only kept for forensic purposes.
+var ErrCredentialRevoked = errors.New("the credential is revoked")
+
+// IsActive reports whether the credential can be used for authentication
+func (credential *WebauthnCredential) IsActive() bool {
+	return credential.Status == CredentialStatusActive
+}
+
+// SetStatus changes the status of the credential and keeps track of when it was disabled or revoked.
+func (credential *WebauthnCredential) SetStatus(status CredentialStatus, reason *string) error {
+	if credential.Status == CredentialStatusRevoked {
+		return ErrCredentialRevoked
+	}
+
+	now := time.Now().UTC()
+	switch status {
+	case CredentialStatusActive:
+		credential.DisabledAt = nil
+	case CredentialStatusDisabled:
+		if credential.Status != CredentialStatusDisabled {
+			credential.DisabledAt = &now
+		}
+	case CredentialStatusRevoked:
+		credential.RevokedAt = &now
+	}
+
+	credential.Status = status
+	credential.StatusReason = reason
+	credential.UpdatedAt = now
+
+	return nil
+}
+
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 func (credential *WebauthnCredential) Validate(_ *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.StringIsPresent{Name: "ID", Field: credential.ID},
 		&validators.StringIsPresent{Name: "UserId", Field: credential.UserId},
 		&validators.StringIsPresent{Name: "PublicKey", Field: credential.PublicKey},
+		&validators.StringInclusion{Name: "Status", Field: string(credential.Status), List: []string{string(CredentialStatusActive), string(CredentialStatusDisabled), string(CredentialStatusRevoked)}},
 		&validators.IntIsGreaterThan{Name: "SignCount", Field: credential.SignCount, Compared: -1},
 		&validators.TimeIsPresent{Name: "CreatedAt", Field: credential.CreatedAt},
 		&validators.TimeIsPresent{Name: "UpdatedAt", Field: credential.UpdatedAt},
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"github.com/go-webauthn/webauthn/protocol"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
@@ -25,6 +26,23 @@ func (transports Transports) GetNames() []string {
 	return names
 }
 
+// Attachment derives the authenticator attachment from the transports stored at registration. Authenticators
+// reporting the 'internal' transport are platform authenticators, even if they can be used through 'hybrid' as well.
+// An empty attachment is returned if no transports are stored.
+func (transports Transports) Attachment() protocol.AuthenticatorAttachment {
+	attachment := protocol.AuthenticatorAttachment("")
+	for _, transport := range transports {
+		switch protocol.AuthenticatorTransport(transport.Name) {
+		case protocol.Internal:
+			return protocol.Platform
+		case protocol.USB, protocol.NFC, protocol.BLE, protocol.Hybrid, "smart-card":
+			attachment = protocol.CrossPlatform
+		}
+	}
+
+	return attachment
+}
+
 // Validate gets run every time you call a "pop.Validate*" (pop.ValidateAndSave, pop.ValidateAndCreate, pop.ValidateAndUpdate) method.
 func (transport *WebauthnCredentialTransport) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
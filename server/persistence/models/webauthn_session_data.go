@@ -16,6 +16,9 @@ var (
 	WebauthnOperationRegistration   Operation = "registration"
 	WebauthnOperationAuthentication Operation = "authentication"
 	WebauthnOperationTransaction    Operation = "transaction"
+	// WebauthnOperationMfaRequired marks the pending MFA login of an intermediate token, so the token can only be used
+	// once. Its challenge is the id of the token.
+	WebauthnOperationMfaRequired Operation = "mfa_required"
 )
 
 // WebauthnSessionData is used by pop to map your webauthn_session_data database table to your go code.
@@ -30,6 +33,8 @@ type WebauthnSessionData struct {
 	AllowedCredentials []WebauthnSessionDataAllowedCredential `has_many:"webauthn_session_data_allowed_credentials"`
 	ExpiresAt          nulls.Time                             `db:"expires_at"`
 	IsDiscoverable     bool                                   `db:"is_discoverable"`
+	RequiredAttachment *string                                `db:"required_attachment"`
+	MfaToken           *string                                `db:"mfa_token"`
 
 	TenantID uuid.UUID `db:"tenant_id"`
 	Tenant   *Tenant   `belongs_to:"tenants"`
@@ -40,7 +45,7 @@ func (sd *WebauthnSessionData) Validate(_ *pop.Connection) (*validate.Errors, er
 	return validate.Validate(
 		&validators.UUIDIsPresent{Name: "ID", Field: sd.ID},
 		&validators.StringIsPresent{Name: "Challenge", Field: sd.Challenge},
-		&validators.StringInclusion{Name: "Operation", Field: string(sd.Operation), List: []string{string(WebauthnOperationRegistration), string(WebauthnOperationAuthentication), string(WebauthnOperationTransaction)}},
+		&validators.StringInclusion{Name: "Operation", Field: string(sd.Operation), List: []string{string(WebauthnOperationRegistration), string(WebauthnOperationAuthentication), string(WebauthnOperationTransaction), string(WebauthnOperationMfaRequired)}},
 		&validators.TimeIsPresent{Name: "UpdatedAt", Field: sd.UpdatedAt},
 		&validators.TimeIsPresent{Name: "CreatedAt", Field: sd.CreatedAt},
 	), nil
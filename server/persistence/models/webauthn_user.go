@@ -12,15 +12,17 @@ import (
 
 // WebauthnUser is used by pop to map your webauthn_users database table to your go code.
 type WebauthnUser struct {
-	ID          uuid.UUID `json:"id" db:"id"`
-	UserID      string    `json:"user_id" db:"user_id"`
-	Name        string    `json:"name" db:"name"`
-	Icon        string    `json:"icon" db:"icon"`
-	DisplayName string    `json:"display_name" db:"display_name"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
-	Tenant      *Tenant   `json:"tenant" belongs_to:"tenant"`
-	TenantID    uuid.UUID `json:"tenant_id" db:"tenant_id"`
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Name        string     `json:"name" db:"name"`
+	Icon        string     `json:"icon" db:"icon"`
+	DisplayName string     `json:"display_name" db:"display_name"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+	LastLoginAt *time.Time `json:"last_login_at" db:"last_login_at"`
+	DisabledAt  *time.Time `json:"disabled_at" db:"disabled_at"`
+	Tenant      *Tenant    `json:"tenant" belongs_to:"tenant"`
+	TenantID    uuid.UUID  `json:"tenant_id" db:"tenant_id"`
 
 	WebauthnCredentials WebauthnCredentials `json:"webauthn_credentials,omitempty" has_many:"webauthn_credentials"`
 	Transactions        Transactions        `json:"transactions,omitempty" has_many:"transactions"`
@@ -28,6 +30,12 @@ type WebauthnUser struct {
 
 type WebauthnUsers []WebauthnUser
 
+// IsDisabled reports whether the user was disabled through the admin API. Disabled users cannot register or
+// authenticate until they are enabled again.
+func (webauthnUser *WebauthnUser) IsDisabled() bool {
+	return webauthnUser.DisabledAt != nil
+}
+
 func (webauthnUser *WebauthnUser) WebAuthnID() []byte {
 	return []byte(webauthnUser.UserID)
 }
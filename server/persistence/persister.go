@@ -1,11 +1,13 @@
 package persistence
 
 import (
+	"context"
 	"embed"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/teamhanko/passkey-server/config"
 	"github.com/teamhanko/passkey-server/persistence/persisters"
+	"github.com/teamhanko/passkey-server/tracing"
 )
 
 //go:embed migrations/*
@@ -18,6 +20,8 @@ type persister struct {
 type Persister interface {
 	GetConnection() *pop.Connection
 	Transaction(func(tx *pop.Connection) error) error
+	// TransactionContext runs the transaction in the given context, so its queries are traced as part of the request
+	TransactionContext(ctx context.Context, fn func(tx *pop.Connection) error) error
 	GetAuditLogPersister(tx *pop.Connection) persisters.AuditLogPersister
 	GetWebauthnCredentialPersister(tx *pop.Connection) persisters.WebauthnCredentialPersister
 	GetWebauthnSessionDataPersister(tx *pop.Connection) persisters.WebauthnSessionDataPersister
@@ -32,6 +36,10 @@ type Persister interface {
 	GetAuditLogConfigPersister(tx *pop.Connection) persisters.AuditLogConfigPersister
 	GetTransactionPersister(tx *pop.Connection) persisters.TransactionPersister
 	GetMFAConfigPersister(tx *pop.Connection) persisters.MFAConfigPersister
+	GetConfigVersionPersister(tx *pop.Connection) persisters.ConfigVersionPersister
+	GetIdempotencyKeyPersister(tx *pop.Connection) persisters.IdempotencyKeyPersister
+	GetAuditLogCheckpointPersister(tx *pop.Connection) persisters.AuditLogCheckpointPersister
+	GetStatsPersister(tx *pop.Connection) persisters.StatsPersister
 }
 
 type Migrator interface {
@@ -45,11 +53,26 @@ type Database interface {
 }
 
 func NewDatabase(dbConfig config.Database) (Database, error) {
+	return newDatabase(dbConfig, false)
+}
+
+// NewTracedDatabase returns a Database which records its queries as child spans of the span in the context of the
+// connection, see Persister.TransactionContext
+func NewTracedDatabase(dbConfig config.Database) (Database, error) {
+	return newDatabase(dbConfig, true)
+}
+
+func newDatabase(dbConfig config.Database, traced bool) (Database, error) {
 	connectionDetails := &pop.ConnectionDetails{
 		Pool:     5,
 		IdlePool: 0,
 	}
 
+	if traced {
+		connectionDetails.UseInstrumentedDriver = true
+		connectionDetails.InstrumentedDriverOptions = tracing.SqlDriverOptions()
+	}
+
 	if len(dbConfig.Url) > 0 {
 		connectionDetails.URL = dbConfig.Url
 	} else {
@@ -109,6 +132,10 @@ func (p *persister) Transaction(fn func(tx *pop.Connection) error) error {
 	return p.Database.Transaction(fn)
 }
 
+func (p *persister) TransactionContext(ctx context.Context, fn func(tx *pop.Connection) error) error {
+	return p.Database.WithContext(ctx).Transaction(fn)
+}
+
 func (p *persister) GetAuditLogPersister(tx *pop.Connection) persisters.AuditLogPersister {
 	if tx == nil {
 		return persisters.NewAuditLogPersister(p.Database)
@@ -220,3 +247,35 @@ func (p *persister) GetMFAConfigPersister(tx *pop.Connection) persisters.MFAConf
 
 	return persisters.NewMFAConfigPersister(tx)
 }
+
+func (p *persister) GetConfigVersionPersister(tx *pop.Connection) persisters.ConfigVersionPersister {
+	if tx == nil {
+		return persisters.NewConfigVersionPersister(p.Database)
+	}
+
+	return persisters.NewConfigVersionPersister(tx)
+}
+
+func (p *persister) GetIdempotencyKeyPersister(tx *pop.Connection) persisters.IdempotencyKeyPersister {
+	if tx == nil {
+		return persisters.NewIdempotencyKeyPersister(p.Database)
+	}
+
+	return persisters.NewIdempotencyKeyPersister(tx)
+}
+
+func (p *persister) GetAuditLogCheckpointPersister(tx *pop.Connection) persisters.AuditLogCheckpointPersister {
+	if tx == nil {
+		return persisters.NewAuditLogCheckpointPersister(p.Database)
+	}
+
+	return persisters.NewAuditLogCheckpointPersister(tx)
+}
+
+func (p *persister) GetStatsPersister(tx *pop.Connection) persisters.StatsPersister {
+	if tx == nil {
+		return persisters.NewStatsPersister(p.Database)
+	}
+
+	return persisters.NewStatsPersister(tx)
+}
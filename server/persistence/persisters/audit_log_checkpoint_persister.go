@@ -0,0 +1,55 @@
+package persisters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+type AuditLogCheckpointPersister interface {
+	Create(checkpoint *models.AuditLogCheckpoint) error
+	ListBetween(tenantId uuid.UUID, fromSequence int, toSequence int) (models.AuditLogCheckpoints, error)
+}
+
+type auditLogCheckpointPersister struct {
+	database *pop.Connection
+}
+
+func NewAuditLogCheckpointPersister(database *pop.Connection) AuditLogCheckpointPersister {
+	return &auditLogCheckpointPersister{database: database}
+}
+
+func (cp *auditLogCheckpointPersister) Create(checkpoint *models.AuditLogCheckpoint) error {
+	validationErr, err := cp.database.ValidateAndCreate(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to store audit log checkpoint: %w", err)
+	}
+
+	if validationErr != nil && validationErr.HasAny() {
+		return fmt.Errorf("audit log checkpoint validation failed: %w", validationErr)
+	}
+
+	return nil
+}
+
+// ListBetween returns the checkpoints of the tenant for the sequences between from and to (both inclusive)
+func (cp *auditLogCheckpointPersister) ListBetween(tenantId uuid.UUID, fromSequence int, toSequence int) (models.AuditLogCheckpoints, error) {
+	checkpoints := make(models.AuditLogCheckpoints, 0)
+	err := cp.database.
+		Where("tenant_id = ? AND sequence >= ? AND sequence <= ?", tenantId, fromSequence, toSequence).
+		Order("sequence asc").
+		All(&checkpoints)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return checkpoints, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log checkpoints: %w", err)
+	}
+
+	return checkpoints, nil
+}
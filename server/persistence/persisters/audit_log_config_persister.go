@@ -1,6 +1,8 @@
 package persisters
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/gobuffalo/pop/v6"
@@ -9,6 +11,7 @@ import (
 
 type AuditLogConfigPersister interface {
 	Create(auditLogConfig *models.AuditLogConfig) error
+	ListRetentions() ([]models.AuditLogRetention, error)
 }
 
 type auditLogConfigPersister struct {
@@ -31,3 +34,17 @@ func (ap *auditLogConfigPersister) Create(auditLogConfig *models.AuditLogConfig)
 
 	return nil
 }
+
+// ListRetentions returns the retention of all tenants which do not keep their audit logs forever
+func (ap *auditLogConfigPersister) ListRetentions() ([]models.AuditLogRetention, error) {
+	retentions := make([]models.AuditLogRetention, 0)
+	err := ap.database.RawQuery(
+		"SELECT c.tenant_id AS tenant_id, a.retention_days AS retention_days FROM audit_log_configs a " +
+			"JOIN configs c ON c.id = a.config_id WHERE a.retention_days > 0",
+	).All(&retentions)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to list audit log retentions: %w", err)
+	}
+
+	return retentions, nil
+}
@@ -8,13 +8,23 @@ import (
 	"time"
 
 	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
 	"github.com/teamhanko/passkey-server/persistence/models"
 )
 
 type AuditLogPersister interface {
-	Create(auditLog models.AuditLog) error
+	// Create appends the audit log to the chain of its tenant
+	Create(auditLog *models.AuditLog) error
 	List(options AuditLogOptions) ([]models.AuditLog, error)
+	ListAfter(options AuditLogOptions, cursor AuditLogCursorOptions) ([]models.AuditLog, error)
 	Count(options AuditLogOptions) (int, error)
+	GetAllForTenant(tenantId uuid.UUID) (models.AuditLogs, error)
+	ListChain(options AuditLogChainOptions) (models.AuditLogs, error)
+	GetBySequence(tenantId *uuid.UUID, sequence int) (*models.AuditLog, error)
+	GetChainHead(tenantId *uuid.UUID) (*models.AuditLogChain, error)
+	Purge(tenantId uuid.UUID, before time.Time) (int, error)
+	ListForUser(tenantId uuid.UUID, userId string, targetIds []string) (models.AuditLogs, error)
+	Pseudonymize(auditLog *models.AuditLog) error
 }
 
 type auditLogPersister struct {
@@ -27,8 +37,61 @@ func NewAuditLogPersister(database *pop.Connection) AuditLogPersister {
 	}
 }
 
-func (p *auditLogPersister) Create(auditLog models.AuditLog) error {
-	vErr, err := p.database.ValidateAndCreate(&auditLog)
+func (p *auditLogPersister) Create(auditLog *models.AuditLog) error {
+	// pop commits the outer transaction when transactions are nested
+	if p.database.TX != nil {
+		return p.createChained(p.database, auditLog)
+	}
+
+	return p.database.Transaction(func(tx *pop.Connection) error {
+		return p.createChained(tx, auditLog)
+	})
+}
+
+// createChained appends the entry to the chain of its tenant. The head of the chain is created together with the
+// tenant. It is locked only to link the entry, so concurrent entries of the same tenant get consecutive sequences.
+func (p *auditLogPersister) createChained(tx *pop.Connection, auditLog *models.AuditLog) error {
+	if auditLog.CreatedAt.IsZero() {
+		auditLog.CreatedAt = time.Now().UTC()
+	}
+	// not all databases store fractions of seconds (e.g. DATETIME of MySQL rounds them), so the stored creation time
+	// must be the hashed one
+	auditLog.CreatedAt = auditLog.CreatedAt.Truncate(time.Second)
+
+	err := auditLog.SealPersonalData()
+	if err != nil {
+		return err
+	}
+
+	contentHash, err := auditLog.CalculateContentHash()
+	if err != nil {
+		return err
+	}
+	auditLog.ContentHash = &contentHash
+
+	chainId := models.AuditLogChainId(auditLog.TenantID)
+
+	var chain models.AuditLogChain
+	err = tx.RawQuery("SELECT * FROM audit_log_chains WHERE id = ? FOR UPDATE", chainId).First(&chain)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("audit log chain '%s' does not exist", chainId)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get audit log chain: %w", err)
+	}
+
+	sequence := chain.Sequence + 1
+	auditLog.Sequence = &sequence
+	auditLog.PreviousHash = nil
+	if chain.Sequence > 0 {
+		previousHash := chain.Hash
+		auditLog.PreviousHash = &previousHash
+	}
+
+	hash := auditLog.CalculateHash()
+	auditLog.Hash = &hash
+
+	vErr, err := tx.ValidateAndCreate(auditLog)
 	if err != nil {
 		return fmt.Errorf("failed to store auditlog: %w", err)
 	}
@@ -36,9 +99,25 @@ func (p *auditLogPersister) Create(auditLog models.AuditLog) error {
 		return fmt.Errorf("auditlog object validation failed: %w", vErr)
 	}
 
+	chain.Sequence = sequence
+	chain.Hash = hash
+	err = tx.Update(&chain)
+	if err != nil {
+		return fmt.Errorf("failed to update audit log chain: %w", err)
+	}
+
 	return nil
 }
 
+type AuditLogChainOptions struct {
+	// TenantId selects the chain of the tenant, nil selects the chain of entries without tenant
+	TenantId      *uuid.UUID
+	Start         *time.Time
+	End           *time.Time
+	AfterSequence int
+	Limit         int
+}
+
 type AuditLogOptions struct {
 	Page     int
 	PerPage  int
@@ -49,6 +128,32 @@ type AuditLogOptions struct {
 	Ip       string
 	Search   string
 	TenantId string
+	// Actor, TargetType and TargetId filter the entries of admin operations
+	Actor      string
+	TargetType string
+	TargetId   string
+	// AdminOnly restricts the list to entries of admin operations
+	AdminOnly     bool
+	TransactionId string
+	Error         string
+	// Browser, Os and City match parts of the value, e.g. `Chrome` matches all versions
+	Browser string
+	Os      string
+	Device  string
+	Country string
+	City    string
+	Asn     int
+}
+
+// AuditLogCursorOptions page through the entries ordered by creation time and id
+type AuditLogCursorOptions struct {
+	// AfterCreatedAt and AfterId are the position of the last entry of the previous page. Both are empty for the
+	// first page.
+	AfterCreatedAt *time.Time
+	AfterId        *uuid.UUID
+	Limit          int
+	// Ascending lists the oldest entries first, e.g. for exports
+	Ascending bool
 }
 
 func (p *auditLogPersister) List(options AuditLogOptions) ([]models.AuditLog, error) {
@@ -68,6 +173,36 @@ func (p *auditLogPersister) List(options AuditLogOptions) ([]models.AuditLog, er
 	return auditLogs, nil
 }
 
+// ListAfter uses a keyset on (created_at, id) instead of an offset. In contrast to List it stays fast on large tables
+// and pages are not shifted by entries which are created in between.
+func (p *auditLogPersister) ListAfter(options AuditLogOptions, cursor AuditLogCursorOptions) ([]models.AuditLog, error) {
+	var auditLogs []models.AuditLog
+
+	operator, direction := "<", "desc"
+	if cursor.Ascending {
+		operator, direction = ">", "asc"
+	}
+
+	query := p.database.Q()
+	query = p.addOptionsToSqlQuery(query, options)
+	if cursor.AfterCreatedAt != nil && cursor.AfterId != nil {
+		query = query.Where(
+			fmt.Sprintf("(created_at %[1]s ? OR (created_at = ? AND id %[1]s ?))", operator),
+			cursor.AfterCreatedAt, cursor.AfterCreatedAt, cursor.AfterId,
+		)
+	}
+
+	err := query.Order(fmt.Sprintf("created_at %[1]s, id %[1]s", direction)).Limit(cursor.Limit).All(&auditLogs)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return auditLogs, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch auditLogs: %w", err)
+	}
+
+	return auditLogs, nil
+}
+
 func (p *auditLogPersister) Count(options AuditLogOptions) (int, error) {
 	query := p.database.Q()
 	query = p.addOptionsToSqlQuery(query, options)
@@ -109,10 +244,236 @@ func (p *auditLogPersister) addOptionsToSqlQuery(query *pop.Query, options Audit
 		}
 	}
 
+	if len(options.Actor) > 0 {
+		query = query.Where("actor LIKE ?", "%"+options.Actor+"%")
+	}
+
+	if len(options.TargetType) > 0 {
+		query = query.Where("target_type = ?", options.TargetType)
+	}
+
+	if len(options.TargetId) > 0 {
+		query = query.Where("target_id = ?", options.TargetId)
+	}
+
+	if len(options.TransactionId) > 0 {
+		query = query.Where("transaction_id = ?", options.TransactionId)
+	}
+
+	if len(options.Error) > 0 {
+		query = query.Where("error LIKE ?", likeContains(options.Error))
+	}
+
+	if len(options.Browser) > 0 {
+		query = query.Where("meta_browser LIKE ?", "%"+options.Browser+"%")
+	}
+
+	if len(options.Os) > 0 {
+		query = query.Where("meta_os LIKE ?", "%"+options.Os+"%")
+	}
+
+	if len(options.Device) > 0 {
+		query = query.Where("meta_device = ?", options.Device)
+	}
+
+	if len(options.Country) > 0 {
+		query = query.Where("meta_country = ?", strings.ToUpper(options.Country))
+	}
+
+	if len(options.City) > 0 {
+		query = query.Where("meta_city LIKE ?", "%"+options.City+"%")
+	}
+
+	if options.Asn > 0 {
+		query = query.Where("meta_asn = ?", options.Asn)
+	}
+
+	if options.AdminOnly {
+		adminTypes := make([]interface{}, 0, len(models.AdminAuditLogTypes))
+		for _, adminType := range models.AdminAuditLogTypes {
+			adminTypes = append(adminTypes, string(adminType))
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(adminTypes)), ",")
+		query = query.Where(fmt.Sprintf("type IN (%s)", placeholders), adminTypes...)
+	}
+
 	if len(options.Search) > 0 {
 		arg := "%" + options.Search + "%"
-		query = query.Where("(meta_source_ip LIKE ? OR actor_user_id LIKE ?)", arg, arg)
+		query = query.Where("(meta_source_ip LIKE ? OR actor_user_id LIKE ? OR actor LIKE ?)", arg, arg, arg)
 	}
 
 	return query
 }
+
+func (p *auditLogPersister) GetAllForTenant(tenantId uuid.UUID) (models.AuditLogs, error) {
+	auditLogs := models.AuditLogs{}
+	err := p.database.Where("tenant_id = ?", tenantId).Order("created_at asc").All(&auditLogs)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return auditLogs, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit logs for tenant: %w", err)
+	}
+
+	return auditLogs, nil
+}
+
+// ListChain returns the chained entries ordered by their sequence
+func (p *auditLogPersister) ListChain(options AuditLogChainOptions) (models.AuditLogs, error) {
+	auditLogs := models.AuditLogs{}
+
+	query := p.whereChain(p.database.Q(), options.TenantId).
+		Where("sequence > ?", options.AfterSequence)
+	if options.Start != nil {
+		query = query.Where("created_at >= ?", options.Start)
+	}
+	if options.End != nil {
+		query = query.Where("created_at <= ?", options.End)
+	}
+	if options.Limit > 0 {
+		query = query.Limit(options.Limit)
+	}
+
+	err := query.Order("sequence asc").All(&auditLogs)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return auditLogs, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit log chain: %w", err)
+	}
+
+	return auditLogs, nil
+}
+
+func (p *auditLogPersister) GetBySequence(tenantId *uuid.UUID, sequence int) (*models.AuditLog, error) {
+	auditLog := models.AuditLog{}
+	err := p.whereChain(p.database.Q(), tenantId).Where("sequence = ?", sequence).First(&auditLog)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log: %w", err)
+	}
+
+	return &auditLog, nil
+}
+
+func (p *auditLogPersister) GetChainHead(tenantId *uuid.UUID) (*models.AuditLogChain, error) {
+	chain := models.AuditLogChain{}
+	err := p.database.Find(&chain, models.AuditLogChainId(tenantId))
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log chain: %w", err)
+	}
+
+	return &chain, nil
+}
+
+// Purge deletes all entries of the tenant created before the given time. The chain is cut after the latest purged
+// entry, whose sequence and hash are kept as anchor, so the remaining entries can still be verified. Must be called
+// within a transaction.
+func (p *auditLogPersister) Purge(tenantId uuid.UUID, before time.Time) (int, error) {
+	chainId := models.AuditLogChainId(&tenantId)
+
+	var chain models.AuditLogChain
+	err := p.database.RawQuery("SELECT * FROM audit_log_chains WHERE id = ? FOR UPDATE", chainId).First(&chain)
+	hasChain := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get audit log chain: %w", err)
+	}
+
+	purgedSequence := 0
+	var last models.AuditLog
+	err = p.whereChain(p.database.Q(), &tenantId).Where("created_at < ?", before).Order("sequence desc").First(&last)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get latest audit log to purge: %w", err)
+	}
+
+	if err == nil && hasChain && *last.Sequence > chain.PurgedSequence {
+		purgedSequence = *last.Sequence
+		chain.PurgedSequence = purgedSequence
+		chain.PurgedHash = last.Hash
+		err = p.database.Update(&chain)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update audit log chain: %w", err)
+		}
+	}
+
+	// entries created before the chain was introduced have no sequence
+	count, err := p.database.RawQuery(
+		"DELETE FROM audit_logs WHERE tenant_id = ? AND (sequence <= ? OR (sequence IS NULL AND created_at < ?))",
+		tenantId, purgedSequence, before,
+	).ExecWithCount()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge audit logs: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListForUser returns the entries of the tenant with the user as actor and the admin operations on the user or its
+// credentials, which are identified by the target ids. Admin operations on credentials are linked to their owner as
+// actor, so all entries about a credential of the user are found, even if the credential was already deleted.
+func (p *auditLogPersister) ListForUser(tenantId uuid.UUID, userId string, targetIds []string) (models.AuditLogs, error) {
+	auditLogs := models.AuditLogs{}
+
+	condition := "actor_user_id = ? OR (target_type = ? AND target_id IN (" +
+		"SELECT linked.target_id FROM audit_logs linked WHERE linked.tenant_id = ? AND linked.actor_user_id = ? AND linked.target_type = ?" +
+		"))"
+	credentialTarget := string(models.AuditLogTargetCredential)
+	args := []interface{}{tenantId, userId, credentialTarget, tenantId, userId, credentialTarget}
+	if len(targetIds) > 0 {
+		condition += fmt.Sprintf(
+			" OR (target_type IN (?, ?) AND target_id IN (%s))",
+			strings.TrimSuffix(strings.Repeat("?,", len(targetIds)), ","),
+		)
+		args = append(args, string(models.AuditLogTargetUser), string(models.AuditLogTargetCredential))
+		for _, targetId := range targetIds {
+			args = append(args, targetId)
+		}
+	}
+
+	err := p.database.Where(fmt.Sprintf("tenant_id = ? AND (%s)", condition), args...).
+		Order("created_at asc").
+		All(&auditLogs)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return auditLogs, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs of user: %w", err)
+	}
+
+	return auditLogs, nil
+}
+
+// Pseudonymize stores the pseudonymized personal data of the entry and removes its personal data salt. The hashes are
+// left untouched, so the chain stays intact.
+func (p *auditLogPersister) Pseudonymize(auditLog *models.AuditLog) error {
+	err := p.database.UpdateColumns(
+		auditLog,
+		"actor_user_id", "target_id", "meta_source_ip", "meta_user_agent", "meta_city", "meta_asn",
+		"meta_as_organization", "details", "personal_salt", "pseudonymized_at", "updated_at",
+	)
+	if err != nil {
+		return fmt.Errorf("failed to pseudonymize audit log: %w", err)
+	}
+
+	return nil
+}
+
+func (p *auditLogPersister) whereChain(query *pop.Query, tenantId *uuid.UUID) *pop.Query {
+	query = query.Where("sequence IS NOT NULL")
+	if tenantId == nil {
+		return query.Where("tenant_id IS NULL")
+	}
+
+	return query.Where("tenant_id = ?", *tenantId)
+}
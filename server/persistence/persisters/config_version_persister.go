@@ -0,0 +1,77 @@
+package persisters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+type ConfigVersionPersister interface {
+	Create(version *models.ConfigVersion) error
+	List(tenantId uuid.UUID) (models.ConfigVersions, error)
+	Get(tenantId uuid.UUID, version int) (*models.ConfigVersion, error)
+	GetLatestVersion(tenantId uuid.UUID) (int, error)
+}
+
+type configVersionPersister struct {
+	database *pop.Connection
+}
+
+func NewConfigVersionPersister(database *pop.Connection) ConfigVersionPersister {
+	return &configVersionPersister{database: database}
+}
+
+func (cp *configVersionPersister) Create(version *models.ConfigVersion) error {
+	validationErr, err := cp.database.ValidateAndCreate(version)
+	if err != nil {
+		return fmt.Errorf("failed to store config version: %w", err)
+	}
+
+	if validationErr != nil && validationErr.HasAny() {
+		return fmt.Errorf("config version validation failed: %w", validationErr)
+	}
+
+	return nil
+}
+
+func (cp *configVersionPersister) List(tenantId uuid.UUID) (models.ConfigVersions, error) {
+	versions := make(models.ConfigVersions, 0)
+	err := cp.database.Where("tenant_id = ?", tenantId).Order("version desc").All(&versions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (cp *configVersionPersister) Get(tenantId uuid.UUID, version int) (*models.ConfigVersion, error) {
+	configVersion := models.ConfigVersion{}
+	err := cp.database.Where("tenant_id = ? AND version = ?", tenantId, version).First(&configVersion)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config version: %w", err)
+	}
+
+	return &configVersion, nil
+}
+
+func (cp *configVersionPersister) GetLatestVersion(tenantId uuid.UUID) (int, error) {
+	configVersion := models.ConfigVersion{}
+	err := cp.database.Where("tenant_id = ?", tenantId).Order("version desc").First(&configVersion)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest config version: %w", err)
+	}
+
+	return configVersion.Version, nil
+}
@@ -0,0 +1,72 @@
+package persisters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+type IdempotencyKeyPersister interface {
+	Create(key *models.IdempotencyKey) error
+	Get(key string, operation string) (*models.IdempotencyKey, error)
+	Delete(key *models.IdempotencyKey) error
+	DeleteCreatedBefore(before time.Time) (int, error)
+}
+
+type idempotencyKeyPersister struct {
+	database *pop.Connection
+}
+
+func NewIdempotencyKeyPersister(database *pop.Connection) IdempotencyKeyPersister {
+	return &idempotencyKeyPersister{database: database}
+}
+
+func (ip *idempotencyKeyPersister) Create(key *models.IdempotencyKey) error {
+	validationErr, err := ip.database.ValidateAndCreate(key)
+	if err != nil {
+		return fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+
+	if validationErr != nil && validationErr.HasAny() {
+		return fmt.Errorf("idempotency key validation failed: %w", validationErr)
+	}
+
+	return nil
+}
+
+func (ip *idempotencyKeyPersister) Get(key string, operation string) (*models.IdempotencyKey, error) {
+	idempotencyKey := models.IdempotencyKey{}
+	err := ip.database.Where("idempotency_key = ? AND operation = ?", key, operation).First(&idempotencyKey)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &idempotencyKey, nil
+}
+
+func (ip *idempotencyKeyPersister) Delete(key *models.IdempotencyKey) error {
+	err := ip.database.Destroy(key)
+	if err != nil {
+		return fmt.Errorf("failed to delete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteCreatedBefore deletes all keys created before the given time and returns the number of deleted keys
+func (ip *idempotencyKeyPersister) DeleteCreatedBefore(before time.Time) (int, error) {
+	count, err := ip.database.RawQuery("DELETE FROM idempotency_keys WHERE created_at < ?", before).ExecWithCount()
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	return count, nil
+}
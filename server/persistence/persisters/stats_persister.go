@@ -0,0 +1,233 @@
+package persisters
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+// StatsPersister aggregates audit logs and credentials for the stats API. Audit logs are always counted in hourly
+// buckets, larger intervals are summed up afterwards.
+type StatsPersister interface {
+	CountAuditLogsByHour(tenantId uuid.UUID, start time.Time, end time.Time) ([]models.AuditLogTypeCount, error)
+	CountActiveUsers(tenantId uuid.UUID, start time.Time, end time.Time, types []models.AuditLogType) (int, error)
+	CountCredentialsByAaguid(tenantId uuid.UUID, start time.Time, end time.Time) ([]models.CredentialAaguidCount, error)
+	CountBackupEligibleCredentials(tenantId uuid.UUID, start time.Time, end time.Time) (int, int, error)
+	ListRollups(tenantId uuid.UUID, start time.Time, end time.Time) ([]models.AuditLogTypeCount, error)
+	GetLastRollupBucket() (*time.Time, error)
+	GetFirstAuditLogTime() (*time.Time, error)
+	RollUp(from time.Time, to time.Time) (int, error)
+	// CountSessionData and CountAllCredentialsByAaguid count over all tenants for the metrics of the public server
+	CountSessionData() ([]models.SessionDataCount, error)
+	CountAllCredentialsByAaguid() ([]models.CredentialAaguidCount, error)
+}
+
+type statsPersister struct {
+	database *pop.Connection
+}
+
+func NewStatsPersister(database *pop.Connection) StatsPersister {
+	return &statsPersister{database: database}
+}
+
+type statsCount struct {
+	Count int `db:"count"`
+}
+
+type statsTime struct {
+	Time *time.Time `db:"time"`
+}
+
+// hourBucket truncates the creation time to the hour in the dialect of the database
+func (p *statsPersister) hourBucket() string {
+	switch p.database.Dialect.Name() {
+	case "mysql", "mariadb":
+		return "TIMESTAMP(DATE_FORMAT(created_at, '%Y-%m-%d %H:00:00'))"
+	default:
+		return "date_trunc('hour', created_at)"
+	}
+}
+
+func (p *statsPersister) CountAuditLogsByHour(tenantId uuid.UUID, start time.Time, end time.Time) ([]models.AuditLogTypeCount, error) {
+	counts := make([]models.AuditLogTypeCount, 0)
+	query := fmt.Sprintf(
+		"SELECT tenant_id, %s AS bucket, type, COUNT(*) AS count FROM audit_logs "+
+			"WHERE tenant_id = ? AND created_at >= ? AND created_at < ? GROUP BY tenant_id, bucket, type",
+		p.hourBucket(),
+	)
+
+	err := p.database.RawQuery(query, tenantId, start, end).All(&counts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (p *statsPersister) CountActiveUsers(tenantId uuid.UUID, start time.Time, end time.Time, types []models.AuditLogType) (int, error) {
+	args := []interface{}{tenantId, start, end}
+	for _, auditLogType := range types {
+		args = append(args, string(auditLogType))
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(types)), ",")
+	query := fmt.Sprintf(
+		"SELECT COUNT(DISTINCT actor_user_id) AS count FROM audit_logs "+
+			"WHERE tenant_id = ? AND created_at >= ? AND created_at < ? AND actor_user_id IS NOT NULL AND type IN (%s)",
+		placeholders,
+	)
+
+	var result statsCount
+	err := p.database.RawQuery(query, args...).First(&result)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to count active users: %w", err)
+	}
+
+	return result.Count, nil
+}
+
+func (p *statsPersister) CountCredentialsByAaguid(tenantId uuid.UUID, start time.Time, end time.Time) ([]models.CredentialAaguidCount, error) {
+	counts := make([]models.CredentialAaguidCount, 0)
+	err := p.database.RawQuery(
+		"SELECT c.aaguid AS aaguid, COUNT(*) AS count FROM webauthn_credentials c "+
+			"JOIN webauthn_users u ON u.id = c.webauthn_user_id "+
+			"WHERE u.tenant_id = ? AND c.created_at >= ? AND c.created_at < ? GROUP BY c.aaguid ORDER BY count DESC",
+		tenantId, start, end,
+	).All(&counts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to count credentials: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (p *statsPersister) CountAllCredentialsByAaguid() ([]models.CredentialAaguidCount, error) {
+	counts := make([]models.CredentialAaguidCount, 0)
+	err := p.database.RawQuery(
+		"SELECT u.tenant_id AS tenant_id, c.aaguid AS aaguid, COUNT(*) AS count FROM webauthn_credentials c " +
+			"JOIN webauthn_users u ON u.id = c.webauthn_user_id GROUP BY u.tenant_id, c.aaguid",
+	).All(&counts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to count credentials: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (p *statsPersister) CountSessionData() ([]models.SessionDataCount, error) {
+	counts := make([]models.SessionDataCount, 0)
+	err := p.database.RawQuery(
+		"SELECT tenant_id, operation, COUNT(*) AS count FROM webauthn_session_data GROUP BY tenant_id, operation",
+	).All(&counts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to count session data: %w", err)
+	}
+
+	return counts, nil
+}
+
+// CountBackupEligibleCredentials returns the number of all credentials and of the backup eligible credentials
+// registered in the time range
+func (p *statsPersister) CountBackupEligibleCredentials(tenantId uuid.UUID, start time.Time, end time.Time) (int, int, error) {
+	var result struct {
+		Total          int `db:"total"`
+		BackupEligible int `db:"backup_eligible"`
+	}
+
+	err := p.database.RawQuery(
+		"SELECT COUNT(*) AS total, COALESCE(SUM(CASE WHEN c.backup_eligible THEN 1 ELSE 0 END), 0) AS backup_eligible "+
+			"FROM webauthn_credentials c JOIN webauthn_users u ON u.id = c.webauthn_user_id "+
+			"WHERE u.tenant_id = ? AND c.created_at >= ? AND c.created_at < ?",
+		tenantId, start, end,
+	).First(&result)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, fmt.Errorf("failed to count backup eligible credentials: %w", err)
+	}
+
+	return result.Total, result.BackupEligible, nil
+}
+
+func (p *statsPersister) ListRollups(tenantId uuid.UUID, start time.Time, end time.Time) ([]models.AuditLogTypeCount, error) {
+	counts := make([]models.AuditLogTypeCount, 0)
+	err := p.database.RawQuery(
+		"SELECT tenant_id, bucket, type, count FROM audit_log_rollups WHERE tenant_id = ? AND bucket >= ? AND bucket < ?",
+		tenantId, start, end,
+	).All(&counts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to list audit log rollups: %w", err)
+	}
+
+	return counts, nil
+}
+
+// GetLastRollupBucket returns the start of the latest hour which was rolled up or nil if there are no rollups
+func (p *statsPersister) GetLastRollupBucket() (*time.Time, error) {
+	var result statsTime
+	err := p.database.RawQuery("SELECT MAX(bucket) AS time FROM audit_log_rollups").First(&result)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get last audit log rollup: %w", err)
+	}
+
+	return result.Time, nil
+}
+
+func (p *statsPersister) GetFirstAuditLogTime() (*time.Time, error) {
+	var result statsTime
+	err := p.database.RawQuery("SELECT MIN(created_at) AS time FROM audit_logs WHERE tenant_id IS NOT NULL").First(&result)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get first audit log: %w", err)
+	}
+
+	return result.Time, nil
+}
+
+// RollUp stores the hourly counts of the audit logs of all tenants created between from (inclusive) and to
+// (exclusive). Both have to be full hours which were not rolled up before.
+func (p *statsPersister) RollUp(from time.Time, to time.Time) (int, error) {
+	counts := make([]models.AuditLogTypeCount, 0)
+	query := fmt.Sprintf(
+		"SELECT tenant_id, %s AS bucket, type, COUNT(*) AS count FROM audit_logs "+
+			"WHERE tenant_id IS NOT NULL AND created_at >= ? AND created_at < ? GROUP BY tenant_id, bucket, type",
+		p.hourBucket(),
+	)
+
+	err := p.database.RawQuery(query, from, to).All(&counts)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("failed to count audit logs: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, count := range counts {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return 0, fmt.Errorf("failed to create id: %w", err)
+		}
+
+		rollup := models.AuditLogRollup{
+			ID:        id,
+			TenantID:  count.TenantID,
+			Bucket:    count.Bucket,
+			Type:      count.Type,
+			Count:     count.Count,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+
+		validationErr, err := p.database.ValidateAndCreate(&rollup)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store audit log rollup: %w", err)
+		}
+
+		if validationErr != nil && validationErr.HasAny() {
+			return 0, fmt.Errorf("audit log rollup validation failed: %w", validationErr)
+		}
+	}
+
+	return len(counts), nil
+}
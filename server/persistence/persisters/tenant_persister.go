@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
@@ -26,6 +27,8 @@ func NewTenantPersister(database *pop.Connection) TenantPersister {
 	return &tenantPersister{database: database}
 }
 
+// Create stores the tenant together with the empty head of its audit log chain, so appending entries to the chain
+// only has to lock an existing row
 func (t tenantPersister) Create(tenant *models.Tenant) error {
 	validationErr, err := t.database.ValidateAndCreate(tenant)
 	if err != nil {
@@ -36,6 +39,16 @@ func (t tenantPersister) Create(tenant *models.Tenant) error {
 		return fmt.Errorf("tenant validation failed: %w", validationErr)
 	}
 
+	now := time.Now().UTC()
+	err = t.database.Create(&models.AuditLogChain{
+		ID:        models.AuditLogChainId(&tenant.ID),
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store audit log chain: %w", err)
+	}
+
 	return nil
 }
 
@@ -43,7 +56,9 @@ func (t tenantPersister) Get(tenantId uuid.UUID) (*models.Tenant, error) {
 	tenant := models.Tenant{}
 	err := t.database.Eager(
 		"Config.Secrets",
-		"Config.WebauthnConfig.RelyingParty.Origins",
+		"Config.WebauthnConfig.RelyingParties.Origins",
+		"Config.WebauthnConfig.RelyingParties.MobileApps",
+		"Config.WebauthnConfig.TransportStrippingRules",
 		"Config.MfaConfig",
 		"Config.Cors.Origins",
 		"Config.AuditLogConfig",
@@ -94,5 +109,11 @@ func (t tenantPersister) Delete(tenant *models.Tenant) error {
 		return fmt.Errorf("failed to delete tenant: %w", err)
 	}
 
+	// the audit logs of the tenant are deleted by the database, so its chain starts over if the tenant is imported again
+	err = t.database.RawQuery("DELETE FROM audit_log_chains WHERE id = ?", models.AuditLogChainId(&tenant.ID)).Exec()
+	if err != nil {
+		return fmt.Errorf("failed to delete audit log chain: %w", err)
+	}
+
 	return nil
 }
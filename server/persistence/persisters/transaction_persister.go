@@ -1,6 +1,7 @@
 package persisters
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -16,6 +17,9 @@ type TransactionPersister interface {
 	ListByUserId(userId uuid.UUID, tenantId uuid.UUID) (*models.Transactions, error)
 	GetByUserId(userId uuid.UUID, tenantId uuid.UUID) (*models.Transaction, error)
 	GetByChallenge(challenge string, tenantId uuid.UUID) (*models.Transaction, error)
+	GetAllForTenant(tenantId uuid.UUID) (models.Transactions, error)
+	// WithContext returns a persister whose queries are bound to the context, e.g. to trace them as part of its span
+	WithContext(ctx context.Context) TransactionPersister
 }
 
 type transactionPersister struct {
@@ -28,6 +32,10 @@ func NewTransactionPersister(database *pop.Connection) TransactionPersister {
 	}
 }
 
+func (p *transactionPersister) WithContext(ctx context.Context) TransactionPersister {
+	return &transactionPersister{database: p.database.WithContext(ctx)}
+}
+
 func (p *transactionPersister) Create(transaction *models.Transaction) error {
 	vErr, err := p.database.ValidateAndCreate(transaction)
 	if err != nil {
@@ -91,3 +99,17 @@ func (p *transactionPersister) GetByChallenge(challenge string, tenantId uuid.UU
 
 	return &transaction, nil
 }
+
+func (p *transactionPersister) GetAllForTenant(tenantId uuid.UUID) (models.Transactions, error) {
+	transactions := models.Transactions{}
+	err := p.database.Where("tenant_id = ?", tenantId).Order("created_at asc").All(&transactions)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return transactions, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transactions for tenant: %w", err)
+	}
+
+	return transactions, nil
+}
@@ -20,7 +20,7 @@ func NewWebauthnConfigPersister(database *pop.Connection) WebauthnConfigPersiste
 }
 
 func (wp *webauthnConfigPersister) Create(webauthnConfigModel *models.WebauthnConfig) error {
-	validationErr, err := wp.database.ValidateAndCreate(webauthnConfigModel)
+	validationErr, err := wp.database.Eager().ValidateAndCreate(webauthnConfigModel)
 	if err != nil {
 		return fmt.Errorf("failed to store webauthnConfigModel: %w", err)
 	}
@@ -1,9 +1,12 @@
 package persisters
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
 
@@ -16,7 +19,11 @@ type WebauthnCredentialPersister interface {
 	Create(credential *models.WebauthnCredential) error
 	Update(credential *models.WebauthnCredential) error
 	Delete(credential *models.WebauthnCredential) error
-	GetFromUser(string, uuid.UUID) ([]models.WebauthnCredential, error)
+	List(options WebauthnCredentialOptions) ([]models.WebauthnCredential, error)
+	Count(options WebauthnCredentialOptions) (int, error)
+	ListByIds(ids []string) ([]models.WebauthnCredential, error)
+	// WithContext returns a persister whose queries are bound to the context, e.g. to trace them as part of its span
+	WithContext(ctx context.Context) WebauthnCredentialPersister
 }
 
 type webauthnCredentialPersister struct {
@@ -29,6 +36,10 @@ func NewWebauthnCredentialPersister(database *pop.Connection) WebauthnCredential
 	}
 }
 
+func (w *webauthnCredentialPersister) WithContext(ctx context.Context) WebauthnCredentialPersister {
+	return &webauthnCredentialPersister{database: w.database.WithContext(ctx)}
+}
+
 func (w *webauthnCredentialPersister) Get(id string, tenantId uuid.UUID) (*models.WebauthnCredential, error) {
 	credential := models.WebauthnCredential{}
 	err := w.database.
@@ -92,15 +103,142 @@ func (w *webauthnCredentialPersister) Delete(credential *models.WebauthnCredenti
 	return nil
 }
 
-func (w *webauthnCredentialPersister) GetFromUser(userId string, tenantId uuid.UUID) ([]models.WebauthnCredential, error) {
-	var credentials []models.WebauthnCredential
-	err := w.database.Eager().
-		Where("webauthn_credentials.user_id = ? AND u.tenant_id = ?", &userId, tenantId).
-		LeftJoin("webauthn_users u", "u.id = webauthn_credentials.webauthn_user_id").
-		Order("created_at asc").All(&credentials)
+type WebauthnCredentialSortField string
+
+const (
+	WebauthnCredentialSortCreatedAt  WebauthnCredentialSortField = "created_at"
+	WebauthnCredentialSortLastUsedAt WebauthnCredentialSortField = "last_used_at"
+	WebauthnCredentialSortName       WebauthnCredentialSortField = "name"
+)
+
+type WebauthnCredentialType string
+
+const (
+	WebauthnCredentialTypePasskey WebauthnCredentialType = "passkey"
+	WebauthnCredentialTypeMFA     WebauthnCredentialType = "mfa"
+)
+
+type WebauthnCredentialOptions struct {
+	TenantId uuid.UUID
+	UserId   string
+	Page     int
+	// PerPage disables the pagination if it is 0
+	PerPage       int
+	SortBy        WebauthnCredentialSortField
+	SortDirection string
+
+	Type           WebauthnCredentialType
+	BackupEligible *bool
+	BackupState    *bool
+	AAGUID         *uuid.UUID
+	LastUsedBefore *time.Time
+	LastUsedAfter  *time.Time
+}
+
+// List returns a page of the credentials of a user which match the options
+func (w *webauthnCredentialPersister) List(options WebauthnCredentialOptions) ([]models.WebauthnCredential, error) {
+	credentials := make([]models.WebauthnCredential, 0)
+
+	query := w.addOptionsToSqlQuery(w.database.Q().Eager(), options).Order(w.getOrder(options))
+	if options.PerPage > 0 {
+		query = query.Paginate(options.Page, options.PerPage)
+	}
+
+	err := query.All(&credentials)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return credentials, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+func (w *webauthnCredentialPersister) Count(options WebauthnCredentialOptions) (int, error) {
+	query := w.addOptionsToSqlQuery(w.database.Q(), options)
+	count, err := query.Count(&models.WebauthnCredential{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get credential count: %w", err)
+	}
+
+	return count, nil
+}
+
+func (w *webauthnCredentialPersister) addOptionsToSqlQuery(query *pop.Query, options WebauthnCredentialOptions) *pop.Query {
+	query = query.
+		Where("webauthn_credentials.user_id = ? AND u.tenant_id = ?", options.UserId, options.TenantId).
+		LeftJoin("webauthn_users u", "u.id = webauthn_credentials.webauthn_user_id")
+
+	switch options.Type {
+	case WebauthnCredentialTypePasskey:
+		query = query.Where("webauthn_credentials.is_mfa = ?", false)
+	case WebauthnCredentialTypeMFA:
+		query = query.Where("webauthn_credentials.is_mfa = ?", true)
+	}
+
+	if options.BackupEligible != nil {
+		query = query.Where("webauthn_credentials.backup_eligible = ?", *options.BackupEligible)
+	}
+
+	if options.BackupState != nil {
+		query = query.Where("webauthn_credentials.backup_state = ?", *options.BackupState)
+	}
+
+	if options.AAGUID != nil {
+		query = query.Where("webauthn_credentials.aaguid = ?", *options.AAGUID)
+	}
+
+	if options.LastUsedBefore != nil {
+		query = query.Where("webauthn_credentials.last_used_at < ?", *options.LastUsedBefore)
+	}
+
+	if options.LastUsedAfter != nil {
+		query = query.Where("webauthn_credentials.last_used_at > ?", *options.LastUsedAfter)
+	}
+
+	return query
+}
+
+func (w *webauthnCredentialPersister) getOrder(options WebauthnCredentialOptions) string {
+	direction := "asc"
+	if strings.ToLower(options.SortDirection) == "desc" {
+		direction = "desc"
+	}
+
+	sortBy := options.SortBy
+	switch sortBy {
+	case WebauthnCredentialSortLastUsedAt, WebauthnCredentialSortName:
+		// credentials without a value are sorted as the smallest values on all dialects
+		nullDirection := "desc"
+		if direction == "desc" {
+			nullDirection = "asc"
+		}
+
+		return fmt.Sprintf("(webauthn_credentials.%s IS NULL) %s, webauthn_credentials.%s %s, webauthn_credentials.id %s", sortBy, nullDirection, sortBy, direction, direction)
+	default:
+		return fmt.Sprintf("webauthn_credentials.created_at %s, webauthn_credentials.id %s", direction, direction)
+	}
+}
+
+// ListByIds returns the credentials with the given IDs regardless of their tenant
+func (w *webauthnCredentialPersister) ListByIds(ids []string) ([]models.WebauthnCredential, error) {
+	credentials := make([]models.WebauthnCredential, 0)
+	if len(ids) == 0 {
+		return credentials, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	err := w.database.Where("id IN (?)", args...).All(&credentials)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return credentials, nil
 	}
+
 	if err != nil {
 		return nil, fmt.Errorf("failed to get credentials: %w", err)
 	}
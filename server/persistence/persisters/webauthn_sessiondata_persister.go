@@ -1,6 +1,7 @@
 package persisters
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -14,6 +15,13 @@ type WebauthnSessionDataPersister interface {
 	GetByChallenge(challenge string, tenantId uuid.UUID) (*models.WebauthnSessionData, error)
 	Create(sessionData models.WebauthnSessionData) error
 	Delete(sessionData models.WebauthnSessionData) error
+	ListByUserId(userId string, tenantId uuid.UUID) ([]models.WebauthnSessionData, error)
+	DeleteByUserId(userId string, tenantId uuid.UUID) error
+	// DeleteByChallenge returns whether session data with the challenge was deleted, so concurrent requests cannot both
+	// use it
+	DeleteByChallenge(challenge string, tenantId uuid.UUID, operation models.Operation) (bool, error)
+	// WithContext returns a persister whose queries are bound to the context, e.g. to trace them as part of its span
+	WithContext(ctx context.Context) WebauthnSessionDataPersister
 }
 
 type sessionDataPersister struct {
@@ -24,6 +32,10 @@ func NewWebauthnSessionDataPersister(db *pop.Connection) WebauthnSessionDataPers
 	return &sessionDataPersister{database: db}
 }
 
+func (ws *sessionDataPersister) WithContext(ctx context.Context) WebauthnSessionDataPersister {
+	return &sessionDataPersister{database: ws.database.WithContext(ctx)}
+}
+
 func (ws *sessionDataPersister) GetByChallenge(challenge string, tenantId uuid.UUID) (*models.WebauthnSessionData, error) {
 	var sessionData []models.WebauthnSessionData
 	err := ws.database.Eager().Where("challenge = ? AND tenant_id = ?", challenge, tenantId).All(&sessionData)
@@ -62,3 +74,38 @@ func (ws *sessionDataPersister) Delete(sessionData models.WebauthnSessionData) e
 
 	return nil
 }
+
+func (ws *sessionDataPersister) ListByUserId(userId string, tenantId uuid.UUID) ([]models.WebauthnSessionData, error) {
+	sessionData := make([]models.WebauthnSessionData, 0)
+	err := ws.database.Eager().Where("user_id = ? AND tenant_id = ?", userId, tenantId).Order("created_at asc").All(&sessionData)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return sessionData, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessionData: %w", err)
+	}
+
+	return sessionData, nil
+}
+
+func (ws *sessionDataPersister) DeleteByUserId(userId string, tenantId uuid.UUID) error {
+	err := ws.database.RawQuery("DELETE FROM webauthn_session_data WHERE user_id = ? AND tenant_id = ?", userId, tenantId).Exec()
+	if err != nil {
+		return fmt.Errorf("failed to delete sessionData: %w", err)
+	}
+
+	return nil
+}
+
+func (ws *sessionDataPersister) DeleteByChallenge(challenge string, tenantId uuid.UUID, operation models.Operation) (bool, error) {
+	count, err := ws.database.RawQuery(
+		"DELETE FROM webauthn_session_data WHERE challenge = ? AND tenant_id = ? AND operation = ?",
+		challenge, tenantId, operation,
+	).ExecWithCount()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete sessionData: %w", err)
+	}
+
+	return count > 0, nil
+}
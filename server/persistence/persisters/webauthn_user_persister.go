@@ -1,9 +1,12 @@
 package persisters
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
@@ -12,12 +15,16 @@ import (
 
 type WebauthnUserPersister interface {
 	Create(webauthnUser *models.WebauthnUser) error
-	AllForTenant(tenantId uuid.UUID, page int, perPage int, sort string) (models.WebauthnUsers, error)
-	Count(tenantId uuid.UUID) (int, error)
+	AllForTenant(options WebauthnUserOptions) (models.WebauthnUsers, error)
+	GetAllForTenant(tenantId uuid.UUID) (models.WebauthnUsers, error)
+	Count(options WebauthnUserOptions) (int, error)
 	GetById(id uuid.UUID) (*models.WebauthnUser, error)
 	GetByUserId(userId string, tenantId uuid.UUID) (*models.WebauthnUser, error)
 	Update(webauthnUser *models.WebauthnUser) error
 	Delete(user *models.WebauthnUser) error
+	UpdateLastLogin(id uuid.UUID, lastLoginAt time.Time) error
+	// WithContext returns a persister whose queries are bound to the context, e.g. to trace them as part of its span
+	WithContext(ctx context.Context) WebauthnUserPersister
 }
 
 type webauthnUserPersister struct {
@@ -30,6 +37,10 @@ func NewWebauthnUserPersister(database *pop.Connection) WebauthnUserPersister {
 	}
 }
 
+func (p *webauthnUserPersister) WithContext(ctx context.Context) WebauthnUserPersister {
+	return &webauthnUserPersister{database: p.database.WithContext(ctx)}
+}
+
 func (p *webauthnUserPersister) Create(webauthnUser *models.WebauthnUser) error {
 	vErr, err := p.database.ValidateAndCreate(webauthnUser)
 	if err != nil {
@@ -42,12 +53,50 @@ func (p *webauthnUserPersister) Create(webauthnUser *models.WebauthnUser) error
 	return nil
 }
 
-func (p *webauthnUserPersister) AllForTenant(tenantId uuid.UUID, page int, perPage int, sort string) (models.WebauthnUsers, error) {
+type WebauthnUserSortField string
+
+const (
+	WebauthnUserSortCreatedAt   WebauthnUserSortField = "created_at"
+	WebauthnUserSortLastLoginAt WebauthnUserSortField = "last_login_at"
+	WebauthnUserSortUserId      WebauthnUserSortField = "user_id"
+	WebauthnUserSortName        WebauthnUserSortField = "name"
+	WebauthnUserSortDisplayName WebauthnUserSortField = "display_name"
+)
+
+type WebauthnUserCredentialFilter string
+
+const (
+	WebauthnUserWithoutCredentials WebauthnUserCredentialFilter = "none"
+	WebauthnUserOnlyMFACredentials WebauthnUserCredentialFilter = "mfa_only"
+)
+
+type WebauthnUserOptions struct {
+	TenantId      uuid.UUID
+	Page          int
+	PerPage       int
+	SortBy        WebauthnUserSortField
+	SortDirection string
+
+	// UserId matches the user id exactly
+	UserId string
+	// Name and DisplayName match prefixes
+	Name        string
+	DisplayName string
+	// Search matches a prefix of the user id, name or display name
+	Search string
+
+	Credentials WebauthnUserCredentialFilter
+	// UnusedSince only returns users with credentials, of which none was used after the given time
+	UnusedSince *time.Time
+}
+
+func (p *webauthnUserPersister) AllForTenant(options WebauthnUserOptions) (models.WebauthnUsers, error) {
 	webauthnUsers := models.WebauthnUsers{}
-	err := p.database.
-		Where("tenant_id = ?", tenantId).
-		Order(fmt.Sprintf("webauthn_users.created_at %s", sort)).
-		Paginate(page, perPage).
+
+	query := p.addOptionsToSqlQuery(p.database.Q(), options)
+	err := query.
+		Order(p.getOrder(options)).
+		Paginate(options.Page, options.PerPage).
 		All(&webauthnUsers)
 
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
@@ -55,12 +104,91 @@ func (p *webauthnUserPersister) AllForTenant(tenantId uuid.UUID, page int, perPa
 	}
 
 	if err != nil {
-		return webauthnUsers, fmt.Errorf("failed to get webauthn users for tenant %w", err)
+		return webauthnUsers, fmt.Errorf("failed to get webauthn users for tenant: %w", err)
 	}
 
 	return webauthnUsers, nil
 }
 
+func (p *webauthnUserPersister) addOptionsToSqlQuery(query *pop.Query, options WebauthnUserOptions) *pop.Query {
+	query = query.Where("webauthn_users.tenant_id = ?", options.TenantId)
+
+	if len(options.UserId) > 0 {
+		query = query.Where("webauthn_users.user_id = ?", options.UserId)
+	}
+
+	if len(options.Name) > 0 {
+		query = query.Where("webauthn_users.name LIKE ?", likePrefix(options.Name))
+	}
+
+	if len(options.DisplayName) > 0 {
+		query = query.Where("webauthn_users.display_name LIKE ?", likePrefix(options.DisplayName))
+	}
+
+	if len(options.Search) > 0 {
+		arg := likePrefix(options.Search)
+		query = query.Where("(webauthn_users.user_id LIKE ? OR webauthn_users.name LIKE ? OR webauthn_users.display_name LIKE ?)", arg, arg, arg)
+	}
+
+	// the subqueries are served by the indexes on webauthn_user_id of webauthn_credentials
+	hasCredentials := "EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.webauthn_user_id = webauthn_users.id)"
+	switch options.Credentials {
+	case WebauthnUserWithoutCredentials:
+		query = query.Where("NOT " + hasCredentials)
+	case WebauthnUserOnlyMFACredentials:
+		query = query.
+			Where(hasCredentials).
+			Where("NOT EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.webauthn_user_id = webauthn_users.id AND c.is_mfa = ?)", false)
+	}
+
+	if options.UnusedSince != nil {
+		query = query.
+			Where(hasCredentials).
+			Where("NOT EXISTS (SELECT 1 FROM webauthn_credentials c WHERE c.webauthn_user_id = webauthn_users.id AND c.last_used_at >= ?)", options.UnusedSince)
+	}
+
+	return query
+}
+
+func (p *webauthnUserPersister) getOrder(options WebauthnUserOptions) string {
+	direction := "desc"
+	if strings.ToLower(options.SortDirection) == "asc" {
+		direction = "asc"
+	}
+
+	sortBy := options.SortBy
+	switch sortBy {
+	case WebauthnUserSortLastLoginAt:
+		// users who never logged in are treated as the oldest logins on all dialects
+		nullDirection := "asc"
+		if direction == "asc" {
+			nullDirection = "desc"
+		}
+
+		return fmt.Sprintf("(webauthn_users.last_login_at IS NULL) %s, webauthn_users.last_login_at %s, webauthn_users.id %s", nullDirection, direction, direction)
+	case WebauthnUserSortUserId, WebauthnUserSortName, WebauthnUserSortDisplayName:
+	default:
+		sortBy = WebauthnUserSortCreatedAt
+	}
+
+	return fmt.Sprintf("webauthn_users.%s %s, webauthn_users.id %s", sortBy, direction, direction)
+}
+
+// likePrefix escapes the wildcards of the value, so it only matches as a prefix
+func likePrefix(value string) string {
+	return escapeLike(value) + "%"
+}
+
+// likeContains escapes the wildcards of the value, so it only matches as a substring
+func likeContains(value string) string {
+	return "%" + escapeLike(value) + "%"
+}
+
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_")
+	return replacer.Replace(value)
+}
+
 func (p *webauthnUserPersister) GetById(id uuid.UUID) (*models.WebauthnUser, error) {
 	webauthnUser := models.WebauthnUser{}
 	err := p.database.Eager().Find(&webauthnUser, id)
@@ -77,7 +205,10 @@ func (p *webauthnUserPersister) GetById(id uuid.UUID) (*models.WebauthnUser, err
 
 func (p *webauthnUserPersister) GetByUserId(userId string, tenantId uuid.UUID) (*models.WebauthnUser, error) {
 	webauthnUser := models.WebauthnUser{}
-	err := p.database.Eager().Where("user_id = ? AND tenant_id = ?", userId, tenantId).First(&webauthnUser)
+	err := p.database.
+		Eager("Tenant", "WebauthnCredentials.Transports", "Transactions").
+		Where("user_id = ? AND tenant_id = ?", userId, tenantId).
+		First(&webauthnUser)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
@@ -110,11 +241,41 @@ func (p *webauthnUserPersister) Delete(user *models.WebauthnUser) error {
 	return nil
 }
 
-func (p *webauthnUserPersister) Count(tenantId uuid.UUID) (int, error) {
-	count, err := p.database.Where("tenant_id = ?", tenantId).Count(&models.WebauthnUser{})
+func (p *webauthnUserPersister) Count(options WebauthnUserOptions) (int, error) {
+	query := p.addOptionsToSqlQuery(p.database.Q(), options)
+	count, err := query.Count(&models.WebauthnUser{})
 	if err != nil {
 		return 0, fmt.Errorf("failed to get user count: %w", err)
 	}
 
 	return count, nil
 }
+
+func (p *webauthnUserPersister) UpdateLastLogin(id uuid.UUID, lastLoginAt time.Time) error {
+	err := p.database.RawQuery("UPDATE webauthn_users SET last_login_at = ? WHERE id = ?", lastLoginAt, id).Exec()
+	if err != nil {
+		return fmt.Errorf("failed to update last login of webauthn user: %w", err)
+	}
+
+	return nil
+}
+
+// GetAllForTenant returns all users of the tenant including their credentials
+func (p *webauthnUserPersister) GetAllForTenant(tenantId uuid.UUID) (models.WebauthnUsers, error) {
+	webauthnUsers := models.WebauthnUsers{}
+	err := p.database.
+		Eager("WebauthnCredentials.Transports").
+		Where("tenant_id = ?", tenantId).
+		Order("created_at asc").
+		All(&webauthnUsers)
+
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return webauthnUsers, nil
+	}
+
+	if err != nil {
+		return webauthnUsers, fmt.Errorf("failed to get webauthn users for tenant: %w", err)
+	}
+
+	return webauthnUsers, nil
+}
@@ -0,0 +1,163 @@
+package provisioning
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/knadh/koanf/parsers/yaml"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/validators"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ApiKeyDefinition describes an API key of a tenant. Only the metadata is declared, the key itself is generated.
+type ApiKeyDefinition struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// TenantDefinition describes the desired state of a tenant. It uses the same format and validation as the request
+// body of 'POST /tenants' of the admin API, but the ID is mandatory, so a tenant can be matched against the database.
+type TenantDefinition struct {
+	request.CreateTenantDto
+	ApiKeys []ApiKeyDefinition `json:"api_keys" validate:"omitempty,unique=Name,dive"`
+}
+
+type TenantsFile struct {
+	Tenants []TenantDefinition `json:"tenants" validate:"dive"`
+}
+
+// LoadTenants reads and validates the tenant definitions from a file or from all YAML files in a directory
+func LoadTenants(path string) ([]TenantDefinition, error) {
+	files, err := findFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make([]TenantDefinition, 0)
+	tenantFiles := make(map[string]string)
+	for _, file := range files {
+		tenantsFile, err := loadFile(file)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, definition := range tenantsFile.Tenants {
+			if otherFile, ok := tenantFiles[*definition.Id]; ok {
+				return nil, fmt.Errorf("tenant '%s' is defined in '%s' and '%s'", *definition.Id, otherFile, file)
+			}
+
+			tenantFiles[*definition.Id] = file
+			definitions = append(definitions, definition)
+		}
+	}
+
+	return definitions, nil
+}
+
+func findFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read '%s': %w", path, err)
+	}
+
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read directory '%s': %w", path, err)
+	}
+
+	files := make([]string, 0)
+	for _, entry := range entries {
+		extension := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (extension != ".yaml" && extension != ".yml") {
+			continue
+		}
+
+		files = append(files, filepath.Join(path, entry.Name()))
+	}
+
+	sort.Strings(files)
+
+	return files, nil
+}
+
+func loadFile(file string) (*TenantsFile, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read '%s': %w", file, err)
+	}
+
+	values, err := yaml.Parser().Unmarshal(content)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %w", file, err)
+	}
+
+	// the DTOs only know their JSON representation
+	jsonContent, err := json.Marshal(values)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %w", file, err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(jsonContent))
+	decoder.DisallowUnknownFields()
+
+	var tenantsFile TenantsFile
+	err = decoder.Decode(&tenantsFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse '%s': %w", file, err)
+	}
+
+	validator := validators.NewCustomValidator()
+	for i, definition := range tenantsFile.Tenants {
+		err = validateDefinition(validator, definition)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %d in '%s' is invalid: %w", i+1, file, err)
+		}
+	}
+
+	return &tenantsFile, nil
+}
+
+func validateDefinition(validator *validators.CustomValidator, definition TenantDefinition) error {
+	if definition.Id == nil {
+		return errors.New("id is a required field")
+	}
+
+	if definition.CreateApiKey {
+		return errors.New("create_api_key is not supported, use api_keys instead")
+	}
+
+	err := validator.Validate(&definition)
+	if err != nil {
+		var httpError *echo.HTTPError
+		if errors.As(err, &httpError) {
+			return fmt.Errorf("%v", httpError.Message)
+		}
+
+		return err
+	}
+
+	if !definition.Config.Passkey.HasUniqueRelyingPartyIds() {
+		return errors.New("relying party ids must be unique")
+	}
+
+	return nil
+}
+
+func (definition *TenantDefinition) hasApiKey(name string) bool {
+	for _, apiKey := range definition.ApiKeys {
+		if apiKey.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,32 @@
+package provisioning
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLoadTenants_Directory(t *testing.T) {
+	definitions, err := LoadTenants("./testdata/tenants")
+	assert.NoError(t, err)
+	assert.Len(t, definitions, 2)
+
+	assert.Equal(t, "6a7c4b5e-0f1d-4c2a-9b8e-3d2f1a0b9c8d", *definitions[0].Id)
+	assert.Equal(t, "example.com", definitions[0].Config.Passkey.RelyingParty.Id)
+	assert.True(t, definitions[0].Config.Mfa.RequireForLogin)
+	assert.Equal(t, "stdout", definitions[0].Config.AuditLog.OutputStream)
+	assert.Equal(t, []ApiKeyDefinition{{Name: "provisioning"}}, definitions[0].ApiKeys)
+
+	assert.Equal(t, "Other", definitions[1].DisplayName)
+	assert.Nil(t, definitions[1].Config.Mfa)
+}
+
+func TestLoadTenants_File(t *testing.T) {
+	definitions, err := LoadTenants("./testdata/tenants/other.yml")
+	assert.NoError(t, err)
+	assert.Len(t, definitions, 1)
+}
+
+func TestLoadTenants_Invalid(t *testing.T) {
+	_, err := LoadTenants("./testdata/invalid.yaml")
+	assert.ErrorContains(t, err, "id is a required field")
+}
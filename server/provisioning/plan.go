@@ -0,0 +1,97 @@
+package provisioning
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"github.com/teamhanko/passkey-server/utils"
+	"io"
+)
+
+type Action string
+
+const (
+	ActionCreate    Action = "create"
+	ActionUpdate    Action = "update"
+	ActionDelete    Action = "delete"
+	ActionUnchanged Action = "unchanged"
+)
+
+// TenantPlan contains all changes which are needed to bring a tenant into the desired state
+type TenantPlan struct {
+	Action      Action
+	TenantId    uuid.UUID
+	DisplayName string
+
+	DisplayNameChanged bool
+	ConfigChanges      []utils.JsonChange
+	AddedApiKeys       []string
+	RemovedApiKeys     models.Secrets
+
+	definition *TenantDefinition
+	tenant     *models.Tenant
+}
+
+type Plan struct {
+	Tenants []TenantPlan
+}
+
+func (p *Plan) HasChanges() bool {
+	for _, tenant := range p.Tenants {
+		if tenant.Action != ActionUnchanged {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Print writes a human-readable diff of the plan
+func (p *Plan) Print(w io.Writer) {
+	if !p.HasChanges() {
+		_, _ = fmt.Fprintln(w, "No changes. Tenants are up to date.")
+		return
+	}
+
+	for _, tenant := range p.Tenants {
+		switch tenant.Action {
+		case ActionCreate:
+			_, _ = fmt.Fprintf(w, "+ tenant %s (%s) will be created\n", tenant.TenantId, tenant.DisplayName)
+		case ActionDelete:
+			_, _ = fmt.Fprintf(w, "- tenant %s (%s) will be deleted\n", tenant.TenantId, tenant.DisplayName)
+		case ActionUpdate:
+			_, _ = fmt.Fprintf(w, "~ tenant %s (%s) will be updated\n", tenant.TenantId, tenant.DisplayName)
+			if tenant.DisplayNameChanged {
+				_, _ = fmt.Fprintf(w, "    display_name: %s -> %s\n", formatValue(tenant.tenant.DisplayName), formatValue(tenant.DisplayName))
+			}
+
+			for _, change := range tenant.ConfigChanges {
+				_, _ = fmt.Fprintf(w, "    config%s: %s -> %s\n", change.Path, formatValue(change.From), formatValue(change.To))
+			}
+		default:
+			continue
+		}
+
+		for _, name := range tenant.AddedApiKeys {
+			_, _ = fmt.Fprintf(w, "    + api key %s\n", formatValue(name))
+		}
+
+		for _, secret := range tenant.RemovedApiKeys {
+			_, _ = fmt.Fprintf(w, "    - api key %s\n", formatValue(secret.Name))
+		}
+	}
+}
+
+func formatValue(value interface{}) string {
+	if value == nil {
+		return "(none)"
+	}
+
+	formatted, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+
+	return string(formatted)
+}
@@ -0,0 +1,254 @@
+package provisioning
+
+import (
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/teamhanko/passkey-server/api/dto/admin/request"
+	"github.com/teamhanko/passkey-server/api/services/admin"
+	"github.com/teamhanko/passkey-server/persistence"
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+// Actor is recorded as the author of all config versions created by the provisioning
+const Actor = "provisioning"
+
+// Reconciler brings the tenants in the database into the state described by tenant definitions
+type Reconciler struct {
+	persister persistence.Persister
+	prune     bool
+
+	// the admin services log through the echo logger of their context
+	ctx echo.Context
+}
+
+// NewReconciler creates a new reconciler. With prune enabled, tenants and API keys which are not defined are deleted.
+func NewReconciler(persister persistence.Persister, prune bool) *Reconciler {
+	return &Reconciler{
+		persister: persister,
+		prune:     prune,
+		ctx:       echo.New().NewContext(nil, nil),
+	}
+}
+
+// Plan returns the changes which are needed to reconcile the tenants without applying them
+func (r *Reconciler) Plan(definitions []TenantDefinition) (*Plan, error) {
+	return r.createPlan(r.persister.GetConnection(), definitions)
+}
+
+// Apply reconciles the tenants in a single transaction and returns the applied changes
+func (r *Reconciler) Apply(definitions []TenantDefinition) (*Plan, error) {
+	var plan *Plan
+	err := r.persister.Transaction(func(tx *pop.Connection) error {
+		var err error
+		plan, err = r.createPlan(tx, definitions)
+		if err != nil {
+			return err
+		}
+
+		for _, tenantPlan := range plan.Tenants {
+			err = r.applyTenantPlan(tx, tenantPlan)
+			if err != nil {
+				return fmt.Errorf("unable to %s tenant '%s': %w", tenantPlan.Action, tenantPlan.TenantId, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+func (r *Reconciler) createPlan(tx *pop.Connection, definitions []TenantDefinition) (*Plan, error) {
+	tenantPersister := r.persister.GetTenantPersister(tx)
+
+	plan := &Plan{}
+	definedTenants := make(map[uuid.UUID]bool)
+	for i := range definitions {
+		definition := &definitions[i]
+		tenantId := uuid.FromStringOrNil(*definition.Id)
+		definedTenants[tenantId] = true
+
+		tenant, err := tenantPersister.Get(tenantId)
+		if err != nil {
+			return nil, err
+		}
+
+		tenantPlan := TenantPlan{
+			Action:      ActionCreate,
+			TenantId:    tenantId,
+			DisplayName: definition.DisplayName,
+			definition:  definition,
+			tenant:      tenant,
+		}
+
+		if tenant == nil {
+			for _, apiKey := range definition.ApiKeys {
+				tenantPlan.AddedApiKeys = append(tenantPlan.AddedApiKeys, apiKey.Name)
+			}
+		} else {
+			err = r.planUpdate(tx, &tenantPlan)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		plan.Tenants = append(plan.Tenants, tenantPlan)
+	}
+
+	if !r.prune {
+		return plan, nil
+	}
+
+	tenants, err := tenantPersister.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range tenants {
+		tenant := &tenants[i]
+		if definedTenants[tenant.ID] {
+			continue
+		}
+
+		plan.Tenants = append(plan.Tenants, TenantPlan{
+			Action:      ActionDelete,
+			TenantId:    tenant.ID,
+			DisplayName: tenant.DisplayName,
+			tenant:      tenant,
+		})
+	}
+
+	return plan, nil
+}
+
+func (r *Reconciler) planUpdate(tx *pop.Connection, tenantPlan *TenantPlan) error {
+	definition := tenantPlan.definition
+	tenant := tenantPlan.tenant
+
+	tenantPlan.DisplayNameChanged = tenant.DisplayName != definition.DisplayName
+
+	configChanges, err := r.newTenantService(tx, tenant).DiffConfig(request.UpdateConfigDto{CreateConfigDto: definition.Config})
+	if err != nil {
+		return err
+	}
+	tenantPlan.ConfigChanges = configChanges
+
+	existingApiKeys := make(map[string]bool)
+	for _, secret := range tenant.Config.Secrets {
+		if !secret.IsAPISecret {
+			continue
+		}
+
+		existingApiKeys[secret.Name] = true
+		if r.prune && !definition.hasApiKey(secret.Name) {
+			tenantPlan.RemovedApiKeys = append(tenantPlan.RemovedApiKeys, secret)
+		}
+	}
+
+	for _, apiKey := range definition.ApiKeys {
+		if !existingApiKeys[apiKey.Name] {
+			tenantPlan.AddedApiKeys = append(tenantPlan.AddedApiKeys, apiKey.Name)
+		}
+	}
+
+	if tenantPlan.DisplayNameChanged || len(tenantPlan.ConfigChanges) > 0 || len(tenantPlan.AddedApiKeys) > 0 || len(tenantPlan.RemovedApiKeys) > 0 {
+		tenantPlan.Action = ActionUpdate
+	} else {
+		tenantPlan.Action = ActionUnchanged
+	}
+
+	return nil
+}
+
+func (r *Reconciler) applyTenantPlan(tx *pop.Connection, tenantPlan TenantPlan) error {
+	switch tenantPlan.Action {
+	case ActionCreate:
+		_, err := r.newTenantService(tx, nil).Create(tenantPlan.definition.CreateTenantDto)
+		if err != nil {
+			return err
+		}
+
+		return r.applyApiKeys(tx, tenantPlan)
+	case ActionUpdate:
+		err := r.applyApiKeys(tx, tenantPlan)
+		if err != nil {
+			return err
+		}
+
+		tenantService := r.newTenantService(tx, tenantPlan.tenant)
+		if tenantPlan.DisplayNameChanged {
+			err = tenantService.Update(request.UpdateTenantDto{DisplayName: tenantPlan.DisplayName})
+			if err != nil {
+				return err
+			}
+		}
+
+		if len(tenantPlan.ConfigChanges) > 0 {
+			err = tenantService.UpdateConfig(request.UpdateConfigDto{CreateConfigDto: tenantPlan.definition.Config}, models.ConfigChangeProvision)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case ActionDelete:
+		return r.newTenantService(tx, tenantPlan.tenant).Delete()
+	default:
+		return nil
+	}
+}
+
+func (r *Reconciler) applyApiKeys(tx *pop.Connection, tenantPlan TenantPlan) error {
+	if len(tenantPlan.AddedApiKeys) == 0 && len(tenantPlan.RemovedApiKeys) == 0 {
+		return nil
+	}
+
+	// reload the tenant, it might have been created in this transaction
+	tenant, err := r.persister.GetTenantPersister(tx).Get(tenantPlan.TenantId)
+	if err != nil {
+		return err
+	}
+
+	secretService := admin.NewSecretService(r.ctx, *tenant, r.persister.GetSecretsPersister(tx))
+	for _, name := range tenantPlan.AddedApiKeys {
+		_, err = secretService.Create(request.CreateSecretDto{Name: name}, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, secret := range tenantPlan.RemovedApiKeys {
+		err = secretService.Remove(request.RemoveSecretDto{SecretId: secret.ID.String()}, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Reconciler) newTenantService(tx *pop.Connection, tenant *models.Tenant) admin.TenantService {
+	actor := Actor
+
+	return admin.NewTenantService(admin.CreateTenantServiceParams{
+		Ctx:    r.ctx,
+		Tenant: tenant,
+		Actor:  &actor,
+
+		TenantPersister:         r.persister.GetTenantPersister(tx),
+		ConfigPersister:         r.persister.GetConfigPersister(tx),
+		CorsPersister:           r.persister.GetCorsPersister(tx),
+		WebauthnConfigPersister: r.persister.GetWebauthnConfigPersister(tx),
+		RelyingPartyPerister:    r.persister.GetWebauthnRelyingPartyPersister(tx),
+		AuditConfigPersister:    r.persister.GetAuditLogConfigPersister(tx),
+		SecretPersister:         r.persister.GetSecretsPersister(tx),
+		JwkPersister:            r.persister.GetJwkPersister(tx),
+		MFAConfigPersister:      r.persister.GetMFAConfigPersister(tx),
+		ConfigVersionPersister:  r.persister.GetConfigVersionPersister(tx),
+	})
+}
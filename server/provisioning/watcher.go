@@ -0,0 +1,75 @@
+package provisioning
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+	"log"
+	"os"
+	"time"
+)
+
+// Watch reconciles the tenant files of the provisioning directory on start and whenever one of them changes.
+// The directory is polled, as this also works for mounted volumes (e.g. kubernetes config maps).
+func Watch(cfg config.Provisioning, persister persistence.Persister) {
+	reconciler := NewReconciler(persister, cfg.Prune)
+
+	var lastChecksum []byte
+	ticker := time.NewTicker(cfg.GetInterval())
+	defer ticker.Stop()
+
+	for {
+		checksum, err := directoryChecksum(cfg.Directory)
+		if err != nil {
+			log.Printf("failed to read provisioning directory: %v", err)
+		} else if !bytes.Equal(checksum, lastChecksum) && reconcile(reconciler, cfg.Directory) {
+			// failed reconciliations are retried in the next interval, even if the files did not change
+			lastChecksum = checksum
+		}
+
+		<-ticker.C
+	}
+}
+
+// reconcile applies the tenant files of the directory and reports whether they were applied successfully
+func reconcile(reconciler *Reconciler, directory string) bool {
+	definitions, err := LoadTenants(directory)
+	if err != nil {
+		log.Printf("failed to load tenant files: %v", err)
+		return false
+	}
+
+	plan, err := reconciler.Apply(definitions)
+	if err != nil {
+		log.Printf("failed to provision tenants: %v", err)
+		return false
+	}
+
+	var output bytes.Buffer
+	plan.Print(&output)
+	log.Printf("provisioned tenants from '%s':\n%s", directory, output.String())
+
+	return true
+}
+
+func directoryChecksum(directory string) ([]byte, error) {
+	files, err := findFiles(directory)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read '%s': %w", file, err)
+		}
+
+		_, _ = fmt.Fprintf(hash, "%s:%d:", file, len(content))
+		hash.Write(content)
+	}
+
+	return hash.Sum(nil), nil
+}
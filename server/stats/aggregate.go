@@ -0,0 +1,113 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/teamhanko/passkey-server/persistence/models"
+)
+
+const (
+	IntervalHour = "hour"
+	IntervalDay  = "day"
+)
+
+// Ceremony maps a ceremony to the audit log types of its final step
+type Ceremony struct {
+	Name      string
+	Succeeded models.AuditLogType
+	Failed    models.AuditLogType
+}
+
+var Ceremonies = []Ceremony{
+	{Name: "registration", Succeeded: models.AuditLogWebAuthnRegistrationFinalSucceeded, Failed: models.AuditLogWebAuthnRegistrationFinalFailed},
+	{Name: "authentication", Succeeded: models.AuditLogWebAuthnAuthenticationFinalSucceeded, Failed: models.AuditLogWebAuthnAuthenticationFinalFailed},
+	{Name: "transaction", Succeeded: models.AuditLogWebAuthnTransactionFinalSucceeded, Failed: models.AuditLogWebAuthnTransactionFinalFailed},
+	{Name: "mfa_registration", Succeeded: models.AuditLogMfaRegistrationFinalSucceeded, Failed: models.AuditLogMfaRegistrationFinalFailed},
+	{Name: "mfa_authentication", Succeeded: models.AuditLogMfaAuthenticationFinalSucceeded, Failed: models.AuditLogMfaAuthenticationFinalFailed},
+}
+
+// SucceededTypes returns the types of all successful ceremonies. A user with one of them in a time range is active.
+func SucceededTypes() []models.AuditLogType {
+	types := make([]models.AuditLogType, 0, len(Ceremonies))
+	for _, ceremony := range Ceremonies {
+		types = append(types, ceremony.Succeeded)
+	}
+
+	return types
+}
+
+// Bucket contains the number of audit logs per type in the interval starting at Start
+type Bucket struct {
+	Start  time.Time                   `json:"start"`
+	Counts map[models.AuditLogType]int `json:"counts"`
+}
+
+type CeremonyStats struct {
+	Ceremony  string `json:"ceremony"`
+	Succeeded int    `json:"succeeded"`
+	Failed    int    `json:"failed"`
+	// SuccessRatio is empty if the ceremony was not finished in the time range
+	SuccessRatio *float64 `json:"success_ratio,omitempty"`
+}
+
+// Truncate returns the start of the interval containing the time. Days start at midnight UTC.
+func Truncate(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	if interval == IntervalDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+
+	return t.Truncate(time.Hour)
+}
+
+// BucketCounts sums up the hourly counts into buckets of the interval. Buckets without audit logs are omitted.
+func BucketCounts(counts []models.AuditLogTypeCount, interval string) []Bucket {
+	buckets := make(map[time.Time]*Bucket)
+	for _, count := range counts {
+		start := Truncate(count.Bucket, interval)
+		bucket, ok := buckets[start]
+		if !ok {
+			bucket = &Bucket{Start: start, Counts: make(map[models.AuditLogType]int)}
+			buckets[start] = bucket
+		}
+
+		bucket.Counts[count.Type] += count.Count
+	}
+
+	result := make([]Bucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		result = append(result, *bucket)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+
+	return result
+}
+
+func SummarizeCeremonies(counts []models.AuditLogTypeCount) []CeremonyStats {
+	totals := make(map[models.AuditLogType]int)
+	for _, count := range counts {
+		totals[count.Type] += count.Count
+	}
+
+	result := make([]CeremonyStats, 0, len(Ceremonies))
+	for _, ceremony := range Ceremonies {
+		stats := CeremonyStats{
+			Ceremony:  ceremony.Name,
+			Succeeded: totals[ceremony.Succeeded],
+			Failed:    totals[ceremony.Failed],
+		}
+
+		if attempts := stats.Succeeded + stats.Failed; attempts > 0 {
+			ratio := float64(stats.Succeeded) / float64(attempts)
+			stats.SuccessRatio = &ratio
+		}
+
+		result = append(result, stats)
+	}
+
+	return result
+}
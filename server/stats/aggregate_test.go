@@ -0,0 +1,56 @@
+package stats
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/teamhanko/passkey-server/persistence/models"
+	"testing"
+	"time"
+)
+
+func hourlyCounts() []models.AuditLogTypeCount {
+	return []models.AuditLogTypeCount{
+		{Bucket: time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), Type: models.AuditLogWebAuthnAuthenticationFinalSucceeded, Count: 3},
+		{Bucket: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC), Type: models.AuditLogWebAuthnAuthenticationFinalSucceeded, Count: 5},
+		{Bucket: time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC), Type: models.AuditLogWebAuthnAuthenticationFinalFailed, Count: 2},
+		{Bucket: time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC), Type: models.AuditLogWebAuthnAuthenticationFinalSucceeded, Count: 2},
+	}
+}
+
+func TestBucketCountsByHour(t *testing.T) {
+	buckets := BucketCounts(hourlyCounts(), IntervalHour)
+
+	require.Len(t, buckets, 3)
+	assert.Equal(t, time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), buckets[0].Start)
+	assert.Equal(t, map[models.AuditLogType]int{
+		models.AuditLogWebAuthnAuthenticationFinalSucceeded: 5,
+		models.AuditLogWebAuthnAuthenticationFinalFailed:    2,
+	}, buckets[1].Counts)
+}
+
+func TestBucketCountsByDay(t *testing.T) {
+	buckets := BucketCounts(hourlyCounts(), IntervalDay)
+
+	require.Len(t, buckets, 2)
+	assert.Equal(t, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), buckets[0].Start)
+	assert.Equal(t, 3, buckets[0].Counts[models.AuditLogWebAuthnAuthenticationFinalSucceeded])
+	assert.Equal(t, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), buckets[1].Start)
+	assert.Equal(t, 7, buckets[1].Counts[models.AuditLogWebAuthnAuthenticationFinalSucceeded])
+	assert.Equal(t, 2, buckets[1].Counts[models.AuditLogWebAuthnAuthenticationFinalFailed])
+}
+
+func TestSummarizeCeremonies(t *testing.T) {
+	ceremonies := SummarizeCeremonies(hourlyCounts())
+
+	require.Len(t, ceremonies, len(Ceremonies))
+
+	assert.Equal(t, "registration", ceremonies[0].Ceremony)
+	assert.Nil(t, ceremonies[0].SuccessRatio)
+
+	authentication := ceremonies[1]
+	assert.Equal(t, "authentication", authentication.Ceremony)
+	assert.Equal(t, 10, authentication.Succeeded)
+	assert.Equal(t, 2, authentication.Failed)
+	require.NotNil(t, authentication.SuccessRatio)
+	assert.InDelta(t, 10.0/12.0, *authentication.SuccessRatio, 0.0001)
+}
@@ -0,0 +1,68 @@
+package stats
+
+import (
+	"log"
+	"time"
+
+	"github.com/gobuffalo/pop/v6"
+	"github.com/teamhanko/passkey-server/config"
+	"github.com/teamhanko/passkey-server/persistence"
+)
+
+// Watch rolls up the audit logs in the configured interval
+func Watch(cfg config.Stats, persister persistence.Persister) {
+	ticker := time.NewTicker(cfg.GetRollupInterval())
+	defer ticker.Stop()
+
+	for {
+		count, err := RollUp(persister, time.Now())
+		if err != nil {
+			log.Printf("failed to roll up audit logs: %v", err)
+		} else if count > 0 {
+			log.Printf("rolled up %d audit log counts", count)
+		}
+
+		<-ticker.C
+	}
+}
+
+// RollUp aggregates the audit logs of all complete hours since the last rollup. Audit logs which are created with a
+// time in an hour which was already rolled up, e.g. by importing a tenant, are not included in the rollups.
+func RollUp(persister persistence.Persister, now time.Time) (int, error) {
+	to := now.UTC().Truncate(time.Hour)
+
+	count := 0
+	err := persister.Transaction(func(tx *pop.Connection) error {
+		statsPersister := persister.GetStatsPersister(tx)
+
+		var from time.Time
+		last, err := statsPersister.GetLastRollupBucket()
+		if err != nil {
+			return err
+		}
+
+		if last != nil {
+			from = last.UTC().Add(time.Hour)
+		} else {
+			first, err := statsPersister.GetFirstAuditLogTime()
+			if err != nil {
+				return err
+			}
+
+			if first == nil {
+				return nil
+			}
+
+			from = first.UTC().Truncate(time.Hour)
+		}
+
+		if !from.Before(to) {
+			return nil
+		}
+
+		count, err = statsPersister.RollUp(from, to)
+		return err
+	})
+
+	return count, err
+}
@@ -0,0 +1,85 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+
+	"github.com/luna-duclos/instrumentedsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SqlDriverOptions returns the options of the instrumented database driver. Every query becomes a child span of the
+// span in the context of the pop connection. Query arguments are omitted, as they contain personal data and keys.
+func SqlDriverOptions() []instrumentedsql.Opt {
+	return []instrumentedsql.Opt{
+		instrumentedsql.WithTracer(sqlTracer{}),
+		instrumentedsql.WithOmitArgs(),
+		instrumentedsql.WithOpsExcluded(
+			instrumentedsql.OpSQLRowsNext,
+			instrumentedsql.OpSQLStmtClose,
+			instrumentedsql.OpSQLResLastInsertID,
+			instrumentedsql.OpSQLResRowsAffected,
+			instrumentedsql.OpSQLDummyPing,
+		),
+	}
+}
+
+type sqlTracer struct{}
+
+// GetSpan returns a no-op span for contexts without span, so queries of background jobs and migrations do not start
+// traces of their own
+func (sqlTracer) GetSpan(ctx context.Context) instrumentedsql.Span {
+	if ctx == nil || !trace.SpanContextFromContext(ctx).IsValid() {
+		return sqlSpan{}
+	}
+
+	return sqlSpan{ctx: ctx}
+}
+
+type sqlSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+func (s sqlSpan) NewChild(name string) instrumentedsql.Span {
+	if s.ctx == nil {
+		return s
+	}
+
+	ctx, span := otel.Tracer(instrumentationName).Start(s.ctx, name, trace.WithSpanKind(trace.SpanKindClient))
+
+	return sqlSpan{ctx: ctx, span: span}
+}
+
+func (s sqlSpan) SetLabel(k, v string) {
+	if s.span == nil {
+		return
+	}
+
+	if k == "query" {
+		s.span.SetAttributes(semconv.DBQueryText(v))
+		return
+	}
+
+	s.span.SetAttributes(attribute.String(k, v))
+}
+
+func (s sqlSpan) SetError(err error) {
+	if s.span == nil || err == nil || errors.Is(err, driver.ErrSkip) {
+		return
+	}
+
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s sqlSpan) Finish() {
+	if s.span != nil {
+		s.span.End()
+	}
+}
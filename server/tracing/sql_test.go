@@ -0,0 +1,76 @@
+package tracing
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previous)
+	})
+
+	return recorder
+}
+
+func TestSqlSpanIsChildOfRequestSpan(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, parent := Start(context.Background(), "request")
+	span := sqlTracer{}.GetSpan(ctx).NewChild("sql-conn-query")
+	span.SetLabel("component", "database/sql")
+	span.SetLabel("query", "SELECT 1")
+	span.Finish()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "sql-conn-query", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Contains(t, spans[0].Attributes(), semconv.DBQueryText("SELECT 1"))
+}
+
+func TestSqlSpanWithoutRequestSpan(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	span := sqlTracer{}.GetSpan(context.Background()).NewChild("sql-conn-query")
+	span.SetLabel("query", "SELECT 1")
+	span.SetError(errors.New("failed"))
+	span.Finish()
+
+	assert.Empty(t, recorder.Ended())
+}
+
+func TestSqlSpanError(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	ctx, parent := Start(context.Background(), "request")
+	skipped := sqlTracer{}.GetSpan(ctx).NewChild("sql-prepare")
+	skipped.SetError(driver.ErrSkip)
+	skipped.Finish()
+	failed := sqlTracer{}.GetSpan(ctx).NewChild("sql-conn-exec")
+	failed.SetError(errors.New("relation does not exist"))
+	failed.Finish()
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "relation does not exist", spans[1].Status().Description)
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// MergePatch applies a JSON merge patch (RFC 7386) to the given document
+func MergePatch(document []byte, patch []byte) ([]byte, error) {
+	var documentValue interface{}
+	if err := json.Unmarshal(document, &documentValue); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("failed to parse merge patch: %w", err)
+	}
+
+	return json.Marshal(mergeValue(documentValue, patchValue))
+}
+
+func mergeValue(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+		} else {
+			targetObject[key] = mergeValue(targetObject[key], value)
+		}
+	}
+
+	return targetObject
+}
+
+// JsonChange describes a changed value between two JSON documents. The path uses the JSON pointer syntax (RFC 6901).
+type JsonChange struct {
+	Path string      `json:"path"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Diff compares two JSON documents and returns all changed values ordered by their path.
+// Arrays are compared as a whole.
+func Diff(from []byte, to []byte) ([]JsonChange, error) {
+	var fromValue interface{}
+	if err := json.Unmarshal(from, &fromValue); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	var toValue interface{}
+	if err := json.Unmarshal(to, &toValue); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	changes := make([]JsonChange, 0)
+	diffValue("", fromValue, toValue, &changes)
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes, nil
+}
+
+func diffValue(path string, from interface{}, to interface{}, changes *[]JsonChange) {
+	fromObject, fromIsObject := from.(map[string]interface{})
+	toObject, toIsObject := to.(map[string]interface{})
+
+	if fromIsObject && toIsObject {
+		for key, fromChild := range fromObject {
+			diffValue(path+"/"+escapePointer(key), fromChild, toObject[key], changes)
+		}
+
+		for key, toChild := range toObject {
+			if _, ok := fromObject[key]; !ok {
+				diffValue(path+"/"+escapePointer(key), nil, toChild, changes)
+			}
+		}
+
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, JsonChange{Path: path, From: from, To: to})
+	}
+}
+
+func escapePointer(key string) string {
+	escaped := make([]rune, 0, len(key))
+	for _, char := range key {
+		switch char {
+		case '~':
+			escaped = append(escaped, '~', '0')
+		case '/':
+			escaped = append(escaped, '~', '1')
+		default:
+			escaped = append(escaped, char)
+		}
+	}
+
+	return string(escaped)
+}
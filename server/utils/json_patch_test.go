@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	document := []byte(`{"cors":{"allowed_origins":["https://a.example"],"allow_unsafe_wildcard":false},"mfa":{"timeout":60000}}`)
+	patch := []byte(`{"cors":{"allowed_origins":["https://b.example"]},"mfa":null,"webauthn":{"timeout":30000}}`)
+
+	merged, err := MergePatch(document, patch)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"cors":{"allowed_origins":["https://b.example"],"allow_unsafe_wildcard":false},"webauthn":{"timeout":30000}}`, string(merged))
+}
+
+func TestMergePatch_InvalidPatch(t *testing.T) {
+	_, err := MergePatch([]byte(`{}`), []byte(`{`))
+	assert.Error(t, err)
+}
+
+func TestDiff(t *testing.T) {
+	from := []byte(`{"cors":{"allowed_origins":["https://a.example"],"allow_unsafe_wildcard":false},"mfa":{"timeout":60000}}`)
+	to := []byte(`{"cors":{"allowed_origins":["https://b.example"],"allow_unsafe_wildcard":false},"webauthn":{"timeout":30000}}`)
+
+	changes, err := Diff(from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []JsonChange{
+		{Path: "/cors/allowed_origins", From: []interface{}{"https://a.example"}, To: []interface{}{"https://b.example"}},
+		{Path: "/mfa", From: map[string]interface{}{"timeout": float64(60000)}},
+		{Path: "/webauthn", To: map[string]interface{}{"timeout": float64(30000)}},
+	}, changes)
+}
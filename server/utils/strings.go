@@ -0,0 +1,14 @@
+package utils
+
+// TruncateRunes shortens the value to at most maxLength characters without splitting a multi-byte character
+func TruncateRunes(value string, maxLength int) string {
+	count := 0
+	for i := range value {
+		if count == maxLength {
+			return value[:i]
+		}
+		count++
+	}
+
+	return value
+}
@@ -0,0 +1,15 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTruncateRunes(t *testing.T) {
+	assert.Equal(t, "Mozilla", TruncateRunes("Mozilla", 10))
+	assert.Equal(t, "Mozi", TruncateRunes("Mozilla", 4))
+	assert.Equal(t, "Gerät ", TruncateRunes("Gerät 🔑", 6))
+	assert.Equal(t, "Gerät 🔑", TruncateRunes("Gerät 🔑", 7))
+	assert.Equal(t, "", TruncateRunes("Gerät", 0))
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mileusna/useragent"
+)
+
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// UserAgent is a summary of a user agent header, good enough to tell users which device created a credential and to
+// describe the client of an audit log. Unknown parts are left empty.
+type UserAgent struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	// Device is the class of the device, one of desktop, mobile, tablet or bot
+	Device string
+}
+
+// ParseUserAgent detects the browser, operating system and device class of a user agent header
+func ParseUserAgent(userAgent string) UserAgent {
+	if strings.TrimSpace(userAgent) == "" {
+		return UserAgent{}
+	}
+
+	ua := useragent.Parse(userAgent)
+
+	var device string
+	switch {
+	case ua.Bot:
+		device = DeviceBot
+	case ua.Tablet:
+		device = DeviceTablet
+	case ua.Mobile:
+		device = DeviceMobile
+	case ua.Desktop:
+		device = DeviceDesktop
+	}
+
+	return UserAgent{
+		Browser:        ua.Name,
+		BrowserVersion: ua.Version,
+		OS:             ua.OS,
+		OSVersion:      ua.OSVersion,
+		Device:         device,
+	}
+}
+
+// String returns a human-readable summary like "Safari on macOS"
+func (ua UserAgent) String() string {
+	switch {
+	case ua.Browser != "" && ua.OS != "":
+		return fmt.Sprintf("%s on %s", ua.Browser, ua.OS)
+	case ua.Browser != "":
+		return ua.Browser
+	default:
+		return ua.OS
+	}
+}
+
+// BrowserWithVersion returns the browser including its version, e.g. "Safari 17.1"
+func (ua UserAgent) BrowserWithVersion() string {
+	return strings.TrimSpace(ua.Browser + " " + ua.BrowserVersion)
+}
+
+// OSWithVersion returns the operating system including its version, e.g. "iOS 17.1"
+func (ua UserAgent) OSWithVersion() string {
+	return strings.TrimSpace(ua.OS + " " + ua.OSVersion)
+}